@@ -0,0 +1,336 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"rayyanriaz/tool-version-manager/pkg/impl/bundle"
+	"rayyanriaz/tool-version-manager/pkg/impl/config"
+	"rayyanriaz/tool-version-manager/pkg/impl/state"
+	"rayyanriaz/tool-version-manager/pkg/models"
+	"rayyanriaz/tool-version-manager/pkg/utils"
+
+	"github.com/goccy/go-yaml"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bundleTools string
+	bundleOut   string
+	bundleForce bool
+)
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Move installed versions to another host",
+	Long: `Package installed versions into a single file and install them elsewhere, e.g. on an air-gapped host.
+
+A bundle holds the version directories, their cached artifacts, their install metadata and the definitions
+of their tools. Installing it needs neither the network nor the tools' scripts.`,
+}
+
+var bundleCreateCmd = &cobra.Command{
+	Use:   "create --tools <tool-id>[@<version>],... --out <file>",
+	Short: "Package installed versions of tools into a bundle",
+	Long: `Package installed versions of tools into a tar file. Without a version, the linked version is bundled.
+
+Examples:
+  tvm bundle create --tools rg,fd --out tools.tar
+  tvm bundle create --tools rg@14.1.0,jq --out tools.tar`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if bundleTools == "" || bundleOut == "" {
+			return fmt.Errorf("--tools and --out are required")
+		}
+
+		manifest := bundle.Manifest{
+			FormatVersion: bundle.FormatVersion,
+			CreatedAt:     time.Now(),
+			TvmVersion:    Version,
+		}
+		bundled := make(map[string]bool)
+		for _, spec := range strings.Split(bundleTools, ",") {
+			toolID, rawVersion, _ := strings.Cut(strings.TrimSpace(spec), "@")
+			// a bundle installs and links one version per tool, and the tool lock isn't reentrant
+			if bundled[toolID] {
+				return fmt.Errorf("tool %s is given more than once, a bundle holds one version per tool", toolID)
+			}
+			bundled[toolID] = true
+			tool, tvm, err := getToolWithTVM(toolID)
+			if err != nil {
+				return err
+			}
+			lock, err := lockTool(toolID)
+			if err != nil {
+				return err
+			}
+			// keep the version from being uninstalled until it is in the bundle
+			defer lock.Unlock()
+
+			entry, err := newBundleEntry(tool, tvm, models.ToolVersion(rawVersion))
+			if err != nil {
+				return err
+			}
+			manifest.Tools = append(manifest.Tools, entry)
+			manifest.Definitions = append(manifest.Definitions, models.ToolWrapper{Wrapped: tool})
+		}
+
+		w, err := bundle.Create(bundleOut, manifest)
+		if err != nil {
+			return err
+		}
+		added := make(map[string]bool)
+		for _, entry := range manifest.Tools {
+			if err := w.AddDir(bundle.VersionPath(entry.Tool, entry.Version), toolVersionDir(entry.Tool, entry.Version)); err != nil {
+				w.Abort()
+				return fmt.Errorf("failed to bundle %s version %s: %w", entry.Tool, entry.Version, err)
+			}
+			for key, artifact := range entry.Artifacts {
+				if added[artifact.Digest] {
+					continue
+				}
+				added[artifact.Digest] = true
+				if err := w.AddFile(bundle.ArtifactPath(artifact.Digest), artifactCache.BlobPath(artifact.Digest)); err != nil {
+					w.Abort()
+					return fmt.Errorf("failed to bundle artifact %s: %w", key, err)
+				}
+			}
+		}
+		if err := w.Close(); err != nil {
+			w.Abort()
+			return fmt.Errorf("failed to write bundle: %w", err)
+		}
+
+		for _, entry := range manifest.Tools {
+			fmt.Printf("Bundled %s version %s (%d artifacts)\n", entry.Tool, entry.Version, len(entry.Artifacts))
+		}
+		fmt.Printf("Wrote %s\n", bundleOut)
+		return nil
+	},
+}
+
+// newBundleEntry describes an installed version of a tool for a bundle, defaulting to the linked version
+func newBundleEntry(tool models.Tool, tvm models.ToolVersionManager, version models.ToolVersion) (bundle.ToolEntry, error) {
+	if version == "" {
+		linked, err := getLinkedVersion(tool, tvm)
+		if err != nil {
+			return bundle.ToolEntry{}, fmt.Errorf("failed to get linked version of %s: %w", tool.GetId(), err)
+		}
+		if linked == "" {
+			return bundle.ToolEntry{}, fmt.Errorf("%s is not linked, pass %s@<version> to pick a version", tool.GetId(), tool.GetId())
+		}
+		version = linked
+	}
+	installed, err := isInstalled(tool, tvm, version)
+	if err != nil {
+		return bundle.ToolEntry{}, fmt.Errorf("failed to get local versions of %s: %w", tool.GetId(), err)
+	}
+	if !installed {
+		return bundle.ToolEntry{}, fmt.Errorf("%s version %s is not installed", tool.GetId(), version)
+	}
+
+	digest, size, err := utils.DirDigest(toolVersionDir(tool.GetId(), version))
+	if err != nil {
+		return bundle.ToolEntry{}, fmt.Errorf("failed to hash %s version %s: %w", tool.GetId(), version, err)
+	}
+	record, _ := installStore.Get(tool.GetId(), version)
+	record.Version = version
+	record.Digest = digest
+	record.SizeBytes = size
+
+	artifacts := make(map[string]state.Artifact)
+	for _, key := range artifactCache.Keys(tool.GetId() + "/" + string(version)) {
+		if artifact, ok := artifactCache.Lookup(key); ok {
+			artifacts[key] = artifact
+		}
+	}
+	return bundle.ToolEntry{
+		Tool:      tool.GetId(),
+		Version:   version,
+		Digest:    digest,
+		Record:    record,
+		Artifacts: artifacts,
+	}, nil
+}
+
+var bundleInstallCmd = &cobra.Command{
+	Use:   "install <file>",
+	Short: "Install and link the versions in a bundle",
+	Long: `Install the versions in a bundle and link them. Everything in the bundle is checked against the digests
+in its manifest before anything is changed. Tools that aren't in the config yet are added to it with the
+bundled definition; tools that are keep their own. No scripts are run apart from the link hooks.
+
+Examples:
+  tvm bundle install tools.tar`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if err := os.MkdirAll(configService.DownloadsDir, 0755); err != nil {
+			return err
+		}
+		// extract next to the version directories, so they can be moved into place with a rename
+		extractDir, err := os.MkdirTemp(configService.DownloadsDir, ".bundle-*")
+		if err != nil {
+			return fmt.Errorf("failed to create extract directory: %w", err)
+		}
+		defer os.RemoveAll(extractDir)
+
+		manifest, err := bundle.Extract(args[0], extractDir, manager.Registry())
+		if err != nil {
+			return err
+		}
+		if err := verifyBundle(manifest, extractDir); err != nil {
+			return err
+		}
+		if err := addBundledDefinitions(manifest.Definitions); err != nil {
+			return err
+		}
+
+		for _, entry := range manifest.Tools {
+			for key, artifact := range entry.Artifacts {
+				if err := addBundledArtifact(extractDir, key, artifact); err != nil {
+					return err
+				}
+			}
+			if err := installBundledVersion(extractDir, entry); err != nil {
+				return fmt.Errorf("failed to install %s version %s: %w", entry.Tool, entry.Version, err)
+			}
+			fmt.Printf("Installed and linked %s version %s\n", entry.Tool, entry.Version)
+		}
+		return nil
+	},
+}
+
+// verifyBundle checks the extracted version directories and artifacts against the digests in the manifest
+func verifyBundle(manifest *bundle.Manifest, extractDir string) error {
+	for _, entry := range manifest.Tools {
+		digest, _, err := utils.DirDigest(filepath.Join(extractDir, bundle.VersionPath(entry.Tool, entry.Version)))
+		if err != nil {
+			return fmt.Errorf("bundle is missing %s version %s: %w", entry.Tool, entry.Version, err)
+		}
+		if digest != entry.Digest {
+			return fmt.Errorf("%s version %s in the bundle is corrupted: its digest is %s, expected %s", entry.Tool, entry.Version, digest, entry.Digest)
+		}
+		for key, artifact := range entry.Artifacts {
+			digest, err := utils.FileDigest(filepath.Join(extractDir, bundle.ArtifactPath(artifact.Digest)))
+			if err != nil {
+				return fmt.Errorf("bundle is missing artifact %s: %w", key, err)
+			}
+			if digest != artifact.Digest {
+				return fmt.Errorf("artifact %s in the bundle is corrupted: its digest is %s, expected %s", key, digest, artifact.Digest)
+			}
+		}
+	}
+	return nil
+}
+
+// addBundledDefinitions adds the tools of a bundle that the config doesn't have yet, and reloads the config
+func addBundledDefinitions(definitions []models.ToolWrapper) error {
+	var missing []models.ToolWrapper
+	for _, definition := range definitions {
+		if _, err := getToolById(definition.Wrapped.GetId()); err != nil {
+			missing = append(missing, definition)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	editor, err := config.NewConfigEditor(configService.GetConfigFilePath(), manager.Registry())
+	if err != nil {
+		return err
+	}
+	for _, definition := range missing {
+		item, err := yaml.Marshal(definition)
+		if err != nil {
+			return fmt.Errorf("failed to encode tool %s: %w", definition.Wrapped.GetId(), err)
+		}
+		if err := editor.AppendToSequence("tools", string(item)); err != nil {
+			return fmt.Errorf("failed to add tool %s: %w", definition.Wrapped.GetId(), err)
+		}
+	}
+	if err := editor.Save(); err != nil {
+		return fmt.Errorf("failed to add bundled tools: %w", err)
+	}
+	if err := configService.Load(); err != nil {
+		return fmt.Errorf("failed to reload config: %w", err)
+	}
+	for _, definition := range missing {
+		fmt.Printf("Added %s to %s\n", definition.Wrapped.GetId(), configService.GetConfigFilePath())
+	}
+	return nil
+}
+
+// addBundledArtifact adds an extracted artifact to the artifact cache, unless it is already cached
+func addBundledArtifact(extractDir, key string, artifact state.Artifact) error {
+	if cached, ok := artifactCache.Lookup(key); ok && cached.Digest == artifact.Digest {
+		return nil
+	}
+	f, err := os.Open(filepath.Join(extractDir, bundle.ArtifactPath(artifact.Digest)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	added, err := artifactCache.Add(key, artifact.URL, f)
+	if err != nil {
+		return err
+	}
+	if added.Digest != artifact.Digest {
+		return fmt.Errorf("artifact %s changed while adding it to the cache", key)
+	}
+	return nil
+}
+
+// installBundledVersion moves an extracted version into place, records it and links it. A version that is
+// already installed is kept if its content is the same as the bundled one.
+func installBundledVersion(extractDir string, entry bundle.ToolEntry) error {
+	tool, tvm, err := getToolWithTVM(entry.Tool)
+	if err != nil {
+		return err
+	}
+	lock, err := lockTool(entry.Tool)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	versionDir := toolVersionDir(entry.Tool, entry.Version)
+	if _, err := os.Lstat(versionDir); err == nil {
+		digest, _, err := utils.DirDigest(versionDir)
+		if err != nil {
+			return err
+		}
+		if digest != entry.Digest {
+			return fmt.Errorf("%s already exists with different content, uninstall it first", versionDir)
+		}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(versionDir), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(filepath.Join(extractDir, bundle.VersionPath(entry.Tool, entry.Version)), versionDir); err != nil {
+			return err
+		}
+	}
+
+	record := entry.Record
+	record.Version = entry.Version
+	record.Digest = entry.Digest
+	record.Broken = ""
+	if err := installStore.Put(entry.Tool, record); err != nil {
+		return fmt.Errorf("failed to record install: %w", err)
+	}
+	return linkToolVersion(tool, tvm, entry.Version, bundleForce)
+}
+
+func init() {
+	bundleCreateCmd.Flags().StringVar(&bundleTools, "tools", "", "Comma separated tools to bundle, as <tool-id> or <tool-id>@<version>")
+	bundleCreateCmd.Flags().StringVarP(&bundleOut, "out", "o", "", "File to write the bundle to")
+	bundleInstallCmd.Flags().BoolVarP(&bundleForce, "force", "f", false, "Overwrite links owned by other tools or not managed by tvm")
+	bundleCmd.AddCommand(bundleCreateCmd)
+	bundleCmd.AddCommand(bundleInstallCmd)
+	RootCmd.AddCommand(bundleCmd)
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"rayyanriaz/tool-version-manager/pkg/models"
+	"rayyanriaz/tool-version-manager/pkg/tvm"
+
+	"github.com/spf13/cobra"
+)
+
+var changelogCmd = &cobra.Command{
+	Use:   "changelog <tool-id> [from..to]",
+	Short: "Show the release notes of the versions between two versions of a tool",
+	Long: `Show the release notes of every version after 'from', up to and including 'to', newest first.
+'from' defaults to the linked version and 'to' to the latest version. Either side of the range can be
+left out, e.g. '1.8.0..' or '..1.9.0'.
+
+Notes are cached in the state directory. They are fetched again only if the cache doesn't have 'to' yet,
+and the cache is used when they can't be fetched, so they can be read offline.
+
+Examples:
+  tvm changelog terraform                 # linked version .. latest version
+  tvm changelog terraform 1.8.0..1.9.2
+  tvm changelog rg ..14.1.0`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tool, toolTVM, err := getToolWithTVM(args[0])
+		if err != nil {
+			return err
+		}
+
+		var from, to models.ToolVersion
+		if len(args) == 2 {
+			rawFrom, rawTo, found := strings.Cut(args[1], "..")
+			if !found {
+				return fmt.Errorf("invalid range %q, expected from..to", args[1])
+			}
+			from, to = models.ToolVersion(strings.TrimSpace(rawFrom)), models.ToolVersion(strings.TrimSpace(rawTo))
+		}
+		if from == "" {
+			if from, err = getLinkedVersion(tool, toolTVM); err != nil {
+				return fmt.Errorf("failed to get linked version: %w", err)
+			}
+		}
+		if to == "" {
+			if to, err = getLatestVersion(tool, toolTVM); err != nil {
+				return fmt.Errorf("failed to get latest version: %w", err)
+			}
+		}
+
+		notes, err := manager.ReleaseNotes(tool, toolTVM, from, to)
+		if err != nil {
+			return err
+		}
+		if len(notes) == 0 {
+			fmt.Printf("No release notes for %s between %s and %s\n", tool.GetId(), tvm.DescribeVersion(from), to)
+			return nil
+		}
+		printReleaseNotes(tool.GetId(), notes)
+		return nil
+	},
+}
+
+// releaseNotesMu keeps the notes of concurrently upgraded tools from interleaving
+var releaseNotesMu sync.Mutex
+
+func printReleaseNotes(toolID string, notes []models.ReleaseNote) {
+	releaseNotesMu.Lock()
+	defer releaseNotesMu.Unlock()
+
+	for _, note := range notes {
+		header := fmt.Sprintf("%s %s", toolID, note.Version)
+		if note.Title != "" && note.Title != string(note.Version) && note.Title != note.Tag {
+			header += " - " + note.Title
+		}
+		if !note.PublishedAt.IsZero() {
+			header += fmt.Sprintf(" (%s)", note.PublishedAt.Local().Format("2006-01-02"))
+		}
+		fmt.Println(header)
+		fmt.Println(strings.Repeat("=", len(header)))
+		if note.URL != "" {
+			fmt.Println(note.URL)
+		}
+		if body := strings.TrimSpace(strings.ReplaceAll(note.Body, "\r\n", "\n")); body != "" {
+			fmt.Printf("\n%s\n", body)
+		}
+		fmt.Println()
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(changelogCmd)
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var checkAll bool
+
+var checkCmd = &cobra.Command{
+	Use:   "check [tool-id]",
+	Short: "Smoke test the linked versions of tools",
+	Long: `Run each tool's 'verify' command against its linked version and check that the output matches
+the verify pattern for that version. This catches binaries that crash on this machine, are built for
+the wrong architecture, or report a different version than the one that is linked.
+
+Tools without a 'verify' block, and tools that aren't linked, are skipped. 'upgrade' runs the same
+check right after linking and reverts the link if it fails.
+
+Examples:
+  tvm check rg
+  tvm check rg,fd
+  tvm check --all`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !checkAll && len(args) == 0 {
+			return fmt.Errorf("you must provide either a tool ID or use the --all flag")
+		}
+		if checkAll && len(args) > 0 {
+			return fmt.Errorf("cannot use --all with specific tool IDs")
+		}
+
+		var toolIDs []string
+		if checkAll {
+			tools, err := getAllTools()
+			if err != nil {
+				return fmt.Errorf("failed to get tools: %w", err)
+			}
+			for _, toolWrapper := range tools {
+				toolIDs = append(toolIDs, toolWrapper.Wrapped.GetId())
+			}
+		} else {
+			for _, toolID := range splitToolIDs(args[0]) {
+				if toolID == "" {
+					return fmt.Errorf("invalid empty tool ID in input")
+				}
+				toolIDs = append(toolIDs, toolID)
+			}
+		}
+
+		var failed, skipped int
+		for _, toolID := range toolIDs {
+			tool, tvm, err := getToolWithTVM(toolID)
+			if err != nil {
+				return err
+			}
+			if tool.GetVerify() == nil {
+				if !checkAll {
+					fmt.Printf("%s: skipped, no verify configured\n", toolID)
+				}
+				skipped++
+				continue
+			}
+			linked, err := getLinkedVersion(tool, tvm)
+			if err != nil {
+				fmt.Printf("%s: FAILED to get linked version: %v\n", toolID, err)
+				failed++
+				continue
+			}
+			if linked == "" {
+				fmt.Printf("%s: skipped, not linked\n", toolID)
+				skipped++
+				continue
+			}
+			if err := manager.Verify(cmd.Context(), tool, linked); err != nil {
+				fmt.Printf("%s %s: FAILED: %v\n", toolID, linked, err)
+				failed++
+				continue
+			}
+			fmt.Printf("%s %s: ok\n", toolID, linked)
+		}
+
+		fmt.Printf("\n%d checked, %d failed, %d skipped\n", len(toolIDs)-skipped, failed, skipped)
+		if failed > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d tool(s) failed verification", failed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	checkCmd.Flags().BoolVarP(&checkAll, "all", "a", false, "Check all tools")
+	RootCmd.AddCommand(checkCmd)
+}
@@ -3,17 +3,25 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"rayyanriaz/tool-version-manager/pkg/impl/config"
-	scriptdriventvm "rayyanriaz/tool-version-manager/pkg/impl/scriptdriven_tvm"
+	"rayyanriaz/tool-version-manager/pkg/impl/state"
 	"rayyanriaz/tool-version-manager/pkg/models"
+	"rayyanriaz/tool-version-manager/pkg/tvm"
+	"rayyanriaz/tool-version-manager/pkg/utils"
 )
 
 var (
-	configPath         string
-	verbose            bool
-	configService      *config.LocalFileConfig
-	remoteVersionCache *config.RemoteVersionsCache
+	configPath    string
+	verbose       bool
+	offline       bool
+	manager       *tvm.Manager
+	configService *config.LocalFileConfig
+	installStore  *state.InstallStore
+	linkHistory   *state.LinkHistory
+	journal       *state.Journal
+	artifactCache *state.ArtifactCache
 )
 
 func bootstrap() error {
@@ -22,85 +30,121 @@ func bootstrap() error {
 		configPath = os.Getenv("TVM_CONFIG")
 	}
 	if configPath == "" {
-		configPath = "/home/rayyan/programs/tvm_config.yaml"
+		configPath = "tools.yaml"
 	}
-
-	/*
-		Bootstrap different TVMs with root info.
-		Until a better design is made, I am just assigning the missing globals in this function
-	*/
-
-	// for scriptdriven.ScriptsDrivenTVM
-	cfg := config.NewLocalFileConfig(configPath)
-	models.ToolRegistrar.RegisterConfig("scripts_driven", cfg)
-	models.ToolRegistrar.RegisterTVM("scripts_driven", scriptdriventvm.NewScriptsDrivenTVM())
-	if err := cfg.Load(); err != nil {
-		return fmt.Errorf("failed to create config service for script_driven: %w", err)
+	if os.Getenv("TVM_OFFLINE") != "" {
+		offline = true
 	}
-	configService = cfg
 
-	// Initialize remote versions cache
-	remoteVersionCache = config.NewRemoteVersionsCache(cfg.RemoteVersionsCacheFilePath)
-	if err := remoteVersionCache.Load(); err != nil {
-		return fmt.Errorf("failed to load remote versions cache: %w", err)
+	m, err := tvm.New(managerOptions())
+	if err != nil {
+		return err
+	}
+	manager = m
+	configService = m.Config()
+	installStore = m.InstallStore()
+	linkHistory = m.LinkHistory()
+	journal = m.Journal()
+	artifactCache = m.ArtifactCache()
+
+	for _, r := range m.Recovered() {
+		if r.Err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to recover interrupted %s of %s: %v\n", r.Entry.Operation, describeJournalEntry(r.Entry), r.Err)
+		} else {
+			fmt.Fprintf(os.Stderr, "Recovered interrupted %s of %s: %s\n", r.Entry.Operation, describeJournalEntry(r.Entry), r.Resolution)
+		}
 	}
-
 	return nil
 }
 
-func getToolById(toolID string) (models.Tool, error) {
-
-	for _, tool_type := range models.ToolRegistrar.GetRegisteredConfigTypes() {
-		cfg, err := models.ToolRegistrar.GetConfig(tool_type)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get config service for tool type '%s': %w", tool_type, err)
-		}
-		for _, toolWrapper := range cfg.GetTools() {
-			if toolWrapper.Wrapped.GetId() == toolID {
-				return toolWrapper.Wrapped, nil
-			}
-		}
+// managerOptions are the options of the Manager behind the commands, from the flags and the environment
+func managerOptions() tvm.Options {
+	return tvm.Options{
+		ConfigPath: configPath,
+		Offline:    offline,
+		Channel:    models.Channel(channelOverride),
+		TvmVersion: Version,
+		Progress:   os.Stdout,
 	}
-	// if we reach here, we didn't find the tool in any registered config service
-	return nil, fmt.Errorf("tool '%s' not found in any configuration", toolID)
+}
+
+func getToolById(toolID string) (models.Tool, error) {
+	return manager.Tool(toolID)
 }
 
 func getToolWithTVM(toolId string) (models.Tool, models.ToolVersionManager, error) {
-	tool, err := getToolById(toolId)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get tool '%s': %w", toolId, err)
-	}
+	return manager.ToolWithTVM(toolId)
+}
 
-	tvm, err := models.ToolRegistrar.GetTVM(tool.GetType())
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get TVM for tool type '%s': %w", tool.GetType(), err)
+func getAllTools() (models.UniqueToolWrappers, error) {
+	return manager.Tools()
+}
+
+// splitToolIDs splits a comma separated list of tool IDs
+func splitToolIDs(arg string) []string {
+	toolIDs := strings.Split(arg, ",")
+	for i := range toolIDs {
+		toolIDs[i] = strings.TrimSpace(toolIDs[i])
 	}
+	return toolIDs
+}
 
-	return tool, tvm, nil
+// toolVersionDir is where a version of a tool is installed
+func toolVersionDir(toolID string, version models.ToolVersion) string {
+	return manager.VersionDir(toolID, version)
 }
 
-func getAllTools() (models.UniqueToolWrappers, error) {
-	var allTools models.UniqueToolWrappers
+// toolVersionBinaries maps the link names of a tool to its binaries in an installed version,
+// i.e. what its symlinks would point to if that version was linked
+func toolVersionBinaries(tool models.Tool, version models.ToolVersion) (map[string]string, error) {
+	return manager.VersionBinaries(tool, version)
+}
 
-	for _, tool_type := range models.ToolRegistrar.GetRegisteredConfigTypes() {
-		cfg, err := models.ToolRegistrar.GetConfig(tool_type)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get config for tool type '%s': %w", tool_type, err)
-		}
-		allTools = append(allTools, cfg.GetTools()...)
-	}
+// lockTool takes the per-tool file lock that guards install, link and unlink
+func lockTool(toolID string) (*utils.FileLock, error) {
+	return manager.LockTool(toolID)
+}
+
+// getLinkedVersion returns the currently linked version of a tool, or "" if it isn't linked
+func getLinkedVersion(tool models.Tool, tvm models.ToolVersionManager) (models.ToolVersion, error) {
+	return manager.LinkedVersion(tool, tvm)
+}
 
-	return allTools, nil
+// installToolVersion installs a version of a tool, journaled. Callers are expected to hold the tool lock.
+func installToolVersion(tool models.Tool, tvm models.ToolVersionManager, version models.ToolVersion) error {
+	return manager.InstallVersion(tool, tvm, version)
 }
 
-// getCachedLatestVersion returns the cached latest version for a tool
-func getCachedLatestVersion(toolID string) (models.ToolVersion, bool) {
-	version, _, found := remoteVersionCache.GetCachedVersion(toolID)
-	return version, found
+// linkToolVersion links a version of a tool, journaled and recorded in the link history.
+// Callers are expected to hold the tool lock.
+func linkToolVersion(tool models.Tool, tvm models.ToolVersionManager, version models.ToolVersion, force bool) error {
+	return manager.LinkVersion(tool, tvm, version, force)
 }
 
-// updateCachedLatestVersion updates the cached latest version for a tool and saves to disk
-func updateCachedLatestVersion(toolID string, version models.ToolVersion) error {
-	remoteVersionCache.SetCachedVersion(toolID, version)
-	return remoteVersionCache.Save()
+// isInstalled reports whether a version of a tool is installed locally
+func isInstalled(tool models.Tool, tvm models.ToolVersionManager, version models.ToolVersion) (bool, error) {
+	return manager.IsInstalled(tool, tvm, version)
+}
+
+// channelFlag is a --channel override of the tools' release channels, validated when the flag is parsed
+type channelFlag models.Channel
+
+func (f *channelFlag) String() string { return string(*f) }
+func (f *channelFlag) Type() string   { return "channel" }
+func (f *channelFlag) Set(s string) error {
+	channel, err := models.ParseChannel(s)
+	if err != nil {
+		return err
+	}
+	*f = channelFlag(channel)
+	return nil
+}
+
+// channelOverride is set by the --channel flag of the commands that resolve latest versions
+var channelOverride channelFlag
+
+// getLatestVersion returns the cached latest version of a tool while it is fresh,
+// and fetches it from remote (updating the cache) once it has gone stale
+func getLatestVersion(tool models.Tool, tvm models.ToolVersionManager) (models.ToolVersion, error) {
+	return manager.LatestVersion(tool, tvm)
 }
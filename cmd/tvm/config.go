@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"rayyanriaz/tool-version-manager/pkg/impl/config"
+	plugintvm "rayyanriaz/tool-version-manager/pkg/impl/plugin_tvm"
+
+	"github.com/goccy/go-yaml"
+	"github.com/spf13/cobra"
+)
+
+var (
+	addType       string
+	addPreset     string
+	addRepo       string
+	addAssetRegex string
+	addSymlinks   []string
+)
+
+var addCmd = &cobra.Command{
+	Use:   "add <tool-id>",
+	Short: "Add a tool to the config file",
+	Long: `Add a tool at the end of the config's tools. The rest of the file (comments, anchors,
+the variables section, formatting) is left untouched.
+
+--preset merges an anchor defined in the config (e.g. one under variables.bashSources) into the
+tool's scripts. --repo and --asset-regex are stored in the tool's extra section, where the GitHub
+scripts read them. --symlink takes from[:to] and can be repeated.
+
+Examples:
+  tvm add rg --preset bashScriptsTar --repo BurntSushi/ripgrep --symlink rg
+  tvm add gh --preset bashScriptsTar --repo cli/cli --asset-regex 'linux_amd64.tar.gz$' --symlink bin/gh`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		toolID := args[0]
+		if _, err := getToolById(toolID); err == nil {
+			return fmt.Errorf("tool %s already exists", toolID)
+		}
+		tvm, err := manager.Registry().GetTVM(addType)
+		if err != nil {
+			return err
+		}
+		if pluginTVM, ok := tvm.(*plugintvm.PluginTVM); ok {
+			if err := pluginTVM.Resolve(); err != nil {
+				return fmt.Errorf("no backend for type %s: %w", addType, err)
+			}
+		}
+
+		editor, err := config.NewConfigEditor(configService.GetConfigFilePath(), manager.Registry())
+		if err != nil {
+			return err
+		}
+		if addPreset != "" {
+			found, err := editor.HasAnchor(addPreset)
+			if err != nil {
+				return err
+			}
+			if !found {
+				return fmt.Errorf("preset %s is not defined in %s, it must be an anchor like &%s", addPreset, configService.GetConfigFilePath(), addPreset)
+			}
+		}
+
+		item, err := renderToolItem(toolID)
+		if err != nil {
+			return err
+		}
+		if err := editor.AppendToSequence("tools", item); err != nil {
+			return fmt.Errorf("failed to add tool %s: %w", toolID, err)
+		}
+		if err := editor.Save(); err != nil {
+			return fmt.Errorf("failed to add tool %s: %w", toolID, err)
+		}
+
+		fmt.Printf("Added %s to %s\n", toolID, configService.GetConfigFilePath())
+		if addPreset == "" {
+			fmt.Println("It has no scripts yet, add them under source.scripts or use --preset next time.")
+		}
+		return nil
+	},
+}
+
+// renderToolItem renders the config entry of the tool being added
+func renderToolItem(toolID string) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "id: %s\n", quoteYAML(toolID))
+	fmt.Fprintf(&b, "type: %s\n", quoteYAML(addType))
+
+	if len(addSymlinks) > 0 {
+		b.WriteString("symlinks:\n")
+		for _, symlink := range addSymlinks {
+			from, to, _ := strings.Cut(symlink, ":")
+			if strings.TrimSpace(from) == "" {
+				return "", fmt.Errorf("invalid symlink %q, expected from[:to]", symlink)
+			}
+			fmt.Fprintf(&b, "  - from: %s\n", quoteYAML(from))
+			if to != "" {
+				fmt.Fprintf(&b, "    to: %s\n", quoteYAML(to))
+			}
+		}
+	}
+
+	if addPreset != "" {
+		fmt.Fprintf(&b, "source:\n  scripts:\n    <<: *%s\n", addPreset)
+	}
+
+	if addRepo != "" || addAssetRegex != "" {
+		b.WriteString("extra:\n")
+		if addRepo != "" {
+			fmt.Fprintf(&b, "  Repo: %s\n", quoteYAML(addRepo))
+		}
+		if addAssetRegex != "" {
+			fmt.Fprintf(&b, "  AssetRegex: %s\n", quoteYAML(addAssetRegex))
+		}
+	}
+	return b.String(), nil
+}
+
+// quoteYAML renders a string as a YAML scalar, quoted only if needed
+func quoteYAML(s string) string {
+	out, err := yaml.Marshal(s)
+	if err != nil {
+		return fmt.Sprintf("%q", s)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Edit the config file",
+	Long: `Edit the config file in place. Only the edited lines change, comments, anchors,
+the variables section and formatting are kept as they are.`,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <path> <value>",
+	Short: "Set a value in the config file",
+	Long: `Set a scalar value at a dotted path, creating missing keys. Tools are selected by their id.
+
+Examples:
+  tvm config set remote_versions_cache_max_age 12h
+  tvm config set tools.rg.channel prerelease
+  tvm config set tools.gh.extra.Repo cli/cli`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		editor, err := config.NewConfigEditor(configService.GetConfigFilePath(), manager.Registry())
+		if err != nil {
+			return err
+		}
+		if err := editor.Set(args[0], args[1]); err != nil {
+			return fmt.Errorf("failed to set %s: %w", args[0], err)
+		}
+		if err := editor.Save(); err != nil {
+			return fmt.Errorf("failed to set %s: %w", args[0], err)
+		}
+		fmt.Printf("Set %s to %s\n", args[0], args[1])
+		return nil
+	},
+}
+
+var configRemoveToolCmd = &cobra.Command{
+	Use:   "remove-tool <tool-id>",
+	Short: "Remove a tool from the config file",
+	Long: `Remove a tool from the config file. Its installed versions and links are left in place,
+unlink it first if they should go too.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		toolID := args[0]
+		editor, err := config.NewConfigEditor(configService.GetConfigFilePath(), manager.Registry())
+		if err != nil {
+			return err
+		}
+		if err := editor.RemoveFromSequence("tools", "id", toolID); err != nil {
+			return fmt.Errorf("failed to remove tool %s: %w", toolID, err)
+		}
+		if err := editor.Save(); err != nil {
+			return fmt.Errorf("failed to remove tool %s: %w", toolID, err)
+		}
+		fmt.Printf("Removed %s from %s\n", toolID, configService.GetConfigFilePath())
+		return nil
+	},
+}
+
+func init() {
+	addCmd.Flags().StringVar(&addType, "type", "scripts_driven", "Tool type")
+	addCmd.Flags().StringVar(&addPreset, "preset", "", "Anchor in the config to merge into the tool's scripts, e.g. bashScriptsTar")
+	addCmd.Flags().StringVar(&addRepo, "repo", "", "GitHub repository, owner/name")
+	addCmd.Flags().StringVar(&addAssetRegex, "asset-regex", "", "Regex matching the release asset to download")
+	addCmd.Flags().StringArrayVar(&addSymlinks, "symlink", nil, "Binary to link, from[:to] (repeatable)")
+	RootCmd.AddCommand(addCmd)
+
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configRemoveToolCmd)
+	RootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"rayyanriaz/tool-version-manager/pkg/tvm"
+
+	"github.com/spf13/cobra"
+)
+
+var daemonOnce bool
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Check for updates in the background",
+	Long: `Refresh the latest remote versions in the background, so that 'table', 'outdated' and 'upgrade' don't have to.
+Every check fetches only the versions whose cache entry is older than 'remote_versions_cache_max_age', and then
+upgrades the linked tools marked 'auto_upgrade: true' that are behind, to the newest version their 'constraint' allows.
+
+Checks run every 'daemon_interval' (default 1h) plus a random delay of up to 'daemon_jitter' (default 10m).
+Each check loads the config again, so edits are picked up without a restart. The outcome of the last check is
+written to the state directory, and 'tvm table' shows when it ran.
+
+SIGTERM or SIGINT stop the daemon after the operations in progress have finished.
+
+Examples:
+  tvm daemon                # run until stopped, e.g. as a systemd user service
+  tvm daemon --once         # run a single check, e.g. from cron`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGTERM, os.Interrupt)
+		defer stop()
+		return tvm.RunDaemon(ctx, managerOptions(), tvm.DaemonOptions{Once: daemonOnce})
+	},
+}
+
+func init() {
+	daemonCmd.Flags().BoolVar(&daemonOnce, "once", false, "Run a single check and exit")
+	RootCmd.AddCommand(daemonCmd)
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"rayyanriaz/tool-version-manager/pkg/impl/state"
+	"rayyanriaz/tool-version-manager/pkg/tvm"
+
+	"github.com/spf13/cobra"
+)
+
+var doctorClear bool
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Report interrupted operations that tvm recovered, and operations still in progress",
+	Long: `Install, link and unlink operations are written to a journal in the state directory before
+anything is changed. If tvm is killed halfway, the next tvm invocation finds the incomplete entry and
+recovers it: interrupted links are reverted to the previous version, interrupted unlinks are completed,
+and partial installs are removed. Versions that were installed before, or are linked, are kept and marked as
+unverified if their content changed. This command lists what was recovered.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recovered, err := journal.Recovered()
+		if err != nil {
+			return fmt.Errorf("failed to read recovered journal entries: %w", err)
+		}
+		pending, err := journal.Pending()
+		if err != nil {
+			return fmt.Errorf("failed to read journal: %w", err)
+		}
+
+		if len(recovered) == 0 {
+			fmt.Println("No interrupted operations were recovered.")
+		} else {
+			fmt.Printf("Recovered %d interrupted operation(s):\n", len(recovered))
+			for _, r := range recovered {
+				fmt.Printf("  %s  %s %s: %s\n", r.RecoveredAt.Local().Format(time.DateTime), r.Entry.Operation, describeJournalEntry(r.Entry), r.Resolution)
+				if r.Error != "" {
+					fmt.Printf("    error: %s\n", r.Error)
+				}
+			}
+		}
+
+		if len(pending) > 0 {
+			fmt.Printf("\n%d operation(s) in progress in other tvm processes:\n", len(pending))
+			for _, entry := range pending {
+				fmt.Printf("  %s  %s %s (pid %d)\n", entry.StartedAt.Local().Format(time.DateTime), entry.Operation, describeJournalEntry(entry), entry.Pid)
+			}
+		}
+
+		if doctorClear && len(recovered) > 0 {
+			if err := journal.ClearRecovered(); err != nil {
+				return fmt.Errorf("failed to clear recovered journal entries: %w", err)
+			}
+			fmt.Println("\nCleared the list of recovered operations.")
+		}
+		return nil
+	},
+}
+
+func describeJournalEntry(entry state.JournalEntry) string {
+	switch entry.Operation {
+	case state.JournalOperationLink:
+		return fmt.Sprintf("%s %s -> %s", entry.ToolID, tvm.DescribeVersion(entry.PreviousVersion), entry.Version)
+	case state.JournalOperationUnlink:
+		return fmt.Sprintf("%s %s", entry.ToolID, tvm.DescribeVersion(entry.PreviousVersion))
+	default:
+		return fmt.Sprintf("%s %s", entry.ToolID, entry.Version)
+	}
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorClear, "clear", false, "Clear the list of recovered operations after showing it")
+	RootCmd.AddCommand(doctorCmd)
+}
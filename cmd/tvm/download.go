@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"rayyanriaz/tool-version-manager/pkg/models"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	downloadKey     string
+	downloadURL     string
+	downloadDest    string
+	downloadRefetch bool
+)
+
+var downloadCmd = &cobra.Command{
+	Use:   "_download --key <key> --url <url> -o <file>",
+	Short: "Download a file through the artifact cache (for install scripts)",
+	Long: `Write the artifact cached under --key to -o, downloading it from --url first if it isn't cached.
+Artifacts are stored by their sha256 in the artifacts dir, so they are kept after the install scripts
+delete their copy, and versions can be reinstalled without the network.
+
+In offline mode --url may be empty, since resolving it usually needs the network. The URL the
+artifact was downloaded from is printed, so scripts can still report it.
+
+--refetch (or TVM_REFETCH=1) downloads the artifact again and replaces the cached one. tvm sets
+TVM_REFETCH for the scripts that refetch a moving tag rebuilt upstream, whose cached artifact is the old build.
+
+Example, in a fetchToolForVersion script:
+  url=$("{{.Config.Tvm}}" _download --key "{{.Tool.Id}}/{{.Version}}/asset" --url "$url" -o "$out")`,
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// scripts show the tail of the output when they fail, which should be the error alone
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		if strings.TrimSpace(downloadKey) == "" || downloadDest == "" {
+			return fmt.Errorf("--key and -o are required")
+		}
+
+		if os.Getenv("TVM_REFETCH") != "" {
+			downloadRefetch = true
+		}
+		if downloadRefetch && offline {
+			return fmt.Errorf("cannot download artifact %s again: %w", downloadKey, models.ErrOffline)
+		}
+
+		if artifact, found := artifactCache.Lookup(downloadKey); found && !downloadRefetch {
+			err := artifactCache.CopyTo(artifact, downloadDest)
+			if err == nil {
+				slog.Debug("Using cached artifact", "key", downloadKey, "digest", artifact.Digest)
+				fmt.Println(artifact.URL)
+				return nil
+			}
+			if offline || downloadURL == "" {
+				return err
+			}
+			slog.Warn("Downloading the artifact again", "key", downloadKey, "error", err)
+		}
+
+		if offline {
+			return fmt.Errorf("artifact %s is not cached: %w", downloadKey, models.ErrOffline)
+		}
+		if downloadURL == "" {
+			return fmt.Errorf("artifact %s is not cached and no --url was given", downloadKey)
+		}
+		artifact, err := artifactCache.Download(downloadKey, downloadURL)
+		if err != nil {
+			return err
+		}
+		if err := artifactCache.CopyTo(artifact, downloadDest); err != nil {
+			return err
+		}
+		fmt.Println(artifact.URL)
+		return nil
+	},
+}
+
+func init() {
+	downloadCmd.Flags().StringVar(&downloadKey, "key", "", "Cache key of the artifact, e.g. <tool>/<version>/<asset>")
+	downloadCmd.Flags().StringVar(&downloadURL, "url", "", "URL to download the artifact from if it isn't cached")
+	downloadCmd.Flags().StringVarP(&downloadDest, "output", "o", "", "File to write the artifact to")
+	downloadCmd.Flags().BoolVar(&downloadRefetch, "refetch", false, "Download the artifact again even if it is cached (default: $TVM_REFETCH)")
+	RootCmd.AddCommand(downloadCmd)
+}
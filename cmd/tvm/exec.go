@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"rayyanriaz/tool-version-manager/pkg/models"
+
+	"github.com/spf13/cobra"
+)
+
+var execInstall bool
+
+var execCmd = &cobra.Command{
+	Use:   "exec <tool-id>@<version> [--install] -- <command> [args...]",
+	Short: "Run a command with a specific installed version of a tool, without linking it",
+	Long: `Run a command against an installed version of a tool. The linked version stays as it is.
+
+If the command is one of the tool's link names, it runs that version's binary directly. The version's
+binary directories are also put first on PATH, so scripts that call the tool get the same version.
+The exit code of the command is passed through unchanged.
+
+Examples:
+  tvm exec rg@13.0.0 -- rg --version
+  tvm exec rg@14.1.0 --install -- rg -n TODO .
+  tvm exec node@20.11.0 -- npm test`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if dash := cmd.ArgsLenAtDash(); dash != 1 || len(args) < 2 {
+			return fmt.Errorf("expected <tool-id>@<version> -- <command> [args...]")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		toolID, rawVersion, found := strings.Cut(args[0], "@")
+		if !found || toolID == "" || rawVersion == "" {
+			return fmt.Errorf("invalid %q, expected <tool-id>@<version>", args[0])
+		}
+		version := models.ToolVersion(rawVersion)
+
+		tool, tvm, err := getToolWithTVM(toolID)
+		if err != nil {
+			return err
+		}
+
+		binaries, err := toolVersionBinaries(tool, version)
+		if err != nil {
+			if !execInstall {
+				return fmt.Errorf("%w, pass --install to install it first", err)
+			}
+			if err := installForExec(tool, tvm, version); err != nil {
+				return err
+			}
+			if binaries, err = toolVersionBinaries(tool, version); err != nil {
+				return err
+			}
+		}
+
+		path := execPath(binaries)
+		name := args[1]
+		if binary, ok := binaries[name]; ok {
+			name = binary
+		} else if name, err = lookPathIn(name, path); err != nil {
+			return err
+		}
+		child := exec.Command(name, args[2:]...)
+		child.Stdin = os.Stdin
+		child.Stdout = os.Stdout
+		child.Stderr = os.Stderr
+		child.Env = append(os.Environ(), "PATH="+path)
+
+		// the terminal sends Ctrl-C to the child as well, tvm only has to outlive it to report its exit code.
+		// SIGTERM usually targets tvm alone, so it is passed on.
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
+		defer signal.Stop(signals)
+
+		if err := child.Start(); err != nil {
+			return fmt.Errorf("failed to run %s: %w", args[1], err)
+		}
+		go func() {
+			for sig := range signals {
+				if sig == syscall.SIGTERM {
+					_ = child.Process.Signal(sig)
+				}
+			}
+		}()
+		if err := child.Wait(); err != nil {
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				return fmt.Errorf("failed to run %s: %w", args[1], err)
+			}
+			cmd.SilenceErrors = true
+			return &ExitCodeError{Code: exitCodeOf(exitErr)}
+		}
+		return nil
+	},
+}
+
+// installForExec installs a version of a tool without linking it
+func installForExec(tool models.Tool, tvm models.ToolVersionManager, version models.ToolVersion) error {
+	lock, err := lockTool(tool.GetId())
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	fmt.Fprintf(os.Stderr, "Installing %s version %s...\n", tool.GetId(), version)
+	if err := installToolVersion(tool, tvm, version); err != nil {
+		return fmt.Errorf("failed to install %s version %s: %w", tool.GetId(), version, err)
+	}
+	return nil
+}
+
+// execPath is PATH with the directories of the given binaries in front
+func execPath(binaries map[string]string) string {
+	var dirs []string
+	for _, linkName := range slices.Sorted(maps.Keys(binaries)) {
+		if dir := filepath.Dir(binaries[linkName]); !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return strings.Join(append(dirs, filepath.SplitList(os.Getenv("PATH"))...), string(os.PathListSeparator))
+}
+
+// lookPathIn finds the executable a command name runs with the given PATH, like the shell would. exec.LookPath
+// can't be used, it searches tvm's own PATH, which doesn't have the version's binary directories.
+func lookPathIn(name, path string) (string, error) {
+	if strings.Contains(name, string(os.PathSeparator)) {
+		return name, nil
+	}
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			dir = "."
+		}
+		candidate := filepath.Join(dir, name)
+		if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() && info.Mode()&0111 != 0 {
+			return filepath.Abs(candidate)
+		}
+	}
+	return "", fmt.Errorf("%s: %w", name, exec.ErrNotFound)
+}
+
+// exitCodeOf returns the exit code of a finished command, using the shell's 128+signal convention for signals
+func exitCodeOf(exitErr *exec.ExitError) int {
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return exitErr.ExitCode()
+}
+
+func init() {
+	execCmd.Flags().BoolVar(&execInstall, "install", false, "Install the version first if it isn't installed")
+	RootCmd.AddCommand(execCmd)
+}
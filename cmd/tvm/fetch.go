@@ -2,15 +2,18 @@ package cmd
 
 import (
 	"fmt"
-	"strings"
-	"sync"
+	"time"
 
-	"rayyanriaz/tool-version-manager/pkg/models"
+	"rayyanriaz/tool-version-manager/pkg/tvm"
 
 	"github.com/spf13/cobra"
 )
 
-var fetchAll bool
+var (
+	fetchAll       bool
+	fetchStaleOnly bool
+	fetchMaxAge    time.Duration
+)
 
 var fetchCmd = &cobra.Command{
 	Use:   "fetch [tool-id]",
@@ -18,10 +21,16 @@ var fetchCmd = &cobra.Command{
 	Long: `Fetch the latest available versions from remote sources and cache them locally.
 This allows other commands like 'table' to show remote version info without making network requests.
 
+Cached versions are considered fresh for 'remote_versions_cache_max_age' (default 24h).
+Use --stale-only to skip tools whose cached version is still fresh, and --max-age to
+override the freshness window (implies --stale-only).
+
 Examples:
-  tvm fetch --all       # Fetch latest versions for all tools
-  tvm fetch ripgrep     # Fetch latest version for a specific tool
-  tvm fetch rg,fzf,fd   # Fetch latest versions for multiple tools`,
+  tvm fetch --all                    # Fetch latest versions for all tools
+  tvm fetch --all --stale-only       # Fetch only the tools whose cached version is stale
+  tvm fetch --all --max-age 1h       # Fetch only the tools checked more than an hour ago
+  tvm fetch ripgrep                  # Fetch latest version for a specific tool
+  tvm fetch rg,fzf,fd                # Fetch latest versions for multiple tools`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if !fetchAll && len(args) == 0 {
@@ -31,96 +40,52 @@ Examples:
 		if fetchAll && len(args) > 0 {
 			return fmt.Errorf("cannot use --all with specific tool IDs")
 		}
+		var toolIDs []string
+		if !fetchAll {
+			toolIDs = splitToolIDs(args[0])
+		}
+		opts := tvm.FetchOptions{ToolIDs: toolIDs, StaleOnly: fetchStaleOnly}
+		if cmd.Flags().Changed("max-age") {
+			opts.StaleOnly = true
+			opts.MaxAge = fetchMaxAge
+		}
 
-		allTools, err := getAllTools()
+		results, err := manager.Fetch(cmd.Context(), opts)
 		if err != nil {
-			return fmt.Errorf("failed to get all tools: %w", err)
+			return err
 		}
-
-		var toolIDs []string
-		if fetchAll {
-			toolIDs = make([]string, len(allTools))
-			for i, tool := range allTools {
-				toolIDs[i] = tool.Wrapped.GetId()
-			}
-		} else {
-			toolIDs = strings.Split(args[0], ",")
-			for i := range toolIDs {
-				toolIDs[i] = strings.TrimSpace(toolIDs[i])
-				if toolIDs[i] == "" {
-					return fmt.Errorf("invalid empty tool ID in input")
-				}
-				// toolId should exist in the tools list
-				if _, err := getToolById(toolIDs[i]); err != nil {
-					return fmt.Errorf("tool %s does not exist: %w", toolIDs[i], err)
-				}
+		if opts.StaleOnly && len(results) == 0 {
+			maxAge := opts.MaxAge
+			if maxAge == 0 {
+				maxAge = configService.GetRemoteVersionsCacheMaxAge()
 			}
+			fmt.Printf("All cached versions are fresher than %s, nothing to fetch\n", maxAge)
+			return nil
 		}
 
-		return fetchLatestVersions(toolIDs)
-	},
-}
-
-func fetchLatestVersions(toolIDs []string) error {
-	var wg sync.WaitGroup
-	results := make([]struct {
-		toolID  string
-		version models.ToolVersion
-		err     error
-	}, len(toolIDs))
-
-	for i, toolID := range toolIDs {
-		wg.Add(1)
-		go func(i int, toolID string) {
-			defer wg.Done()
-			tool, tvm, err := getToolWithTVM(toolID)
-			if err != nil {
-				results[i] = struct {
-					toolID  string
-					version models.ToolVersion
-					err     error
-				}{toolID, "", err}
-				return
+		var hasErrors bool
+		for _, result := range results {
+			if result.Err != nil {
+				fmt.Printf("Failed to fetch %s: %v\n", result.ToolID, result.Err)
+				hasErrors = true
+				continue
 			}
 
-			version, err := tvm.GetLatestRemoteVersion(tool)
-			results[i] = struct {
-				toolID  string
-				version models.ToolVersion
-				err     error
-			}{toolID, version, err}
-		}(i, toolID)
-	}
-
-	wg.Wait()
-
-	// Process results and update cache
-	var hasErrors bool
-	for _, result := range results {
-		if result.err != nil {
-			fmt.Printf("Failed to fetch %s: %v\n", result.toolID, result.err)
-			hasErrors = true
-			continue
+			fmt.Printf("%s: %s\n", result.ToolID, result.Version)
 		}
 
-		if err := updateCachedLatestVersion(result.toolID, result.version); err != nil {
-			fmt.Printf("Failed to cache %s: %v\n", result.toolID, err)
-			hasErrors = true
-			continue
+		if hasErrors {
+			return fmt.Errorf("some tools failed to fetch")
 		}
 
-		fmt.Printf("%s: %s\n", result.toolID, result.version)
-	}
-
-	if hasErrors {
-		return fmt.Errorf("some tools failed to fetch")
-	}
-
-	fmt.Printf("\nSuccessfully fetched and cached latest versions for %d tools\n", len(toolIDs))
-	return nil
+		fmt.Printf("\nSuccessfully fetched and cached latest versions for %d tools\n", len(results))
+		return nil
+	},
 }
 
 func init() {
 	fetchCmd.Flags().BoolVarP(&fetchAll, "all", "a", false, "Fetch latest versions for all tools")
+	fetchCmd.Flags().BoolVar(&fetchStaleOnly, "stale-only", false, "Only fetch tools whose cached version is older than the max age")
+	fetchCmd.Flags().DurationVar(&fetchMaxAge, "max-age", 0, "Override the cache max age, e.g. 1h or 30m (implies --stale-only)")
 	RootCmd.AddCommand(fetchCmd)
 }
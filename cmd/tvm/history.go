@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var rollbackSteps int
+
+var historyCmd = &cobra.Command{
+	Use:   "history <tool-id>",
+	Short: "Show the link and unlink history of a tool",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		toolID := args[0]
+
+		if _, err := getToolById(toolID); err != nil {
+			return err
+		}
+
+		events := linkHistory.List(toolID)
+		if len(events) == 0 {
+			fmt.Printf("No link history for %s\n", toolID)
+			return nil
+		}
+
+		headers := []string{"At", "Action", "Version", "Previous"}
+		widths := []int{19, 6, 15, 15}
+		printTableRow(headers, widths, true, nil)
+		var sep []string
+		for _, width := range widths {
+			sep = append(sep, strings.Repeat("-", width))
+		}
+		printTableRow(sep, widths, false, nil)
+		for i := len(events) - 1; i >= 0; i-- {
+			event := events[i]
+			printTableRow([]string{
+				event.At.Local().Format(time.DateTime),
+				string(event.Action),
+				valueOrNA(string(event.Version)),
+				valueOrNA(string(event.PreviousVersion)),
+			}, widths, false, nil)
+		}
+		return nil
+	},
+}
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback <tool-id>",
+	Short: "Relink the version that was linked before the last link or unlink",
+	Long: `Relink the version of a tool that was linked before its last link or unlink.
+With --steps N, go back N events in the link history. The version is reinstalled if it was pruned.
+
+Examples:
+  tvm rollback ripgrep            # undo the last link/unlink of ripgrep
+  tvm rollback ripgrep --steps 2  # relink the version linked before the last two events`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		toolID := args[0]
+
+		tool, tvm, err := getToolWithTVM(toolID)
+		if err != nil {
+			return err
+		}
+
+		lock, err := lockTool(toolID)
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock()
+
+		version, err := linkHistory.RollbackTarget(toolID, rollbackSteps)
+		if err != nil {
+			return err
+		}
+
+		installed, err := isInstalled(tool, tvm, version)
+		if err != nil {
+			return fmt.Errorf("failed to get local versions for %s: %w", toolID, err)
+		}
+		if !installed {
+			fmt.Printf("%s version %s is no longer installed, reinstalling...\n", toolID, version)
+			if err := installToolVersion(tool, tvm, version); err != nil {
+				return fmt.Errorf("failed to reinstall %s version %s: %w", toolID, version, err)
+			}
+		}
+
+		fmt.Printf("Rolling back %s to version %s...\n", toolID, version)
+		if err := linkToolVersion(tool, tvm, version, false); err != nil {
+			return fmt.Errorf("failed to link %s version %s: %w", toolID, version, err)
+		}
+
+		fmt.Printf("Successfully rolled back %s to version %s\n", toolID, version)
+		return nil
+	},
+}
+
+func init() {
+	rollbackCmd.Flags().IntVarP(&rollbackSteps, "steps", "n", 1, "Number of link events to go back")
+
+	RootCmd.AddCommand(historyCmd)
+	RootCmd.AddCommand(rollbackCmd)
+}
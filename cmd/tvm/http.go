@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"rayyanriaz/tool-version-manager/pkg/impl/httpclient"
+	"rayyanriaz/tool-version-manager/pkg/models"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	httpHeaders []string
+	httpOutput  string
+)
+
+var httpCmd = &cobra.Command{
+	Use:   "_http <url> [-H 'Name: value']... [-o <file>]",
+	Short: "Fetch a URL like tvm's own API calls do (for scripts)",
+	Long: `Fetch a URL and print the response body, with the same behavior as tvm's own requests: mirrors apply,
+server errors are retried with backoff, short rate limit resets are waited for and long ones fail with a message
+saying what to do. Responses that aren't 2xx fail instead of being printed, so an API error body never ends up
+parsed as data. Requests to api.github.com are authenticated with the configured GitHub token.
+
+Example, in a getLatestRemoteVersion script:
+  rel=$("{{.Config.Tvm}}" _http "https://api.github.com/repos/{{.Tool.Extra.Repo}}/releases/latest") || exit 1
+  echo "$rel" | jq -r .tag_name`,
+	Hidden: true,
+	Args:   cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// scripts show the tail of stderr when they fail, which should be the error alone
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		url := args[0]
+		if offline {
+			return fmt.Errorf("can't fetch %s: %w", url, models.ErrOffline)
+		}
+
+		header := http.Header{}
+		for _, h := range httpHeaders {
+			name, value, found := strings.Cut(h, ":")
+			if !found || strings.TrimSpace(name) == "" {
+				return fmt.Errorf("invalid header %q, expected 'Name: value'", h)
+			}
+			header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+		}
+		if httpclient.IsGitHubAPI(url) && configService.GitHubToken != "" && header.Get("Authorization") == "" {
+			header.Set("Authorization", "token "+configService.GitHubToken)
+		}
+
+		resp, err := manager.HTTPClient().Get(url, header)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		out := io.Writer(os.Stdout)
+		if httpOutput != "" {
+			f, err := os.Create(httpOutput)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		if _, err := io.Copy(out, resp.Body); err != nil {
+			return fmt.Errorf("failed to read response of %s: %w", url, err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	httpCmd.Flags().StringArrayVarP(&httpHeaders, "header", "H", nil, "Request header, 'Name: value' (repeatable)")
+	httpCmd.Flags().StringVarP(&httpOutput, "output", "o", "", "File to write the response body to instead of stdout")
+	RootCmd.AddCommand(httpCmd)
+}
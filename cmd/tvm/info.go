@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var infoCmd = &cobra.Command{
+	Use:   "info <tool-id>",
+	Short: "Show link status, latest remote version and install metadata of a tool",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		toolID := args[0]
+
+		tool, tvm, err := getToolWithTVM(toolID)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Tool:           %s (%s)\n", tool.GetId(), tool.GetType())
+
+		linkedVersion := ""
+		if linkInfo, err := tvm.GetLinkInfo(tool); err != nil {
+			fmt.Printf("Linked version: unknown (%v)\n", err)
+		} else if linkInfo.Version == "" {
+			fmt.Printf("Linked version: %s\n", NA)
+		} else {
+			linkedVersion = string(linkInfo.Version)
+			fmt.Printf("Linked version: %s (linked at %s)\n", linkInfo.Version, formatLinkedAt(linkInfo.LinkedAt))
+		}
+
+		if latestVersion, err := getLatestVersion(tool, tvm); err != nil {
+			fmt.Printf("Latest remote:  %s (%v)\n", NA, err)
+		} else {
+			_, checkedAt, _ := manager.CachedLatestVersion(tool)
+			fmt.Printf("Latest remote:  %s (checked %s)\n", latestVersion, formatAge(checkedAt))
+		}
+
+		localVersions, err := tvm.GetAllLocalVersions(tool)
+		if err != nil {
+			return fmt.Errorf("failed to get local versions for %s: %w", toolID, err)
+		}
+		defer printBrokenVersions(toolID)
+		if len(localVersions) == 0 {
+			fmt.Println("Installed:      none")
+			return nil
+		}
+
+		fmt.Println("Installed:")
+		for _, version := range localVersions {
+			marker := ""
+			if string(version) == linkedVersion {
+				marker = " (linked)"
+			}
+			fmt.Printf("  %s%s\n", version, marker)
+
+			record, found := installStore.Get(toolID, version)
+			if !found {
+				fmt.Println("    no install metadata recorded")
+				continue
+			}
+			fmt.Printf("    installed at:  %s\n", record.InstalledAt.Local().Format(time.DateTime))
+			if record.Unverified != "" {
+				fmt.Printf("    unverified:    %s\n", record.Unverified)
+			}
+			fmt.Printf("    size:          %s\n", formatSize(record.SizeBytes))
+			if record.Adopted {
+				fmt.Println("    installed by:  unknown (found on disk)")
+				continue
+			}
+			fmt.Printf("    source:        %s\n", valueOrNA(record.SourceURL))
+			fmt.Printf("    digest:        %s\n", valueOrNA(record.Digest))
+			fmt.Printf("    installed by:  tvm %s, config %s\n", record.TvmVersion, shortDigest(record.ConfigDigest))
+		}
+		return nil
+	},
+}
+
+// printBrokenVersions lists versions that failed to install and couldn't be removed
+func printBrokenVersions(toolID string) {
+	for _, record := range installStore.List(toolID) {
+		if record.Broken != "" {
+			fmt.Printf("Broken:         %s: %s\n", record.Version, record.Broken)
+		}
+	}
+}
+
+func valueOrNA(s string) string {
+	if s == "" {
+		return NA
+	}
+	return s
+}
+
+// shortDigest shortens "sha256:<hex>" to its first 12 hex characters
+func shortDigest(digest string) string {
+	const prefix = "sha256:"
+	if len(digest) > len(prefix)+12 {
+		return digest[len(prefix) : len(prefix)+12]
+	}
+	return valueOrNA(digest)
+}
+
+// formatSize renders a size in bytes in human readable units
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func init() {
+	RootCmd.AddCommand(infoCmd)
+}
@@ -0,0 +1,312 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"rayyanriaz/tool-version-manager/pkg/models"
+	"rayyanriaz/tool-version-manager/pkg/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// projectVersionsFile pins tool versions for a directory tree, one `<tool> <version>` per line
+const projectVersionsFile = ".tvm-versions"
+
+// projectPathEnv remembers which PATH entries the directory hook added, so they can be taken out again
+const projectPathEnv = "TVM_PROJECT_PATH"
+
+var initHook bool
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var supportedShells = []string{"bash", "zsh", "fish"}
+
+var initCmd = &cobra.Command{
+	Use:   "init <bash|zsh|fish>",
+	Short: "Print shell integration for your rc file",
+	Long: `Print a snippet that puts the symlinks dir on PATH, exports the tools' 'env' variables and the
+absolute path of the config as TVM_CONFIG, and loads tvm's completion. Sourcing it more than once doesn't add
+PATH entries twice.
+
+With --hook, the snippet also re-evaluates ` + projectVersionsFile + ` whenever the directory changes. The file
+pins versions for a project, one '<tool> <version>' per line, and is looked up from the current directory
+upwards. Pinned versions that are installed come first on PATH, without changing the linked versions.
+
+Examples:
+  echo 'eval "$(tvm init bash)"' >> ~/.bashrc
+  echo 'eval "$(tvm init zsh --hook)"' >> ~/.zshrc
+  echo 'tvm init fish | source' >> ~/.config/fish/config.fish`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: supportedShells,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		shell := args[0]
+		if !slices.Contains(supportedShells, shell) {
+			return fmt.Errorf("unsupported shell %q, must be one of %s", shell, strings.Join(supportedShells, ", "))
+		}
+
+		tvmBin, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("failed to locate the tvm executable: %w", err)
+		}
+		symlinksDir, err := filepath.Abs(configService.SymlinksDir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve symlinks dir: %w", err)
+		}
+
+		tools, err := getAllTools()
+		if err != nil {
+			return fmt.Errorf("failed to get tools: %w", err)
+		}
+		env, err := renderToolsEnv(tools)
+		if err != nil {
+			return err
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "# tvm shell integration, generated by `tvm init %s`\n", shell)
+		writePathPrepend(&b, shell, symlinksDir)
+		// the default config is relative to the current directory, so without this the hook and interactive
+		// commands would look for it in whatever directory the shell is in
+		absConfig, err := filepath.Abs(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to resolve config path: %w", err)
+		}
+		writeExport(&b, shell, "TVM_CONFIG", absConfig)
+		for _, kv := range env {
+			writeExport(&b, shell, kv[0], kv[1])
+		}
+		writeCompletion(&b, shell, tvmBin)
+		if initHook {
+			writeHook(&b, shell, tvmBin)
+		}
+		fmt.Print(b.String())
+		return nil
+	},
+}
+
+// renderToolsEnv renders the 'env' of all tools as sorted name/value pairs
+func renderToolsEnv(tools models.UniqueToolWrappers) ([][2]string, error) {
+	var env [][2]string
+	owners := make(map[string]string)
+	for _, toolWrapper := range tools {
+		tool := toolWrapper.Wrapped
+		toolDir := filepath.Join(configService.DownloadsDir, tool.GetId())
+		vars := map[string]any{
+			"ToolDir":     toolDir,
+			"CurrentDir":  filepath.Join(toolDir, "current"),
+			"SymlinksDir": configService.SymlinksDir,
+		}
+		for name, tmpl := range tool.GetEnv() {
+			if !envNamePattern.MatchString(name) {
+				return nil, fmt.Errorf("invalid environment variable name %q in tool %s", name, tool.GetId())
+			}
+			if owner, exists := owners[name]; exists {
+				return nil, fmt.Errorf("environment variable %s is set by both tools %s and %s", name, owner, tool.GetId())
+			}
+			owners[name] = tool.GetId()
+			value, err := utils.RenderTemplate(tmpl, vars, configService.GetMirrors())
+			if err != nil {
+				return nil, fmt.Errorf("failed to render env %s of tool %s: %w", name, tool.GetId(), err)
+			}
+			env = append(env, [2]string{name, value})
+		}
+	}
+	sort.Slice(env, func(i, j int) bool { return env[i][0] < env[j][0] })
+	return env, nil
+}
+
+func writePathPrepend(b *strings.Builder, shell, dir string) {
+	if shell == "fish" {
+		fmt.Fprintf(b, "contains -- %s $PATH; or set -gx PATH %s $PATH\n", shellQuote(dir), shellQuote(dir))
+		return
+	}
+	fmt.Fprintf(b, "case \":$PATH:\" in\n  *:%s:*) ;;\n  *) export PATH=%s\"${PATH:+:$PATH}\" ;;\nesac\n", shellQuote(dir), shellQuote(dir))
+}
+
+func writeExport(b *strings.Builder, shell, name, value string) {
+	if shell == "fish" {
+		fmt.Fprintf(b, "set -gx %s %s\n", name, shellQuote(value))
+		return
+	}
+	fmt.Fprintf(b, "export %s=%s\n", name, shellQuote(value))
+}
+
+func writeCompletion(b *strings.Builder, shell, tvmBin string) {
+	bin := shellQuote(tvmBin)
+	switch shell {
+	case "bash":
+		fmt.Fprintf(b, "source <(%s completion bash)\n", bin)
+	case "zsh":
+		// compdef only exists once compinit has run
+		fmt.Fprintf(b, "if (( $+functions[compdef] )); then\n  source <(%s completion zsh)\nfi\n", bin)
+	case "fish":
+		fmt.Fprintf(b, "%s completion fish | source\n", bin)
+	}
+}
+
+func writeHook(b *strings.Builder, shell, tvmBin string) {
+	bin := shellQuote(tvmBin)
+	switch shell {
+	case "bash":
+		fmt.Fprintf(b, `_tvm_hook() {
+  [[ "$PWD" == "${_TVM_LAST_PWD-}" ]] && return
+  _TVM_LAST_PWD="$PWD"
+  eval "$(%s hook-env bash)"
+}
+case ";${PROMPT_COMMAND-};" in
+  *";_tvm_hook;"*) ;;
+  *) PROMPT_COMMAND="_tvm_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
+`, bin)
+	case "zsh":
+		fmt.Fprintf(b, `_tvm_hook() {
+  eval "$(%s hook-env zsh)"
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook chpwd _tvm_hook
+_tvm_hook
+`, bin)
+	case "fish":
+		fmt.Fprintf(b, `function _tvm_hook --on-variable PWD
+  %s hook-env fish | source
+end
+_tvm_hook
+`, bin)
+	}
+}
+
+// shellQuote quotes a string for bash, zsh and fish alike
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("/._-+=:,@%", r))
+	}) == -1 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+var hookEnvCmd = &cobra.Command{
+	Use:    "hook-env <bash|zsh|fish>",
+	Short:  "Print the PATH changes for the project versions file of the current directory",
+	Hidden: true,
+	Args:   cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		shell := args[0]
+		if !slices.Contains(supportedShells, shell) {
+			return fmt.Errorf("unsupported shell %q, must be one of %s", shell, strings.Join(supportedShells, ", "))
+		}
+
+		// start from PATH without what the hook added for the previous directory
+		previous := filepath.SplitList(os.Getenv(projectPathEnv))
+		var path []string
+		for _, entry := range filepath.SplitList(os.Getenv("PATH")) {
+			if !slices.Contains(previous, entry) {
+				path = append(path, entry)
+			}
+		}
+
+		var added []string
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		if file, found := findProjectVersionsFile(cwd); found {
+			if added, err = projectBinaryDirs(file); err != nil {
+				return err
+			}
+		}
+
+		var b strings.Builder
+		if shell == "fish" {
+			fmt.Fprintf(&b, "set -gx PATH")
+			for _, entry := range append(slices.Clone(added), path...) {
+				fmt.Fprintf(&b, " %s", shellQuote(entry))
+			}
+			b.WriteString("\n")
+		} else {
+			writeExport(&b, shell, "PATH", strings.Join(append(slices.Clone(added), path...), string(os.PathListSeparator)))
+		}
+		if len(added) > 0 {
+			writeExport(&b, shell, projectPathEnv, strings.Join(added, string(os.PathListSeparator)))
+		} else if shell == "fish" {
+			fmt.Fprintf(&b, "set -e %s\n", projectPathEnv)
+		} else {
+			fmt.Fprintf(&b, "unset %s\n", projectPathEnv)
+		}
+		fmt.Print(b.String())
+		return nil
+	},
+}
+
+// findProjectVersionsFile looks for the project versions file in dir and its parents
+func findProjectVersionsFile(dir string) (string, bool) {
+	for {
+		file := filepath.Join(dir, projectVersionsFile)
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			return file, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// projectBinaryDirs returns the directories holding the binaries of the versions pinned in a project versions file.
+// Versions that aren't installed are reported on stderr and skipped, so a stale pin doesn't break the shell.
+func projectBinaryDirs(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", file, err)
+	}
+	defer f.Close()
+
+	var dirs []string
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			fmt.Fprintf(os.Stderr, "tvm: %s:%d: expected '<tool> <version>'\n", file, lineNo)
+			continue
+		}
+		tool, err := getToolById(fields[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "tvm: %s:%d: %v\n", file, lineNo, err)
+			continue
+		}
+		binaries, err := toolVersionBinaries(tool, models.ToolVersion(fields[1]))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "tvm: %s:%d: %v, run `tvm install %s %s`\n", file, lineNo, err, fields[0], fields[1])
+			continue
+		}
+		linkNames := slices.Sorted(maps.Keys(binaries))
+		for _, linkName := range linkNames {
+			if dir := filepath.Dir(binaries[linkName]); !slices.Contains(dirs, dir) {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return dirs, nil
+}
+
+func init() {
+	initCmd.Flags().BoolVar(&initHook, "hook", false, "Re-evaluate "+projectVersionsFile+" files when the directory changes")
+	RootCmd.AddCommand(initCmd)
+	RootCmd.AddCommand(hookEnvCmd)
+}
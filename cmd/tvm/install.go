@@ -4,10 +4,13 @@ import (
 	"fmt"
 
 	"rayyanriaz/tool-version-manager/pkg/models"
+	"rayyanriaz/tool-version-manager/pkg/tvm"
 
 	"github.com/spf13/cobra"
 )
 
+var linkForce bool
+
 var installCmd = &cobra.Command{
 	Use:   "install <tool-id> <version>",
 	Short: "Install a specific version of a tool",
@@ -16,18 +19,10 @@ var installCmd = &cobra.Command{
 		toolID := args[0]
 		version := models.ToolVersion(args[1])
 
-		tool, tvm, err := getToolWithTVM(toolID)
-		if err != nil {
+		if _, err := manager.Install(cmd.Context(), toolID, version, tvm.InstallOptions{Reinstall: true}); err != nil {
 			return err
 		}
 
-		fmt.Printf("Installing %s version %s...\n", toolID, version)
-
-		err = tvm.InstallToolForVersion(tool, version)
-		if err != nil {
-			return fmt.Errorf("failed to install %s version %s: %w", toolID, version, err)
-		}
-
 		fmt.Printf("Successfully installed %s version %s\n", toolID, version)
 		return nil
 	},
@@ -36,21 +31,16 @@ var installCmd = &cobra.Command{
 var linkCmd = &cobra.Command{
 	Use:   "link <tool-id> <version>",
 	Short: "Link a tool version (make it active)",
-	Args:  cobra.ExactArgs(2),
+	Long: `Link a tool version (make it active). Files in the symlinks dir that belong to another
+tool, or that are not managed by tvm, are not overwritten unless --force is given.`,
+	Args: cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		toolID := args[0]
 		version := models.ToolVersion(args[1])
 
-		tool, tvm, err := getToolWithTVM(toolID)
-		if err != nil {
-			return err
-		}
-
 		fmt.Printf("Linking %s version %s...\n", toolID, version)
-
-		err = tvm.LinkTool(tool, version)
-		if err != nil {
-			return fmt.Errorf("failed to link %s version %s: %w", toolID, version, err)
+		if err := manager.Link(cmd.Context(), toolID, version, linkForce); err != nil {
+			return err
 		}
 
 		fmt.Printf("Successfully linked %s version %s\n", toolID, version)
@@ -65,16 +55,9 @@ var unlinkCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		toolID := args[0]
 
-		tool, tvm, err := getToolWithTVM(toolID)
-		if err != nil {
-			return err
-		}
-
 		fmt.Printf("Unlinking %s...\n", toolID)
-
-		err = tvm.UnlinkTool(tool)
-		if err != nil {
-			return fmt.Errorf("failed to unlink %s: %w", toolID, err)
+		if err := manager.Unlink(cmd.Context(), toolID); err != nil {
+			return err
 		}
 
 		fmt.Printf("Successfully unlinked %s\n", toolID)
@@ -84,6 +67,7 @@ var unlinkCmd = &cobra.Command{
 
 func init() {
 	RootCmd.AddCommand(installCmd)
+	linkCmd.Flags().BoolVarP(&linkForce, "force", "f", false, "Overwrite links owned by other tools or not managed by tvm")
 	RootCmd.AddCommand(linkCmd)
 	RootCmd.AddCommand(unlinkCmd)
 }
@@ -9,7 +9,9 @@ import (
 var latestCmd = &cobra.Command{
 	Use:   "latest <tool-id>",
 	Short: "Show the latest available version of a tool",
-	Args:  cobra.ExactArgs(1),
+	Long: `Show the latest available version of a tool in its release channel (stable unless the tool sets
+'channel'). Use --channel to look at another channel, e.g. --channel prerelease.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		toolID := args[0]
 
@@ -18,19 +20,18 @@ var latestCmd = &cobra.Command{
 			return err
 		}
 
-		version, err := tvm.GetLatestRemoteVersion(tool)
+		// Always ask the remote, and update the cache with the outcome
+		version, err := manager.FetchLatestVersion(tool, tvm)
 		if err != nil {
 			return fmt.Errorf("failed to get latest version for %s: %w", toolID, err)
 		}
 
-		// Update cache with latest version
-		_ = updateCachedLatestVersion(toolID, version)
-
 		fmt.Println(version)
 		return nil
 	},
 }
 
 func init() {
+	latestCmd.Flags().Var(&channelOverride, "channel", "Release channel to look at (stable, prerelease or nightly), instead of the tool's own")
 	RootCmd.AddCommand(latestCmd)
 }
@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"rayyanriaz/tool-version-manager/pkg/models"
+	"rayyanriaz/tool-version-manager/pkg/tvm"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of `tvm outdated`
+const (
+	outdatedExitUpToDate = 0
+	outdatedExitOutdated = 1
+	outdatedExitErrors   = 2
+)
+
+var (
+	outdatedRemote bool
+	outdatedFormat string
+)
+
+// outdatedEntry is the outcome of checking one tool
+type outdatedEntry struct {
+	Tool          string            `json:"tool"`
+	LinkedVersion string            `json:"linked_version,omitempty"`
+	LatestVersion string            `json:"latest_version,omitempty"`
+	TargetVersion string            `json:"target_version,omitempty"`
+	Constraint    string            `json:"constraint,omitempty"`
+	Update        models.UpdateKind `json:"update,omitempty"`
+	Outdated      bool              `json:"outdated"`
+	Error         string            `json:"error,omitempty"`
+}
+
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated [tool-id]",
+	Short: "List tools whose linked version is behind",
+	Long: `List tools whose linked version is older than their target version: the newest version allowed
+by the tool's 'constraint' if it has one, otherwise the latest remote version in its channel.
+Each gap is classified as a major, minor or patch update. Tools that aren't linked are skipped.
+
+Latest versions come from the cache while it is fresh, use --remote to fetch them all.
+
+Exit codes:
+  0  every tool is up to date
+  1  some tools are outdated
+  2  some tools could not be checked
+
+Examples:
+  tvm outdated                       # check all tools
+  tvm outdated rg,fd --remote        # check some tools against fresh remote versions
+  tvm outdated --format json         # machine-readable output for CI`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := runOutdated(cmd, args); err != nil {
+			var exitErr *ExitCodeError
+			if errors.As(err, &exitErr) {
+				return err
+			}
+			// anything that prevented the check is an error for CI too
+			return &ExitCodeError{Code: outdatedExitErrors, Err: err}
+		}
+		return nil
+	},
+}
+
+func runOutdated(cmd *cobra.Command, args []string) error {
+	if outdatedFormat != "table" && outdatedFormat != "json" {
+		return fmt.Errorf("invalid format %q, must be table or json", outdatedFormat)
+	}
+
+	var toolIDs []string
+	if len(args) > 0 {
+		toolIDs = splitToolIDs(args[0])
+	}
+
+	// Refresh remote versions like `table` does: everything with --remote, otherwise only stale cache entries
+	refresh := tvm.RefreshStale
+	if outdatedRemote {
+		refresh = tvm.RefreshAll
+	}
+	statuses, err := manager.Status(cmd.Context(), tvm.StatusOptions{ToolIDs: toolIDs, Refresh: refresh})
+	if err != nil {
+		return err
+	}
+
+	entries := make([]outdatedEntry, len(statuses))
+	var wg sync.WaitGroup
+	for i, status := range statuses {
+		wg.Add(1)
+		go func(i int, status tvm.ToolStatus) {
+			defer wg.Done()
+			entries[i] = checkOutdated(status)
+		}(i, status)
+	}
+	wg.Wait()
+
+	// only outdated tools and failures are reported
+	var reported []outdatedEntry
+	exitCode := outdatedExitUpToDate
+	for _, entry := range entries {
+		switch {
+		case entry.Error != "":
+			exitCode = outdatedExitErrors
+		case entry.Outdated:
+			if exitCode == outdatedExitUpToDate {
+				exitCode = outdatedExitOutdated
+			}
+		default:
+			continue
+		}
+		reported = append(reported, entry)
+	}
+
+	if outdatedFormat == "json" {
+		if reported == nil {
+			reported = []outdatedEntry{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(reported); err != nil {
+			return fmt.Errorf("failed to encode output: %w", err)
+		}
+	} else {
+		displayOutdated(reported)
+	}
+
+	if exitCode != outdatedExitUpToDate {
+		// the report says it all, no usage or error message on top
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		return &ExitCodeError{Code: exitCode}
+	}
+	return nil
+}
+
+// checkOutdated compares the linked version of a tool with its target version, using the cached latest version
+func checkOutdated(status tvm.ToolStatus) outdatedEntry {
+	entry := outdatedEntry{Tool: status.ID}
+	fail := func(format string, args ...any) outdatedEntry {
+		entry.Error = fmt.Sprintf(format, args...)
+		return entry
+	}
+
+	tool, toolTVM, err := getToolWithTVM(status.ID)
+	if err != nil {
+		return fail("%v", err)
+	}
+	entry.Constraint = tool.GetConstraint()
+
+	linked := status.LinkedVersion
+	if linked == "" {
+		return entry
+	}
+	entry.LinkedVersion = string(linked)
+
+	latest := status.LatestVersion
+	if latest == "" {
+		if status.FetchError != "" {
+			return fail("failed to fetch latest version: %s", status.FetchError)
+		}
+		return fail("latest version is unknown")
+	}
+	entry.LatestVersion = string(latest)
+
+	target, err := manager.TargetVersion(tool, toolTVM, latest)
+	if err != nil {
+		return fail("%v", err)
+	}
+	entry.TargetVersion = string(target)
+
+	entry.Outdated = tvm.UpdateAvailable(tool, toolTVM, linked, target)
+	if entry.Outdated {
+		entry.Update = models.ClassifyUpdate(linked, target)
+	}
+	return entry
+}
+
+func displayOutdated(entries []outdatedEntry) {
+	if len(entries) == 0 {
+		fmt.Println("All tools are up to date.")
+		return
+	}
+
+	headers := []string{"Tool", "Linked", "Target", "Latest", "Update"}
+	rows := make([][]string, len(entries))
+	for i, entry := range entries {
+		update := string(entry.Update)
+		if entry.Error != "" {
+			update = "error: " + entry.Error
+		}
+		rows[i] = []string{entry.Tool, valueOrNA(entry.LinkedVersion), valueOrNA(entry.TargetVersion), valueOrNA(entry.LatestVersion), update}
+	}
+
+	widths := make([]int, len(headers))
+	for i, header := range headers {
+		widths[i] = len(header)
+	}
+	for _, row := range rows {
+		for i, col := range row {
+			widths[i] = max(widths[i], len(col))
+		}
+	}
+
+	printTableRow(headers, widths, true, nil)
+	var sep []string
+	for _, width := range widths {
+		sep = append(sep, strings.Repeat("-", width))
+	}
+	printTableRow(sep, widths, false, nil)
+	for i, row := range rows {
+		var colColors map[int]string
+		switch {
+		case entries[i].Error != "":
+			colColors = map[int]string{4: colorRed}
+		case entries[i].Update == models.UpdateMajor:
+			colColors = map[int]string{4: colorYellow}
+		default:
+			colColors = map[int]string{4: colorGreen}
+		}
+		printTableRow(row, widths, false, colColors)
+	}
+}
+
+func init() {
+	outdatedCmd.Flags().BoolVarP(&outdatedRemote, "remote", "r", false, "Fetch fresh latest versions for all tools, not only stale ones (updates cache)")
+	outdatedCmd.Flags().StringVarP(&outdatedFormat, "format", "f", "table", "Output format: table, json")
+	outdatedCmd.Flags().Var(&channelOverride, "channel", "Release channel of the latest versions (stable, prerelease or nightly), instead of each tool's own")
+	RootCmd.AddCommand(outdatedCmd)
+}
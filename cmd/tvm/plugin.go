@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"rayyanriaz/tool-version-manager/pkg/impl/config"
+	plugintvm "rayyanriaz/tool-version-manager/pkg/impl/plugin_tvm"
+	"rayyanriaz/tool-version-manager/pkg/models"
+	"rayyanriaz/tool-version-manager/pkg/plugin"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	pluginTestTool    string
+	pluginTestVersion string
+)
+
+var pluginCmd = &cobra.Command{
+	Use:   "plugin",
+	Short: "Manage external backends for tool types",
+	Long: `Tools of a type tvm doesn't know are managed by an external backend: an executable named
+tvm-backend-<type> in the plugins dir (plugins_dir in the config, by default "plugins" next to the config
+file) or on PATH. tvm talks to it with JSON over stdin and stdout, see the "Plugin Backends" section of the
+README.`,
+}
+
+var pluginListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the plugins that were found",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		plugins := plugintvm.ListPlugins(configService.PluginsDir)
+		if len(plugins) == 0 {
+			fmt.Printf("No plugins in %s or on PATH.\n", configService.PluginsDir)
+			return nil
+		}
+		for _, p := range plugins {
+			tvm := plugintvm.NewPluginTVMAt(p.Type, p.Path, configService)
+			if err := tvm.Resolve(); err != nil {
+				fmt.Printf("%-16s %s  (broken: %v)\n", p.Type, p.Path, err)
+				continue
+			}
+			fmt.Printf("%-16s %s\n", p.Type, p.Path)
+		}
+		return nil
+	},
+}
+
+var pluginTestCmd = &cobra.Command{
+	Use:   "test <type> [--tool <tool-id>] [--version <version>]",
+	Short: "Check that a plugin implements the protocol correctly",
+	Long: `Run the operations of a plugin and check their results. Without --tool, only the protocol itself is
+checked. With --tool, a tool of the plugin's type from the config is also installed, linked, unlinked and
+uninstalled, in a temporary downloads and symlinks dir so that nothing real is touched.
+
+Examples:
+  tvm plugin test localdir
+  tvm plugin test localdir --tool mytool --version 1.2.0`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		toolType := args[0]
+		path, err := plugintvm.FindPlugin(toolType, configService.PluginsDir)
+		if err != nil {
+			return err
+		}
+
+		var tool models.Tool
+		if pluginTestTool != "" {
+			if tool, err = getToolById(pluginTestTool); err != nil {
+				return err
+			}
+			if tool.GetType() != toolType {
+				return fmt.Errorf("tool %s has type %s, not %s", tool.GetId(), tool.GetType(), toolType)
+			}
+		}
+
+		sandbox, err := os.MkdirTemp("", "tvm-plugin-test-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(sandbox)
+		sandboxConfig := &config.LocalFileConfig{
+			DownloadsDir: filepath.Join(sandbox, "downloads"),
+			SymlinksDir:  filepath.Join(sandbox, "bin"),
+			PluginsDir:   configService.PluginsDir,
+		}
+		tvm := plugintvm.NewPluginTVMAt(toolType, path, sandboxConfig)
+
+		fmt.Printf("Testing %s\n", path)
+		failed := runPluginChecks(tvm, tool, models.ToolVersion(pluginTestVersion))
+		if failed > 0 {
+			return &ExitCodeError{Code: 1, Err: fmt.Errorf("%d check(s) failed", failed)}
+		}
+		fmt.Println("All checks passed.")
+		return nil
+	},
+}
+
+// runPluginChecks runs the conformance checks of a plugin and returns how many failed
+func runPluginChecks(tvm *plugintvm.PluginTVM, tool models.Tool, version models.ToolVersion) int {
+	failed := 0
+	check := func(name string, fn func() error) bool {
+		if err := fn(); err != nil {
+			fmt.Printf("  FAIL %s: %v\n", name, err)
+			failed++
+			return false
+		}
+		fmt.Printf("  ok   %s\n", name)
+		return true
+	}
+	// names an operation the plugin leaves to tvm's native implementation
+	describe := func(op string) string {
+		if tvm.Supports(op) {
+			return op
+		}
+		return op + " (native)"
+	}
+
+	if !check(plugin.OpDescribe, tvm.Resolve) {
+		return failed
+	}
+	check("unknown operation is an error", func() error {
+		if _, err := tvm.Call("no_such_operation", nil, plugin.Request{}); err == nil {
+			return fmt.Errorf("the plugin accepted it")
+		}
+		return nil
+	})
+	if tool == nil {
+		fmt.Println("  Skipped the operations on tools, pass --tool to run them.")
+		return failed
+	}
+
+	var remote []models.ToolVersion
+	check(plugin.OpListRemoteVersions, func() error {
+		var err error
+		if remote, err = tvm.GetAllRemoteVersions(tool); err != nil {
+			return err
+		}
+		if len(remote) == 0 {
+			return fmt.Errorf("no versions")
+		}
+		return nil
+	})
+	check(plugin.OpLatestRemoteVersion, func() error {
+		latest, err := tvm.GetLatestRemoteVersion(tool)
+		if err != nil {
+			return err
+		}
+		if remote != nil && !slices.Contains(remote, latest) {
+			return fmt.Errorf("%s is not one of the remote versions", latest)
+		}
+		if version == "" {
+			version = latest
+		}
+		return nil
+	})
+	check(describe(plugin.OpCompare), func() error {
+		if len(remote) < 2 {
+			return nil
+		}
+		newer, older := remote[0], remote[1]
+		for _, c := range []struct {
+			v1, v2 models.ToolVersion
+			ok     func(int) bool
+			want   string
+		}{
+			{newer, older, func(r int) bool { return r > 0 }, "positive"},
+			{older, newer, func(r int) bool { return r < 0 }, "negative"},
+			{newer, newer, func(r int) bool { return r == 0 }, "zero"},
+		} {
+			result, err := tvm.CompareVersions(tool, c.v1, c.v2)
+			if err != nil {
+				return err
+			}
+			if !c.ok(result) {
+				return fmt.Errorf("comparing %s to %s gave %d, want %s (remote versions must be newest first)", c.v1, c.v2, result, c.want)
+			}
+		}
+		return nil
+	})
+	if version == "" {
+		fmt.Println("  Skipped installing, no version to install.")
+		return failed
+	}
+
+	if !check(fmt.Sprintf("%s %s", plugin.OpInstall, version), func() error {
+		return tvm.InstallToolForVersion(tool, version)
+	}) {
+		return failed
+	}
+	check(describe(plugin.OpListLocalVersions), func() error {
+		local, err := tvm.GetAllLocalVersions(tool)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(local, version) {
+			return fmt.Errorf("%s is not listed after installing it, got %v", version, local)
+		}
+		return nil
+	})
+	if check(describe(plugin.OpLink), func() error {
+		return tvm.LinkTool(tool, version, false)
+	}) {
+		check(describe(plugin.OpLinkInfo), func() error {
+			info, err := tvm.GetLinkInfo(tool)
+			if err != nil {
+				return err
+			}
+			if info.Version != version {
+				return fmt.Errorf("linked version is %q after linking %s", info.Version, version)
+			}
+			for _, symlink := range tool.GetSymlinks() {
+				path := filepath.Join(tvm.SymlinksDir(), symlink.LinkName())
+				if _, err := os.Stat(path); err != nil {
+					return fmt.Errorf("symlink %s doesn't resolve: %w", path, err)
+				}
+			}
+			return nil
+		})
+		check(describe(plugin.OpUnlink), func() error {
+			if err := tvm.UnlinkTool(tool); err != nil {
+				return err
+			}
+			info, err := tvm.GetLinkInfo(tool)
+			if err != nil {
+				return err
+			}
+			if info.Version != "" {
+				return fmt.Errorf("still linked to %s after unlinking", info.Version)
+			}
+			return nil
+		})
+	}
+	check(describe(plugin.OpUninstall), func() error {
+		if err := tvm.UninstallToolVersion(tool, version); err != nil {
+			return err
+		}
+		local, err := tvm.GetAllLocalVersions(tool)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(local, version) {
+			return fmt.Errorf("%s is still listed after uninstalling it", version)
+		}
+		return nil
+	})
+	return failed
+}
+
+func init() {
+	pluginTestCmd.Flags().StringVar(&pluginTestTool, "tool", "", "tool from the config to run the operations on")
+	pluginTestCmd.Flags().StringVar(&pluginTestVersion, "version", "", "version to install (default: the latest)")
+	pluginCmd.AddCommand(pluginListCmd)
+	pluginCmd.AddCommand(pluginTestCmd)
+	RootCmd.AddCommand(pluginCmd)
+}
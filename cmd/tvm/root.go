@@ -1,12 +1,37 @@
 package cmd
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
 
 	"github.com/spf13/cobra"
 )
 
+// Build information, set with -ldflags by build.sh
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+// ExitCodeError makes the process exit with Code. Err is printed if set.
+type ExitCodeError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitCodeError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit code %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitCodeError) Unwrap() error {
+	return e.Err
+}
+
 var RootCmd = &cobra.Command{
 	Use:   "tvm",
 	Short: "Tool Version Manager - Manage versions of development tools",
@@ -15,8 +40,10 @@ You can install, link, unlink, and switch between different versions of tools li
 }
 
 func init() {
+	RootCmd.Version = fmt.Sprintf("%s (commit %s, built %s)", Version, Commit, BuildDate)
 	RootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "Path to configuration file (default: $TVM_CONFIG or tools.yaml)")
 	RootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	RootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Use only cached versions and artifacts, never the network (default: $TVM_OFFLINE)")
 
 	// Set up logging and bootstrap after flags are parsed
 	RootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
@@ -4,8 +4,9 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
-	"rayyanriaz/tool-version-manager/pkg/models"
+	"rayyanriaz/tool-version-manager/pkg/tvm"
 
 	"github.com/spf13/cobra"
 )
@@ -19,6 +20,7 @@ type ToolTableRow struct {
 	LocalCount      int
 	LatestRemote    string
 	UpdateAvailable bool
+	FetchError      string
 }
 
 var (
@@ -34,10 +36,16 @@ var tableCmd = &cobra.Command{
 	Short: "Show a beautiful tabular view of all tools",
 	Long: `Display all configured tools in a beautiful table format with information about
 linked versions, installation dates, local versions, and latest remote versions (from cache).
-Use --remote to fetch fresh latest versions from remote sources and update the cache.`,
+Cache entries older than 'remote_versions_cache_max_age' are refreshed before display.
+Use --remote to fetch fresh latest versions for all tools and update the cache.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 
-		tools, err := getAllTools()
+		// Refresh remote versions: everything with --remote, otherwise only the stale cache entries
+		refresh := tvm.RefreshStale
+		if showRemote {
+			refresh = tvm.RefreshAll
+		}
+		statuses, err := manager.Status(cmd.Context(), tvm.StatusOptions{Refresh: refresh})
 		if err != nil {
 			return fmt.Errorf("failed to get tools: %w", err)
 		}
@@ -45,69 +53,28 @@ Use --remote to fetch fresh latest versions from remote sources and update the c
 		// Collect data for all tools
 		var rows []ToolTableRow
 
-		for _, toolWrapper := range tools {
-			tool := toolWrapper.Wrapped
-			tvm, err := models.ToolRegistrar.GetTVM(tool.GetType())
-			if err != nil {
-				return fmt.Errorf("failed to get TVM for tool %s: %w", tool.GetId(), err)
-			}
+		for _, status := range statuses {
 			row := ToolTableRow{
-				Name: tool.GetId(),
-				Type: tool.GetType(),
+				Name:            status.ID,
+				Type:            status.Type,
+				LinkedVersion:   NA,
+				LinkedAt:        NA,
+				LocalCount:      len(status.LocalVersions),
+				LatestRemote:    NA,
+				UpdateAvailable: status.UpdateAvailable,
 			}
-
-			// Get linked version and date
-			if linkInfo, err := tvm.GetLinkInfo(tool); err == nil && linkInfo != nil && linkInfo.Version != "" {
-				row.LinkedVersion = string(linkInfo.Version)
-				row.LinkedAt = formatLinkedAt(string(linkInfo.LinkedAt))
-			} else {
-				row.LinkedVersion = NA
-				row.LinkedAt = NA
+			if status.LinkedVersion != "" {
+				row.LinkedVersion = string(status.LinkedVersion)
+				row.LinkedAt = formatLinkedAt(status.LinkedAt)
 			}
-
-			// Get local versions
-			if localVersions, err := tvm.GetAllLocalVersions(tool); err == nil {
-				row.LocalCount = len(localVersions)
-				var versionStrs []string
-				for _, v := range localVersions {
-					versionStrs = append(versionStrs, string(v))
-				}
-				row.LocalVersions = versionStrs
-			} else {
-				row.LocalCount = 0
+			for _, v := range status.LocalVersions {
+				row.LocalVersions = append(row.LocalVersions, string(v))
 			}
-
-			// Get latest remote version - from cache or fresh fetch
-			if showRemote {
-				// Fresh fetch from remote and update cache
-				if latestVersion, err := tvm.GetLatestRemoteVersion(tool); err == nil {
-					row.LatestRemote = string(latestVersion)
-					// Update cache with fresh value
-					_ = updateCachedLatestVersion(tool.GetId(), latestVersion)
-
-					// Check if update is available
-					if row.LinkedVersion != NA {
-						if result, err := tvm.CompareVersions(tool, models.ToolVersion(row.LinkedVersion), latestVersion); err == nil {
-							row.UpdateAvailable = result < 0
-						}
-					}
-				} else {
-					row.LatestRemote = NA
-				}
-			} else {
-				// Use cached version if available
-				if cachedVersion, found := getCachedLatestVersion(tool.GetId()); found {
-					row.LatestRemote = string(cachedVersion)
-
-					// Check if update is available
-					if row.LinkedVersion != NA {
-						if result, err := tvm.CompareVersions(tool, models.ToolVersion(row.LinkedVersion), cachedVersion); err == nil {
-							row.UpdateAvailable = result < 0
-						}
-					}
-				} else {
-					row.LatestRemote = NA
-				}
+			if status.LatestVersion != "" {
+				row.LatestRemote = string(status.LatestVersion)
+			}
+			if status.FetchError != "" {
+				row.FetchError = fmt.Sprintf("fetch failed %s", formatAge(status.FetchFailedAt))
 			}
 
 			rows = append(rows, row)
@@ -142,14 +109,42 @@ Use --remote to fetch fresh latest versions from remote sources and update the c
 			})
 		}
 
-		return displayTable(rows)
+		if err := displayTable(rows); err != nil {
+			return err
+		}
+		printDaemonStatus()
+		return nil
 	},
 }
 
+// printDaemonStatus tells when `tvm daemon` last checked for updates, if it ever ran
+func printDaemonStatus() {
+	status, found := manager.DaemonStatus().Get()
+	if !found {
+		return
+	}
+	line := fmt.Sprintf("\nLast background check: %s", formatAge(status.CheckedAt))
+	if errorCount := len(status.FetchErrors) + len(status.UpgradeErrors); status.Error != "" {
+		line += fmt.Sprintf(" (failed: %s)", status.Error)
+	} else if errorCount > 0 {
+		line += fmt.Sprintf(" (%d error(s))", errorCount)
+	}
+	if len(status.Upgraded) > 0 {
+		var upgraded []string
+		for _, upgrade := range status.Upgraded {
+			upgraded = append(upgraded, fmt.Sprintf("%s %s", upgrade.Tool, upgrade.To))
+		}
+		line += ", auto-upgraded " + strings.Join(upgraded, ", ")
+	}
+	fmt.Println(line)
+}
+
 // ANSI color codes
 const (
-	colorReset = "\033[0m"
-	colorGreen = "\033[32m"
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
 )
 
 // formatLinkedAt truncates the timestamp to show only up to seconds (YYYY-MM-DD HH:MM:SS)
@@ -163,6 +158,29 @@ func formatLinkedAt(linkedAt string) string {
 	return linkedAt
 }
 
+// formatAge renders how long ago t was in a compact form, e.g. "2h ago"
+func formatAge(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
+// formatLatestRemote shows the latest remote version, annotated with the last fetch failure if any
+func formatLatestRemote(row ToolTableRow) string {
+	if row.FetchError == "" {
+		return row.LatestRemote
+	}
+	return fmt.Sprintf("%s (%s)", row.LatestRemote, row.FetchError)
+}
+
 func displayTable(rows []ToolTableRow) error {
 	// Build headers and widths based on verbose mode
 	// Normal mode: Tool, Linked Version, Latest Remote, Update, Linked At, Local Count, Local Versions
@@ -207,7 +225,7 @@ func displayTable(rows []ToolTableRow) error {
 				row.Name,
 				row.Type,
 				row.LinkedVersion,
-				row.LatestRemote,
+				formatLatestRemote(row),
 				updateStatus,
 				row.LinkedAt,
 				fmt.Sprintf("%d", row.LocalCount),
@@ -217,7 +235,7 @@ func displayTable(rows []ToolTableRow) error {
 			data = []string{
 				row.Name,
 				row.LinkedVersion,
-				row.LatestRemote,
+				formatLatestRemote(row),
 				updateStatus,
 				row.LinkedAt,
 				fmt.Sprintf("%d", row.LocalCount),
@@ -267,7 +285,7 @@ func displayTable(rows []ToolTableRow) error {
 				row.Name,
 				row.Type,
 				row.LinkedVersion,
-				row.LatestRemote,
+				formatLatestRemote(row),
 				updateStatus,
 				row.LinkedAt,
 				fmt.Sprintf("%d", row.LocalCount),
@@ -278,7 +296,7 @@ func displayTable(rows []ToolTableRow) error {
 			data = []string{
 				row.Name,
 				row.LinkedVersion,
-				row.LatestRemote,
+				formatLatestRemote(row),
 				updateStatus,
 				row.LinkedAt,
 				fmt.Sprintf("%d", row.LocalCount),
@@ -327,9 +345,10 @@ func printTableRow(cols []string, widths []int, isHeader bool, colColors map[int
 }
 
 func init() {
-	tableCmd.Flags().BoolVarP(&showRemote, "remote", "r", false, "Fetch fresh latest versions from remote (updates cache)")
+	tableCmd.Flags().BoolVarP(&showRemote, "remote", "r", false, "Fetch fresh latest versions for all tools, not only stale ones (updates cache)")
 	tableCmd.Flags().StringVarP(&sortBy, "sort", "s", "name", "Sort by: name, type, linked, count")
 	tableCmd.Flags().StringVarP(&outputFormat, "format", "f", "table", "Output format: table, json")
+	tableCmd.Flags().Var(&channelOverride, "channel", "Release channel of the latest versions (stable, prerelease or nightly), instead of each tool's own")
 
 	RootCmd.AddCommand(tableCmd)
 }
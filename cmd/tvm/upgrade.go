@@ -3,21 +3,35 @@ package cmd
 import (
 	"fmt"
 	"log/slog"
-	"strings"
-	"sync"
 
 	"rayyanriaz/tool-version-manager/pkg/models"
+	"rayyanriaz/tool-version-manager/pkg/tvm"
 
 	"github.com/spf13/cobra"
 )
 
 var force bool
+var overwriteLinks bool
 var all bool
+var atomic bool
+var showNotes bool
 
 var upgradeCmd = &cobra.Command{
 	Use:   "upgrade <tool-id>",
 	Short: "Upgrade a tool to the latest version",
-	Args:  cobra.MaximumNArgs(1),
+	Long: `Upgrade tools to their latest versions. Tools are upgraded independently, so some may
+succeed while others fail.
+
+With --atomic the batch is all-or-nothing: every install happens first without linking anything,
+links are switched only if all installs succeeded, and if any link fails every tool is relinked
+to its pre-upgrade version. The report says whether the batch was committed or rolled back.
+
+Examples:
+  tvm upgrade ripgrep              # upgrade a single tool
+  tvm upgrade rg,fd,fzf            # upgrade several tools
+  tvm upgrade --all --atomic       # upgrade everything, or nothing
+  tvm upgrade neovim --channel nightly`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if !all && len(args) == 0 {
 			return fmt.Errorf("you must provide either a tool ID or use the --all flag")
@@ -27,161 +41,90 @@ var upgradeCmd = &cobra.Command{
 			return fmt.Errorf("cannot use --all with specific tool IDs")
 		}
 
-		allTools, err := getAllTools()
-		if err != nil {
-			return fmt.Errorf("failed to get all tools: %w", err)
+		opts := tvm.UpgradeOptions{Force: force, OverwriteLinks: overwriteLinks, Atomic: atomic}
+		if !all {
+			opts.ToolIDs = splitToolIDs(args[0])
 		}
-		if all {
-			// Get all tools
-			toolIDs := make([]string, len(allTools))
-			for i, tool := range allTools {
-				toolIDs[i] = tool.Wrapped.GetId()
-			}
-			slog.Debug("Upgrading", "tools", toolIDs)
-			return upgradeTools(toolIDs)
+		if showNotes {
+			opts.OnNotes = showUpgradeNotes
 		}
 
-		toolIDs := strings.Split(args[0], ",")
-		for i := range toolIDs {
-			toolIDs[i] = strings.TrimSpace(toolIDs[i])
-			if toolIDs[i] == "" {
-				return fmt.Errorf("invalid empty tool ID in input")
-			}
-			// toolId should exist in the tools list
-			if _, err := getToolById(toolIDs[i]); err != nil {
-				return fmt.Errorf("tool %s does not exist: %w", toolIDs[i], err)
-			}
+		results, err := manager.Upgrade(cmd.Context(), opts)
+		if results == nil {
+			return err
+		}
+		if atomic {
+			printAtomicUpgradeReport(results, err == nil)
+			return err
 		}
 
-		return upgradeTools(toolIDs)
-	},
-}
-
-func upgradeTools(toolIDs []string) error {
-
-	var wg sync.WaitGroup
-	errs := make([]error, len(toolIDs))
-	var mu sync.Mutex
-	for i, toolID := range toolIDs {
-		wg.Add(1)
-		go func(i int, toolID string) {
-			defer wg.Done()
-			err := upgradeTool(toolID)
-			mu.Lock()
-			errs[i] = err
-			if err == nil {
-				fmt.Printf("Successfully upgraded %s\n", toolID)
-			} else {
-				fmt.Printf("Failed to upgrade %s: %v\n", toolID, err)
+		for _, result := range results {
+			switch result.Status {
+			case tvm.UpgradeUpgraded:
+				fmt.Printf("Successfully upgraded %s to version %s\n", result.ToolID, result.To)
+			case tvm.UpgradeFailed:
+				fmt.Printf("Failed to upgrade %s: %v\n", result.ToolID, result.Err)
 			}
-			mu.Unlock()
-		}(i, toolID)
-	}
-
-	wg.Wait()
-
-	var finalErr error
-	for _, err := range errs {
+		}
 		if err != nil {
-			if finalErr == nil {
-				finalErr = fmt.Errorf("errors occurred during upgrade: %v", err)
-			} else {
-				finalErr = fmt.Errorf("%w; %v", finalErr, err)
-			}
+			slog.Error("Upgrade errors", "errors", err)
+			return err
 		}
-	}
-	if finalErr != nil {
-		slog.Error("Upgrade errors", "errors", finalErr)
-		return finalErr
-	} else {
 		slog.Info("All tools upgraded successfully")
 		fmt.Println("All specified tools upgraded successfully.")
 		return nil
-	}
-
+	},
 }
 
-func upgradeTool(toolID string) error {
-	tool, tvm, err := getToolWithTVM(toolID)
+// showUpgradeNotes prints the release notes of an upgrade. Notes are informational, failing to get them doesn't stop it.
+func showUpgradeNotes(tool models.Tool, from, to models.ToolVersion, notes []models.ReleaseNote, err error) {
 	if err != nil {
-		return fmt.Errorf("failed to get tool %s: %w", toolID, err)
+		fmt.Printf("Could not get release notes of %s: %v\n", tool.GetId(), err)
+		return
 	}
-
-	// Get latest version
-	latestVersion, err := tvm.GetLatestRemoteVersion(tool)
-	if err != nil {
-		return fmt.Errorf("failed to get latest version for %s: %w", toolID, err)
-	}
-
-	// Update cache with latest version
-	_ = updateCachedLatestVersion(toolID, latestVersion)
-
-	// Get current linked version
-	linkInfo, err := tvm.GetLinkInfo(tool)
-	var currentVersion models.ToolVersion
-	if err != nil {
-		// If linkInfo is nil or Version is empty, treat as no current version
-		if linkInfo == nil || linkInfo.Version == "" {
-			currentVersion = ""
-			// No current version, proceed with installation
-		} else {
-			return fmt.Errorf("failed to get current version for %s: %w", toolID, err)
-		}
-		// No current version, proceed with installation
-	} else {
-		currentVersion = linkInfo.Version
+	if len(notes) == 0 {
+		fmt.Printf("No release notes for %s between %s and %s\n", tool.GetId(), tvm.DescribeVersion(from), to)
+		return
 	}
+	printReleaseNotes(tool.GetId(), notes)
+}
 
-	// Compare versions if there's a current version
-	if currentVersion != "" {
-		result, err := tvm.CompareVersions(tool, currentVersion, latestVersion)
-		if err != nil {
-			return fmt.Errorf("failed to compare versions for %s: %w", toolID, err)
+// printAtomicUpgradeReport says whether an atomic batch was committed or rolled back, and what happened to each tool
+func printAtomicUpgradeReport(results []tvm.UpgradeResult, committed bool) {
+	if committed {
+		var upgraded []tvm.UpgradeResult
+		for _, result := range results {
+			if result.Status == tvm.UpgradeUpgraded {
+				upgraded = append(upgraded, result)
+			}
 		}
-
-		if result >= 0 && !force {
-			fmt.Printf("%s is already at the latest version (%s)\n", toolID, currentVersion)
-			return nil
+		fmt.Printf("\nBatch COMMITTED: %d upgraded, %d already up to date\n", len(upgraded), len(results)-len(upgraded))
+		for _, result := range upgraded {
+			fmt.Printf("  %s: %s -> %s\n", result.ToolID, tvm.DescribeVersion(result.From), result.To)
 		}
+		return
 	}
 
-	fmt.Printf("Upgrading %s to version %s...\n", toolID, latestVersion)
-
-	// check if the tool is already installed
-	localVersions, err := tvm.GetAllLocalVersions(tool)
-	if err != nil {
-		slog.Debug("Warning: failed to get local versions for tool %s: %v", toolID, err)
-	}
-	isInstalled := false
-	for _, v := range localVersions {
-		if v == latestVersion {
-			isInstalled = true
-			break
+	fmt.Println("\nBatch ROLLED BACK:")
+	for _, result := range results {
+		switch {
+		case result.RestoreErr != nil:
+			fmt.Printf("  %s: FAILED to restore %s: %v\n", result.ToolID, tvm.DescribeVersion(result.From), result.RestoreErr)
+		case result.Status == tvm.UpgradeFailed:
+			fmt.Printf("  %s: %v\n", result.ToolID, result.Err)
+		case result.Status == tvm.UpgradeRolledBack:
+			fmt.Printf("  %s: left at %s\n", result.ToolID, tvm.DescribeVersion(result.From))
 		}
 	}
-	if isInstalled {
-		slog.Debug("Tool %s version %s is already installed", toolID, latestVersion)
-	} else {
-		err = tvm.InstallToolForVersion(tool, latestVersion)
-		if err != nil {
-			return fmt.Errorf("failed to install %s version %s: %w", toolID, latestVersion, err)
-		}
-	}
-
-	// Link latest version
-	err = tvm.LinkTool(tool, latestVersion)
-	if err != nil {
-		return fmt.Errorf("failed to link %s version %s: %w", toolID, latestVersion, err)
-	}
-
-	fmt.Printf("Successfully upgraded %s to version %s\n", toolID, latestVersion)
-	return nil
-
 }
 
 func init() {
-	upgradeCmd.Flags().BoolVarP(&force, "force", "f", false, "Force link even if another version is already linked")
+	upgradeCmd.Flags().BoolVarP(&force, "force", "f", false, "Upgrade even if the latest version is already linked")
+	upgradeCmd.Flags().BoolVar(&overwriteLinks, "overwrite-links", false, "Overwrite links owned by other tools or not managed by tvm")
 	upgradeCmd.Flags().BoolVarP(&all, "all", "a", false, "Upgrade all tools to their latest versions")
+	upgradeCmd.Flags().Var(&channelOverride, "channel", "Release channel to upgrade to (stable, prerelease or nightly), instead of each tool's own")
+	upgradeCmd.Flags().BoolVar(&showNotes, "show-notes", false, "Show the release notes between the linked and the new version before upgrading")
+	upgradeCmd.Flags().BoolVar(&atomic, "atomic", false, "Install everything first and switch links only if all installs succeed, reverting all links if any link fails")
 
 	RootCmd.AddCommand(upgradeCmd)
 }
@@ -0,0 +1,330 @@
+package bundle
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"rayyanriaz/tool-version-manager/pkg/impl/state"
+	"rayyanriaz/tool-version-manager/pkg/models"
+
+	"github.com/goccy/go-yaml"
+)
+
+// FormatVersion is the version of the bundle layout, bumped on incompatible changes
+const FormatVersion = 1
+
+const manifestName = "manifest.yaml"
+
+// Manifest describes the content of a bundle
+type Manifest struct {
+	FormatVersion int         `json:"format_version"`
+	CreatedAt     time.Time   `json:"created_at"`
+	TvmVersion    string      `json:"tvm_version,omitempty"`
+	Tools         []ToolEntry `json:"tools"`
+	// Definitions are the config entries of the bundled tools, added to the target config if it lacks them
+	Definitions []models.ToolWrapper `json:"definitions"`
+}
+
+// ToolEntry is one installed version of a tool in a bundle
+type ToolEntry struct {
+	Tool    string             `json:"tool"`
+	Version models.ToolVersion `json:"version"`
+	// Digest is the utils.DirDigest of the version directory, checked after extracting it
+	Digest string              `json:"digest"`
+	Record state.InstallRecord `json:"record"`
+	// Artifacts are the artifact cache entries of the version, by key
+	Artifacts map[string]state.Artifact `json:"artifacts,omitempty"`
+}
+
+// VersionPath is where the directory of a bundled version is stored, relative to the bundle root
+func VersionPath(toolID string, version models.ToolVersion) string {
+	return path.Join("versions", toolID, string(version))
+}
+
+// ArtifactPath is where an artifact is stored, relative to the bundle root
+func ArtifactPath(digest string) string {
+	return path.Join("artifacts", strings.TrimPrefix(digest, "sha256:"))
+}
+
+// Writer writes a bundle as a tar archive. The manifest comes first, so it can be read before anything is extracted.
+type Writer struct {
+	file *os.File
+	tw   *tar.Writer
+}
+
+// Create starts a bundle at filePath with its manifest
+func Create(filePath string, manifest Manifest) (*Writer, error) {
+	data, err := yaml.Marshal(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode bundle manifest: %w", err)
+	}
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bundle: %w", err)
+	}
+	w := &Writer{file: file, tw: tar.NewWriter(file)}
+	header := &tar.Header{Name: manifestName, Mode: 0644, Size: int64(len(data)), ModTime: manifest.CreatedAt, Typeflag: tar.TypeReg}
+	if err := w.tw.WriteHeader(header); err != nil {
+		w.Abort()
+		return nil, err
+	}
+	if _, err := w.tw.Write(data); err != nil {
+		w.Abort()
+		return nil, err
+	}
+	return w, nil
+}
+
+// AddDir adds a directory tree under name, keeping file modes and symlinks
+func (w *Writer) AddDir(name, dir string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		var link string
+		if d.Type()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(name, filepath.ToSlash(rel))
+		if d.IsDir() {
+			header.Name += "/"
+		}
+		if err := w.tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return w.copyFile(p)
+	})
+}
+
+// AddFile adds a single regular file under name
+func (w *Writer) AddFile(name, filePath string) error {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if err := w.tw.WriteHeader(header); err != nil {
+		return err
+	}
+	return w.copyFile(filePath)
+}
+
+func (w *Writer) copyFile(filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w.tw, f)
+	return err
+}
+
+// Close finishes the bundle
+func (w *Writer) Close() error {
+	if err := w.tw.Close(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// Abort closes and removes an unfinished bundle
+func (w *Writer) Abort() {
+	w.file.Close()
+	os.Remove(w.file.Name())
+}
+
+// Extract unpacks a bundle into destDir and returns its manifest, whose definitions are decoded with registry.
+// Entries that would land outside destDir are rejected, as are symlinks pointing outside of the version
+// directory they are in and entries below a symlink, so nothing is ever written through a link.
+func Extract(filePath, destDir string, registry *models.ToolRegistry) (*Manifest, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer file.Close()
+	root, err := os.OpenRoot(destDir)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	var manifest *Manifest
+	tr := tar.NewReader(file)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+
+		if manifest == nil {
+			if header.Name != manifestName {
+				return nil, fmt.Errorf("%s is not a tvm bundle: it doesn't start with a manifest", filePath)
+			}
+			if manifest, err = readManifest(tr, registry); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		name := filepath.FromSlash(header.Name)
+		if !filepath.IsLocal(name) {
+			return nil, fmt.Errorf("bundle entry %q points outside of the bundle", header.Name)
+		}
+		if err := extractEntry(root, tr, header, filepath.Clean(name)); err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", header.Name, err)
+		}
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("%s is not a tvm bundle: it is empty", filePath)
+	}
+	return manifest, nil
+}
+
+func readManifest(r io.Reader, registry *models.ToolRegistry) (*Manifest, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle manifest: %w", err)
+	}
+	var manifest Manifest
+	if err := yaml.UnmarshalContext(models.WithToolRegistry(context.Background(), registry), data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle manifest: %w", err)
+	}
+	if manifest.FormatVersion != FormatVersion {
+		return nil, fmt.Errorf("unsupported bundle format %d, this tvm reads format %d", manifest.FormatVersion, FormatVersion)
+	}
+	if err := manifest.validate(); err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// validate checks the names that end up in paths, a tool or version like `../x` would be installed outside of
+// the downloads dir
+func (m *Manifest) validate() error {
+	for _, entry := range m.Tools {
+		if !isPathComponent(entry.Tool) {
+			return fmt.Errorf("invalid tool %q", entry.Tool)
+		}
+		if !isPathComponent(string(entry.Version)) {
+			return fmt.Errorf("invalid version %q of tool %s", entry.Version, entry.Tool)
+		}
+		for key, artifact := range entry.Artifacts {
+			if !isPathComponent(strings.TrimPrefix(artifact.Digest, "sha256:")) {
+				return fmt.Errorf("invalid digest %q of artifact %s", artifact.Digest, key)
+			}
+		}
+	}
+	for _, definition := range m.Definitions {
+		if definition.Wrapped == nil {
+			return fmt.Errorf("empty tool definition")
+		}
+		if id := definition.Wrapped.GetId(); !isPathComponent(id) {
+			return fmt.Errorf("invalid tool %q", id)
+		}
+	}
+	return nil
+}
+
+// isPathComponent tells whether s is a single clean path element, which is a file or directory name of its own
+func isPathComponent(s string) bool {
+	return s != "" && s != "." && s != ".." && !strings.ContainsAny(s, `/\`) && filepath.IsLocal(s)
+}
+
+// extractEntry writes an entry at name, relative to root. Existing entries are never overwritten.
+func extractEntry(root *os.Root, tr *tar.Reader, header *tar.Header, name string) error {
+	if err := mkdirAllIn(root, filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	switch header.Typeflag {
+	case tar.TypeDir:
+		return mkdirAllIn(root, name, header.FileInfo().Mode().Perm()|0700)
+	case tar.TypeSymlink:
+		// the parents are real directories, so the link resolves from where it is placed
+		linkname := filepath.FromSlash(header.Linkname)
+		versionDir, ok := versionDirOf(name)
+		if !ok {
+			return fmt.Errorf("symlinks are only allowed in version directories")
+		}
+		target, err := filepath.Rel(versionDir, filepath.Join(filepath.Dir(name), linkname))
+		if filepath.IsAbs(linkname) || err != nil || !filepath.IsLocal(target) {
+			return fmt.Errorf("symlink to %q points outside of its version directory %s", header.Linkname, filepath.ToSlash(versionDir))
+		}
+		return os.Symlink(linkname, filepath.Join(root.Name(), name))
+	case tar.TypeReg:
+		out, err := root.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, header.FileInfo().Mode().Perm())
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, tr)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	default:
+		return fmt.Errorf("unsupported entry type %q", header.Typeflag)
+	}
+}
+
+// versionDirOf returns the version directory, versions/<tool>/<version>, that the entry at name is in
+func versionDirOf(name string) (string, bool) {
+	parts := strings.Split(filepath.ToSlash(name), "/")
+	if len(parts) < 4 || parts[0] != "versions" {
+		return "", false
+	}
+	return filepath.Join(parts[:3]...), true
+}
+
+// mkdirAllIn creates dir and its parents in root, failing if any of them exists but isn't a directory, like a symlink
+func mkdirAllIn(root *os.Root, dir string, perm os.FileMode) error {
+	if dir == "." {
+		return nil
+	}
+	// parents first, Lstat only tells whether the last element is a link
+	if err := mkdirAllIn(root, filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+	info, err := root.Lstat(dir)
+	if os.IsNotExist(err) {
+		return root.Mkdir(dir, perm)
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	return nil
+}
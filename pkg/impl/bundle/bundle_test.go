@@ -0,0 +1,188 @@
+package bundle
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rayyanriaz/tool-version-manager/pkg/models"
+)
+
+const validManifest = "format_version: 1\ntools:\n  - tool: a\n    version: \"1.0\"\n"
+
+// entry is one tar entry of a test bundle, a directory if its name ends with a slash
+type entry struct {
+	name     string
+	linkname string // makes the entry a symlink
+	content  string
+}
+
+// writeBundle writes a tar with a manifest, unless it is empty, and entries, and returns its path
+func writeBundle(t *testing.T, manifest string, entries ...entry) string {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), "bundle.tar")
+	file, err := os.Create(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	tw := tar.NewWriter(file)
+	write := func(header *tar.Header, content string) {
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if manifest != "" {
+		write(&tar.Header{Name: manifestName, Mode: 0644, Size: int64(len(manifest)), Typeflag: tar.TypeReg}, manifest)
+	}
+	for _, e := range entries {
+		switch {
+		case e.linkname != "":
+			write(&tar.Header{Name: e.name, Linkname: e.linkname, Mode: 0777, Typeflag: tar.TypeSymlink}, "")
+		case strings.HasSuffix(e.name, "/"):
+			write(&tar.Header{Name: e.name, Mode: 0755, Typeflag: tar.TypeDir}, "")
+		default:
+			write(&tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.content)), Typeflag: tar.TypeReg}, e.content)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+func TestExtract(t *testing.T) {
+	binary := entry{name: "versions/a/1.0/bin/a", content: "a"}
+	tests := []struct {
+		name     string
+		manifest string
+		entries  []entry
+		wantErr  string
+	}{
+		{
+			name:     "valid bundle",
+			manifest: validManifest,
+			entries: []entry{
+				{name: "versions/a/1.0/"},
+				binary,
+				{name: "versions/a/1.0/bin/alias", linkname: "a"},
+				{name: "versions/a/1.0/lib/a", linkname: "../bin/a"},
+				{name: "artifacts/abc", content: "artifact"},
+			},
+		},
+		{name: "no manifest", entries: []entry{binary}, wantErr: "doesn't start with a manifest"},
+		{name: "unsupported format", manifest: "format_version: 99\n", wantErr: "unsupported bundle format"},
+		{name: "tool outside of the downloads dir", manifest: "format_version: 1\ntools:\n  - tool: ..\n    version: \"1.0\"\n", wantErr: "invalid tool"},
+		{name: "version with a slash", manifest: "format_version: 1\ntools:\n  - tool: a\n    version: 1.0/x\n", wantErr: "invalid version"},
+		{name: "entry outside of the bundle", manifest: validManifest, entries: []entry{{name: "../escape", content: "x"}}, wantErr: "outside of the bundle"},
+		{name: "absolute entry", manifest: validManifest, entries: []entry{{name: "/tmp/escape", content: "x"}}, wantErr: "outside of the bundle"},
+		{
+			name:     "absolute symlink",
+			manifest: validManifest,
+			entries:  []entry{{name: "versions/a/1.0/bin/sh", linkname: "/bin/sh"}},
+			wantErr:  "outside of its version directory",
+		},
+		{
+			name:     "symlink out of the bundle",
+			manifest: validManifest,
+			entries:  []entry{{name: "versions/a/1.0/bin/x", linkname: "../../../../../etc/passwd"}},
+			wantErr:  "outside of its version directory",
+		},
+		{
+			name:     "symlink into another version",
+			manifest: validManifest,
+			entries:  []entry{{name: "versions/a/1.0/bin/x", linkname: "../../../b/2.0/bin/x"}},
+			wantErr:  "outside of its version directory",
+		},
+		{
+			name:     "symlink outside of a version directory",
+			manifest: validManifest,
+			entries:  []entry{{name: "artifacts/abc", linkname: "def"}},
+			wantErr:  "only allowed in version directories",
+		},
+		{
+			name:     "entry below a symlink",
+			manifest: validManifest,
+			entries:  []entry{binary, {name: "versions/a/1.0/lib", linkname: "bin"}, {name: "versions/a/1.0/lib/x", content: "x"}},
+			wantErr:  "not a directory",
+		},
+		{
+			name:     "duplicate entry",
+			manifest: validManifest,
+			entries:  []entry{binary, binary},
+			wantErr:  "exists",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := writeBundle(t, tt.manifest, tt.entries...)
+			destDir := t.TempDir()
+
+			manifest, err := Extract(filePath, destDir, models.NewToolRegistry())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Extract() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if len(manifest.Tools) != 1 || manifest.Tools[0].Tool != "a" {
+				t.Errorf("manifest tools = %+v", manifest.Tools)
+			}
+			for _, name := range []string{"versions/a/1.0/bin/alias", "versions/a/1.0/lib/a"} {
+				data, err := os.ReadFile(filepath.Join(destDir, name))
+				if err != nil || string(data) != "a" {
+					t.Errorf("%s = %q, %v, want the content of bin/a", name, data, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateAndExtract(t *testing.T) {
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "bin", "tool"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("tool", filepath.Join(src, "bin", "alias")); err != nil {
+		t.Fatal(err)
+	}
+
+	filePath := filepath.Join(t.TempDir(), "bundle.tar")
+	w, err := Create(filePath, Manifest{FormatVersion: FormatVersion, Tools: []ToolEntry{{Tool: "tool", Version: "1.0"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddDir(VersionPath("tool", "1.0"), src); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	destDir := t.TempDir()
+	if _, err := Extract(filePath, destDir, models.NewToolRegistry()); err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	binary := filepath.Join(destDir, "versions", "tool", "1.0", "bin", "tool")
+	info, err := os.Stat(binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm()&0100 == 0 {
+		t.Errorf("%s lost its executable bit: %s", binary, info.Mode())
+	}
+	if target, err := os.Readlink(filepath.Join(destDir, "versions", "tool", "1.0", "bin", "alias")); err != nil || target != "tool" {
+		t.Errorf("alias = %q, %v, want a link to tool", target, err)
+	}
+}
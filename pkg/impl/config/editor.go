@@ -0,0 +1,376 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"rayyanriaz/tool-version-manager/pkg/models"
+	"rayyanriaz/tool-version-manager/pkg/utils"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/goccy/go-yaml/token"
+)
+
+// ConfigEditor edits a config file in place. Unlike Save, which re-marshals the whole config and loses anchors,
+// comments and the `variables:` section, it uses the YAML AST only to locate what to change and splices the
+// change into the original text, so everything else is kept byte for byte.
+type ConfigEditor struct {
+	filePath string
+	lines    []string
+	registry *models.ToolRegistry
+}
+
+// NewConfigEditor reads a config file for editing. Its tools are validated with the managers in registry.
+func NewConfigEditor(filePath string, registry *models.ToolRegistry) (*ConfigEditor, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+	return &ConfigEditor{
+		filePath: filePath,
+		lines:    strings.Split(string(data), "\n"),
+		registry: registry,
+	}, nil
+}
+
+// Bytes returns the edited config
+func (e *ConfigEditor) Bytes() []byte {
+	return []byte(strings.Join(e.lines, "\n"))
+}
+
+// Save checks that the edited config still loads and writes it back atomically
+func (e *ConfigEditor) Save() error {
+	var check LocalFileConfig
+	ctx := models.WithToolRegistry(context.Background(), e.registry)
+	if err := yaml.UnmarshalContext(ctx, e.Bytes(), &check, yaml.AllowDuplicateMapKey()); err != nil {
+		return fmt.Errorf("the edited config would be invalid: %w", err)
+	}
+	if check.RemoteVersionsCacheMaxAge != "" {
+		if _, err := time.ParseDuration(check.RemoteVersionsCacheMaxAge); err != nil {
+			return fmt.Errorf("the edited config would be invalid: remote_versions_cache_max_age: %w", err)
+		}
+	}
+	info, err := os.Stat(e.filePath)
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(e.filePath, e.Bytes(), info.Mode().Perm())
+}
+
+// root parses the current text and returns its top-level mapping
+func (e *ConfigEditor) root() (*ast.MappingNode, error) {
+	file, err := parser.ParseBytes(e.Bytes(), parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if len(file.Docs) != 1 {
+		return nil, fmt.Errorf("config must contain exactly one YAML document")
+	}
+	root, ok := unwrap(file.Docs[0].Body).(*ast.MappingNode)
+	if !ok || root.IsFlowStyle {
+		return nil, fmt.Errorf("config must be a block mapping")
+	}
+	return root, nil
+}
+
+// HasAnchor reports whether the config defines an anchor, e.g. a preset under `variables:`
+func (e *ConfigEditor) HasAnchor(name string) (bool, error) {
+	file, err := parser.ParseBytes(e.Bytes(), 0)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse config: %w", err)
+	}
+	found := false
+	for _, doc := range file.Docs {
+		ast.Walk(anchorFinder(func(anchor *ast.AnchorNode) {
+			if anchor.Name.GetToken().Value == name {
+				found = true
+			}
+		}), doc)
+	}
+	return found, nil
+}
+
+type anchorFinder func(*ast.AnchorNode)
+
+func (f anchorFinder) Visit(node ast.Node) ast.Visitor {
+	if anchor, ok := node.(*ast.AnchorNode); ok {
+		f(anchor)
+	}
+	return f
+}
+
+// AppendToSequence adds an item at the end of the block sequence under a top-level key, creating the key if needed.
+// The item is YAML text, unindented, e.g. "id: rg\ntype: scripts_driven".
+func (e *ConfigEditor) AppendToSequence(key string, item string) error {
+	root, err := e.root()
+	if err != nil {
+		return err
+	}
+
+	mv := findKey(root, key)
+	if mv == nil {
+		at := e.lastContentLine(len(e.lines)-1) + 1
+		e.insert(at, append([]string{key + ":"}, indentItem(item, 2)...))
+		return nil
+	}
+
+	keyLine := lineOf(mv.Key.GetToken())
+	keyIndent := indentOf(e.lines[keyLine])
+	switch value := unwrap(mv.Value).(type) {
+	case *ast.NullNode:
+		e.insert(keyLine+1, indentItem(item, keyIndent+2))
+	case *ast.SequenceNode:
+		if value.IsFlowStyle || len(value.Entries) == 0 {
+			return fmt.Errorf("%s is not a block sequence, please edit it by hand", key)
+		}
+		dashIndent := columnOf(value.Entries[0].Start)
+		last := lineOf(value.Entries[len(value.Entries)-1].Start)
+		e.insert(e.blockEnd(last, dashIndent+1, false)+1, indentItem(item, dashIndent))
+	default:
+		return fmt.Errorf("%s is not a sequence", key)
+	}
+	return nil
+}
+
+// RemoveFromSequence removes the item of the block sequence under a top-level key whose field has the given value
+func (e *ConfigEditor) RemoveFromSequence(key, field, value string) error {
+	root, err := e.root()
+	if err != nil {
+		return err
+	}
+	mv := findKey(root, key)
+	if mv == nil {
+		return fmt.Errorf("config has no %s", key)
+	}
+	seq, ok := unwrap(mv.Value).(*ast.SequenceNode)
+	if !ok || seq.IsFlowStyle {
+		return fmt.Errorf("%s is not a block sequence, please edit it by hand", key)
+	}
+	i := findEntry(seq, field, value)
+	if i < 0 {
+		return fmt.Errorf("no %s with %s %q", key, field, value)
+	}
+	start := lineOf(seq.Entries[i].Start)
+	end := e.blockEnd(start, columnOf(seq.Entries[i].Start)+1, false)
+	e.lines = append(e.lines[:start], e.lines[end+1:]...)
+	return nil
+}
+
+// Set sets a scalar at a dotted path, e.g. `remote_versions_cache_max_age` or `tools.rg.extra.Repo`.
+// Sequence items are selected by their `id`. Missing keys are created.
+func (e *ConfigEditor) Set(path string, value string) error {
+	segments := strings.Split(path, ".")
+	for _, segment := range segments {
+		if segment == "" {
+			return fmt.Errorf("invalid path %q", path)
+		}
+	}
+	rendered, err := renderScalar(value)
+	if err != nil {
+		return err
+	}
+
+	root, err := e.root()
+	if err != nil {
+		return err
+	}
+	var node ast.Node = root
+	for i, segment := range segments {
+		switch current := unwrap(node).(type) {
+		case *ast.MappingNode:
+			if current.IsFlowStyle {
+				return fmt.Errorf("%s is a flow mapping, please edit it by hand", strings.Join(segments[:i], "."))
+			}
+			mv := findKey(current, segment)
+			if mv == nil {
+				e.insertKeys(current, segments[i:], rendered)
+				return nil
+			}
+			if i == len(segments)-1 {
+				return e.replaceScalar(mv, path, rendered)
+			}
+			node = mv.Value
+		case *ast.SequenceNode:
+			j := findEntry(current, "id", segment)
+			if j < 0 || current.IsFlowStyle {
+				return fmt.Errorf("no item with id %q in %s", segment, strings.Join(segments[:i], "."))
+			}
+			if i == len(segments)-1 {
+				return fmt.Errorf("%s is not a scalar", path)
+			}
+			node = current.Entries[j].Value
+		default:
+			return fmt.Errorf("%s is not a mapping", strings.Join(segments[:i], "."))
+		}
+	}
+	return nil
+}
+
+// insertKeys adds the missing keys of a path with the value at the end of a mapping
+func (e *ConfigEditor) insertKeys(mapping *ast.MappingNode, keys []string, rendered string) {
+	indent := columnOf(mapping.Values[0].Key.GetToken())
+	lastKey := lineOf(mapping.Values[len(mapping.Values)-1].Key.GetToken())
+	at := e.blockEnd(lastKey, indent+1, true) + 1
+
+	var newLines []string
+	for i, key := range keys {
+		prefix := strings.Repeat(" ", indent+2*i) + key + ":"
+		if i == len(keys)-1 {
+			prefix += " " + rendered
+		}
+		newLines = append(newLines, prefix)
+	}
+	e.insert(at, newLines)
+}
+
+// replaceScalar replaces the value of a key in place, keeping any comment on its line
+func (e *ConfigEditor) replaceScalar(mv *ast.MappingValueNode, path, rendered string) error {
+	keyLine := lineOf(mv.Key.GetToken())
+	text := e.lines[keyLine]
+	colon := columnOf(mv.Start)
+
+	switch value := mv.Value.(type) {
+	case *ast.NullNode:
+		// `key:`, `key: ~` or `key: null`, replaced after the colon
+	case *ast.MappingNode, *ast.SequenceNode, *ast.AnchorNode, *ast.AliasNode, *ast.TagNode, *ast.LiteralNode:
+		return fmt.Errorf("%s is not a scalar, please edit it by hand", path)
+	default:
+		if lineOf(value.GetToken()) != keyLine {
+			return fmt.Errorf("%s is not on the same line as its key, please edit it by hand", path)
+		}
+	}
+
+	comment := ""
+	if group := mv.Value.GetComment(); group != nil && len(group.Comments) > 0 && lineOf(group.Comments[0].GetToken()) == keyLine {
+		commentAt := columnOf(group.Comments[0].GetToken())
+		beforeComment := text[:commentAt]
+		comment = beforeComment[len(strings.TrimRight(beforeComment, " ")):] + text[commentAt:]
+	}
+	e.lines[keyLine] = text[:colon+1] + " " + rendered + comment
+	return nil
+}
+
+// renderScalar renders a value given on the command line: numbers, booleans and null are kept as they are,
+// strings are quoted if YAML needs it
+func renderScalar(value string) (string, error) {
+	var parsed any
+	if err := yaml.Unmarshal([]byte("v: "+value), &struct{ V *any }{V: &parsed}); err == nil {
+		switch parsed.(type) {
+		case map[string]any, []any:
+		case string:
+		default:
+			return strings.TrimSpace(value), nil
+		}
+	}
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to render value %q: %w", value, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// blockEnd returns the last content line of the block starting at line start, whose other lines are indented
+// at least minIndent. Blank and comment lines after it belong to whatever follows.
+// With compactSeq, sequence entries one column less indented also belong to it, like the items of
+// `tools:` when they are written at the key's own indentation.
+func (e *ConfigEditor) blockEnd(start, minIndent int, compactSeq bool) int {
+	end := start
+	for i := start + 1; i < len(e.lines); i++ {
+		if isTrivia(e.lines[i]) {
+			continue
+		}
+		indent := indentOf(e.lines[i])
+		if indent < minIndent && !(compactSeq && indent == minIndent-1 && strings.HasPrefix(strings.TrimSpace(e.lines[i]), "-")) {
+			break
+		}
+		end = i
+	}
+	return end
+}
+
+// lastContentLine returns the last line at or before from that isn't blank or a comment
+func (e *ConfigEditor) lastContentLine(from int) int {
+	for i := from; i >= 0; i-- {
+		if !isTrivia(e.lines[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (e *ConfigEditor) insert(at int, newLines []string) {
+	e.lines = append(e.lines[:at], append(newLines, e.lines[at:]...)...)
+}
+
+func findKey(mapping *ast.MappingNode, key string) *ast.MappingValueNode {
+	for _, mv := range mapping.Values {
+		if mv.Key.GetToken().Value == key {
+			return mv
+		}
+	}
+	return nil
+}
+
+// findEntry returns the index of the sequence item whose field has the given value, or -1
+func findEntry(seq *ast.SequenceNode, field, value string) int {
+	for i, entry := range seq.Entries {
+		if mapping, ok := unwrap(entry.Value).(*ast.MappingNode); ok {
+			if mv := findKey(mapping, field); mv != nil && mv.Value.GetToken() != nil && mv.Value.GetToken().Value == value {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// unwrap returns the node an anchor, tag or document wraps
+func unwrap(node ast.Node) ast.Node {
+	for {
+		switch n := node.(type) {
+		case *ast.AnchorNode:
+			node = n.Value
+		case *ast.TagNode:
+			node = n.Value
+		default:
+			return node
+		}
+	}
+}
+
+// indentItem renders a sequence item at an indentation
+func indentItem(item string, indent int) []string {
+	pad := strings.Repeat(" ", indent)
+	var out []string
+	for i, l := range strings.Split(strings.TrimRight(item, "\n"), "\n") {
+		if i == 0 {
+			out = append(out, pad+"- "+l)
+		} else {
+			out = append(out, pad+"  "+l)
+		}
+	}
+	return out
+}
+
+// lineOf returns the 0-based line of a token
+func lineOf(tk *token.Token) int {
+	return tk.Position.Line - 1
+}
+
+// columnOf returns the 0-based column of a token, which is also its indentation when it starts a line
+func columnOf(tk *token.Token) int {
+	return tk.Position.Column - 1
+}
+
+func indentOf(l string) int {
+	return len(l) - len(strings.TrimLeft(l, " "))
+}
+
+func isTrivia(l string) bool {
+	trimmed := strings.TrimSpace(l)
+	return trimmed == "" || strings.HasPrefix(trimmed, "#")
+}
@@ -0,0 +1,245 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rayyanriaz/tool-version-manager/pkg/models"
+)
+
+const editorConfig = `# tvm config
+variables:
+  github: &github
+    type: scripts_driven
+
+downloads_dir: ./dl # where versions go
+tools:
+  - id: rg # ripgrep
+    <<: *github
+    extra:
+      Repo: BurntSushi/ripgrep
+  - id: fd
+    <<: *github
+
+# the end
+`
+
+// lines joins lines into a config, like a heredoc
+func lines(ls ...string) string {
+	return strings.Join(ls, "\n") + "\n"
+}
+
+func newTestEditor(t *testing.T, content string) *ConfigEditor {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), "tools.yaml")
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	editor, err := NewConfigEditor(filePath, models.NewToolRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return editor
+}
+
+func TestConfigEditorSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		path    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "replace keeps the comment",
+			path:  "downloads_dir",
+			value: "./cache",
+			want:  strings.Replace(editorConfig, "downloads_dir: ./dl # where", "downloads_dir: ./cache # where", 1),
+		},
+		{
+			name:  "replace in a tool",
+			path:  "tools.rg.extra.Repo",
+			value: "someone/ripgrep",
+			want:  strings.Replace(editorConfig, "Repo: BurntSushi/ripgrep", "Repo: someone/ripgrep", 1),
+		},
+		{
+			name:  "new top-level key goes after the tools",
+			path:  "remote_versions_cache_max_age",
+			value: "6h",
+			want:  strings.Replace(editorConfig, "    <<: *github\n\n", "    <<: *github\nremote_versions_cache_max_age: 6h\n\n", 1),
+		},
+		{
+			name:  "new nested keys in a tool",
+			path:  "tools.fd.extra.Repo",
+			value: "sharkdp/fd",
+			want:  strings.Replace(editorConfig, "    <<: *github\n\n", "    <<: *github\n    extra:\n      Repo: sharkdp/fd\n\n", 1),
+		},
+		{
+			name:   "null value",
+			config: lines("downloads_dir:", "symlinks_dir: ./bin"),
+			path:   "downloads_dir",
+			value:  "./dl",
+			want:   lines("downloads_dir: ./dl", "symlinks_dir: ./bin"),
+		},
+		{
+			name:   "strings that aren't plain are quoted",
+			config: lines("github_token: x"),
+			path:   "github_token",
+			value:  "a: b",
+			want:   lines(`github_token: "a: b"`),
+		},
+		{
+			name:   "booleans are kept",
+			config: lines("mirror_fallback: false"),
+			path:   "mirror_fallback",
+			value:  "true",
+			want:   lines("mirror_fallback: true"),
+		},
+		{name: "anchored value", path: "variables.github", value: "x", wantErr: true},
+		{name: "mapping", path: "tools.rg.extra", value: "x", wantErr: true},
+		{name: "unknown tool", path: "tools.jq.extra.Repo", value: "x", wantErr: true},
+		{name: "empty segment", path: "tools..extra", value: "x", wantErr: true},
+		{name: "flow mapping", config: lines("extra: {a: 1}"), path: "extra.a", value: "2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			if config == "" {
+				config = editorConfig
+			}
+			editor := newTestEditor(t, config)
+			err := editor.Set(tt.path, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set(%s, %s) error = %v, wantErr %v", tt.path, tt.value, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if got := string(editor.Bytes()); got != config {
+					t.Errorf("a failed Set changed the config:\n%s", got)
+				}
+				return
+			}
+			if got := string(editor.Bytes()); got != tt.want {
+				t.Errorf("Set(%s, %s) =\n%s\nwant\n%s", tt.path, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigEditorAppendToSequence(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		key     string
+		item    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "after the last item",
+			key:  "tools",
+			item: "id: jq\n<<: *github",
+			want: strings.Replace(editorConfig, "    <<: *github\n\n", "    <<: *github\n  - id: jq\n    <<: *github\n\n", 1),
+		},
+		{
+			name:   "items at the key's indentation",
+			config: lines("tools:", "- id: rg", "  type: scripts_driven", "symlinks_dir: ./bin"),
+			key:    "tools",
+			item:   "id: fd",
+			want:   lines("tools:", "- id: rg", "  type: scripts_driven", "- id: fd", "symlinks_dir: ./bin"),
+		},
+		{
+			name: "missing key is created at the end",
+			key:  "mirrors",
+			item: "from: https://github.com/\nto: https://mirror.example.com/",
+			want: strings.Replace(editorConfig, "    <<: *github\n\n", "    <<: *github\nmirrors:\n  - from: https://github.com/\n    to: https://mirror.example.com/\n\n", 1),
+		},
+		{
+			name:   "empty key",
+			config: lines("tools:", "symlinks_dir: ./bin"),
+			key:    "tools",
+			item:   "id: rg",
+			want:   lines("tools:", "  - id: rg", "symlinks_dir: ./bin"),
+		},
+		{name: "flow sequence", config: lines("tools: []"), key: "tools", item: "id: rg", wantErr: true},
+		{name: "not a sequence", key: "downloads_dir", item: "id: rg", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			if config == "" {
+				config = editorConfig
+			}
+			editor := newTestEditor(t, config)
+			err := editor.AppendToSequence(tt.key, tt.item)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AppendToSequence(%s) error = %v, wantErr %v", tt.key, err, tt.wantErr)
+			}
+			if !tt.wantErr && string(editor.Bytes()) != tt.want {
+				t.Errorf("AppendToSequence(%s) =\n%s\nwant\n%s", tt.key, editor.Bytes(), tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigEditorRemoveFromSequence(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "first item with its comment and nested keys",
+			id:   "rg",
+			want: strings.Replace(editorConfig, "  - id: rg # ripgrep\n    <<: *github\n    extra:\n      Repo: BurntSushi/ripgrep\n", "", 1),
+		},
+		{
+			name: "last item keeps what follows",
+			id:   "fd",
+			want: strings.Replace(editorConfig, "  - id: fd\n    <<: *github\n", "", 1),
+		},
+		{name: "unknown item", id: "jq", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			editor := newTestEditor(t, editorConfig)
+			err := editor.RemoveFromSequence("tools", "id", tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RemoveFromSequence(%s) error = %v, wantErr %v", tt.id, err, tt.wantErr)
+			}
+			if !tt.wantErr && string(editor.Bytes()) != tt.want {
+				t.Errorf("RemoveFromSequence(%s) =\n%s\nwant\n%s", tt.id, editor.Bytes(), tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigEditorHasAnchor(t *testing.T) {
+	editor := newTestEditor(t, editorConfig)
+	for name, want := range map[string]bool{"github": true, "gitlab": false} {
+		if got, err := editor.HasAnchor(name); err != nil || got != want {
+			t.Errorf("HasAnchor(%s) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+}
+
+func TestConfigEditorSaveRejectsInvalidConfig(t *testing.T) {
+	config := lines("remote_versions_cache_max_age: 1h")
+	editor := newTestEditor(t, config)
+	if err := editor.Set("remote_versions_cache_max_age", "soon"); err != nil {
+		t.Fatal(err)
+	}
+	if err := editor.Save(); err == nil {
+		t.Fatal("Save() accepted an invalid duration")
+	}
+	data, err := os.ReadFile(editor.filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != config {
+		t.Errorf("a rejected Save changed the file:\n%s", data)
+	}
+}
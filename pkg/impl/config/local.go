@@ -1,8 +1,11 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
 	"rayyanriaz/tool-version-manager/pkg/models"
 	"rayyanriaz/tool-version-manager/pkg/utils"
@@ -10,16 +13,36 @@ import (
 
 type LocalFileConfig struct {
 	configFilePath              string                    `json:"-"`
+	registry                    *models.ToolRegistry      `json:"-"`
 	Tools                       models.UniqueToolWrappers `json:"tools"`
 	DownloadsDir                string                    `json:"downloads_dir,omitempty"`
 	SymlinksDir                 string                    `json:"symlinks_dir,omitempty"`
 	GitHubToken                 string                    `json:"github_token,omitempty"`
 	RemoteVersionsCacheFilePath string                    `json:"remote_versions_cache_file_path,omitempty"`
+	RemoteVersionsCacheMaxAge   string                    `json:"remote_versions_cache_max_age,omitempty"`
+	StateDir                    string                    `json:"state_dir,omitempty"`
+	ArtifactsDir                string                    `json:"artifacts_dir,omitempty"`
+	Mirrors                     []utils.Mirror            `json:"mirrors,omitempty"`
+	MirrorFallback              bool                      `json:"mirror_fallback,omitempty"`
+	PluginsDir                  string                    `json:"plugins_dir,omitempty"`
+	DaemonInterval              string                    `json:"daemon_interval,omitempty"`
+	DaemonJitter                string                    `json:"daemon_jitter,omitempty"`
+	remoteVersionsCacheMaxAge   time.Duration             `json:"-"`
+	daemonInterval              time.Duration             `json:"-"`
+	daemonJitter                time.Duration             `json:"-"`
 }
 
-func NewLocalFileConfig(configPath string) *LocalFileConfig {
+const (
+	defaultRemoteVersionsCacheMaxAge = 24 * time.Hour
+	defaultDaemonInterval            = time.Hour
+	defaultDaemonJitter              = 10 * time.Minute
+)
+
+// NewLocalFileConfig creates the config of a file, whose tools are created with the managers in registry
+func NewLocalFileConfig(configPath string, registry *models.ToolRegistry) *LocalFileConfig {
 	var config LocalFileConfig
 	config.configFilePath = configPath
+	config.registry = registry
 
 	return &config
 }
@@ -28,8 +51,35 @@ func (c *LocalFileConfig) GetTools() models.UniqueToolWrappers {
 	return c.Tools
 }
 
+// GetRemoteVersionsCacheMaxAge returns how long a cached latest version is considered fresh
+func (c *LocalFileConfig) GetRemoteVersionsCacheMaxAge() time.Duration {
+	return c.remoteVersionsCacheMaxAge
+}
+
+// GetDaemonInterval returns how long `tvm daemon` waits between background checks
+func (c *LocalFileConfig) GetDaemonInterval() time.Duration {
+	return c.daemonInterval
+}
+
+// GetDaemonJitter returns the most that `tvm daemon` adds at random to its interval,
+// so that hosts sharing a config don't all check at once
+func (c *LocalFileConfig) GetDaemonJitter() time.Duration {
+	return c.daemonJitter
+}
+
+// GetMirrors returns the URL rewrite rules of the config
+func (c *LocalFileConfig) GetMirrors() *utils.Mirrors {
+	return &utils.Mirrors{Rules: c.Mirrors, Fallback: c.MirrorFallback}
+}
+
+// GetConfigFilePath returns the path of the loaded config file
+func (c *LocalFileConfig) GetConfigFilePath() string {
+	return c.configFilePath
+}
+
 func (c *LocalFileConfig) Load() error {
-	if err := utils.LoadFile(c.configFilePath, c); err != nil {
+	ctx := models.WithToolRegistry(context.Background(), c.registry)
+	if err := utils.LoadYAMLFileContext(ctx, c.configFilePath, c); err != nil {
 		return fmt.Errorf("failed to load config file %s: %w", c.configFilePath, err)
 	}
 
@@ -42,6 +92,43 @@ func (c *LocalFileConfig) Load() error {
 	if c.RemoteVersionsCacheFilePath == "" {
 		c.RemoteVersionsCacheFilePath = "./.tools.state.yaml"
 	}
+	if c.StateDir == "" {
+		c.StateDir = filepath.Join(c.DownloadsDir, ".tvm_state")
+	}
+	if c.ArtifactsDir == "" {
+		c.ArtifactsDir = filepath.Join(c.DownloadsDir, ".tvm_artifacts")
+	}
+	if c.PluginsDir == "" {
+		c.PluginsDir = filepath.Join(filepath.Dir(c.configFilePath), "plugins")
+	}
+	c.remoteVersionsCacheMaxAge = defaultRemoteVersionsCacheMaxAge
+	if c.RemoteVersionsCacheMaxAge != "" {
+		maxAge, err := time.ParseDuration(c.RemoteVersionsCacheMaxAge)
+		if err != nil {
+			return fmt.Errorf("invalid remote_versions_cache_max_age %q: %w", c.RemoteVersionsCacheMaxAge, err)
+		}
+		c.remoteVersionsCacheMaxAge = maxAge
+	}
+	c.daemonInterval = defaultDaemonInterval
+	if c.DaemonInterval != "" {
+		interval, err := time.ParseDuration(c.DaemonInterval)
+		if err != nil || interval <= 0 {
+			return fmt.Errorf("invalid daemon_interval %q: must be a positive duration like 1h", c.DaemonInterval)
+		}
+		c.daemonInterval = interval
+	}
+	c.daemonJitter = defaultDaemonJitter
+	if c.DaemonJitter != "" {
+		jitter, err := time.ParseDuration(c.DaemonJitter)
+		if err != nil || jitter < 0 {
+			return fmt.Errorf("invalid daemon_jitter %q: must be a duration like 10m", c.DaemonJitter)
+		}
+		c.daemonJitter = jitter
+	}
+
+	if err := utils.ValidateMirrors(c.Mirrors); err != nil {
+		return fmt.Errorf("invalid mirrors: %w", err)
+	}
 
 	// Environment variable takes precedence over config file
 	if envToken := os.Getenv("GITHUB_TOKEN"); envToken != "" {
@@ -60,7 +147,7 @@ func (c *LocalFileConfig) Save() error {
 }
 
 func (l *LocalFileConfig) ensureDirectories() error {
-	directories := []string{l.DownloadsDir, l.SymlinksDir}
+	directories := []string{l.DownloadsDir, l.SymlinksDir, l.StateDir, l.ArtifactsDir}
 
 	for _, dir := range directories {
 		if err := os.MkdirAll(dir, 0755); err != nil {
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"sync"
 	"time"
 
 	"rayyanriaz/tool-version-manager/pkg/models"
@@ -11,13 +12,25 @@ import (
 // ToolVersionCache holds cached version info for a single tool
 type ToolVersionCache struct {
 	LatestVersion models.ToolVersion `json:"latest_version"`
-	LastChecked   time.Time          `json:"last_checked"`
+	// Channel is the release channel the latest version was picked from. Empty means stable.
+	Channel     models.Channel `json:"channel,omitempty"`
+	LastChecked time.Time      `json:"last_checked"`
+	LastError   string         `json:"last_error,omitempty"`
+	LastErrorAt time.Time      `json:"last_error_at,omitempty"`
+	// Tags maps versions to the upstream tags they were derived from, for tools with a tag_filter or tag_transform
+	Tags map[models.ToolVersion]string `json:"tags,omitempty"`
+	// RemoteVersions is the last listed set of remote versions, used in offline mode
+	RemoteVersions []models.ToolVersion `json:"remote_versions,omitempty"`
 }
 
-// RemoteVersionsCache holds cached latest versions for all tools
+// RemoteVersionsCache holds cached latest versions for all tools. It is safe for concurrent use,
+// also by several tvm processes sharing the cache file.
 type RemoteVersionsCache struct {
+	mu       sync.RWMutex                `json:"-"`
 	filePath string                      `json:"-"`
 	Tools    map[string]ToolVersionCache `json:"tools"`
+	// pending are the changes made since the last save, replayed onto the file's current content when saving
+	pending []func(tools map[string]ToolVersionCache) `json:"-"`
 }
 
 // NewRemoteVersionsCache creates a new cache instance
@@ -30,42 +43,167 @@ func NewRemoteVersionsCache(filePath string) *RemoteVersionsCache {
 
 // Load reads the cache from disk. Returns nil error if file doesn't exist.
 func (c *RemoteVersionsCache) Load() error {
-	if _, err := os.Stat(c.filePath); os.IsNotExist(err) {
-		// File doesn't exist yet, that's fine
-		c.Tools = make(map[string]ToolVersionCache)
-		return nil
-	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Tools = c.readFile()
+	c.pending = nil
+	return nil
+}
 
-	if err := utils.LoadFile(c.filePath, c); err != nil {
+// readFile returns the tools in the cache file, or none if it doesn't exist or is corrupted
+func (c *RemoteVersionsCache) readFile() map[string]ToolVersionCache {
+	var onDisk RemoteVersionsCache
+	if _, err := os.Stat(c.filePath); err != nil {
+		return make(map[string]ToolVersionCache)
+	}
+	if err := utils.LoadFile(c.filePath, &onDisk); err != nil || onDisk.Tools == nil {
 		// If file exists but is corrupted/empty, start fresh
-		c.Tools = make(map[string]ToolVersionCache)
-		return nil
+		return make(map[string]ToolVersionCache)
 	}
+	return onDisk.Tools
+}
+
+// Save writes the changes made since the last save to disk. The file is reloaded under a file lock and only
+// these changes are applied to it, so that concurrent tvm processes don't overwrite each other's entries.
+func (c *RemoteVersionsCache) Save() error {
+	// exclusive, so that concurrent saves are written in order
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	if c.Tools == nil {
-		c.Tools = make(map[string]ToolVersionCache)
+	lock, err := utils.LockFile(c.filePath + ".lock")
+	if err != nil {
+		return err
 	}
+	defer lock.Unlock()
 
+	tools := c.readFile()
+	for _, change := range c.pending {
+		change(tools)
+	}
+	if err := utils.SaveFile(c.filePath, &RemoteVersionsCache{Tools: tools}); err != nil {
+		return err
+	}
+	c.Tools = tools
+	c.pending = nil
 	return nil
 }
 
-// Save writes the cache to disk
-func (c *RemoteVersionsCache) Save() error {
-	return utils.SaveFile(c.filePath, c)
+// update applies a change to the cache and remembers it for the next save. Callers must hold the write lock.
+func (c *RemoteVersionsCache) update(change func(tools map[string]ToolVersionCache)) {
+	change(c.Tools)
+	c.pending = append(c.pending, change)
 }
 
-// GetCachedVersion returns the cached latest version for a tool, or empty if not cached
-func (c *RemoteVersionsCache) GetCachedVersion(toolID string) (models.ToolVersion, time.Time, bool) {
-	if cache, ok := c.Tools[toolID]; ok {
-		return cache.LatestVersion, cache.LastChecked, true
+// GetCachedVersion returns the cached latest version of a tool in a channel, or empty if not cached.
+// A version cached for another channel doesn't count.
+func (c *RemoteVersionsCache) GetCachedVersion(toolID string, channel models.Channel) (models.ToolVersion, time.Time, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cache, ok := c.Tools[toolID]
+	if !ok || cache.LastChecked.IsZero() {
+		return "", time.Time{}, false
+	}
+	cachedChannel := cache.Channel
+	if cachedChannel == "" {
+		cachedChannel = models.ChannelStable
 	}
-	return "", time.Time{}, false
+	if cachedChannel != channel {
+		return "", time.Time{}, false
+	}
+	return cache.LatestVersion, cache.LastChecked, true
+}
+
+// SetCachedVersion updates the cached latest version of a tool in a channel and clears any recorded fetch error
+func (c *RemoteVersionsCache) SetCachedVersion(toolID string, version models.ToolVersion, channel models.Channel) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	checkedAt := time.Now()
+	c.update(func(tools map[string]ToolVersionCache) {
+		tools[toolID] = ToolVersionCache{
+			LatestVersion:  version,
+			Channel:        channel,
+			LastChecked:    checkedAt,
+			Tags:           tools[toolID].Tags,
+			RemoteVersions: tools[toolID].RemoteVersions,
+		}
+	})
+}
+
+// SetTags remembers the upstream tags of versions of a tool, in addition to the ones already known
+func (c *RemoteVersionsCache) SetTags(toolID string, tags map[models.ToolVersion]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.update(func(tools map[string]ToolVersionCache) {
+		cache := tools[toolID]
+		merged := make(map[models.ToolVersion]string, len(cache.Tags)+len(tags))
+		for version, tag := range cache.Tags {
+			merged[version] = tag
+		}
+		for version, tag := range tags {
+			merged[version] = tag
+		}
+		cache.Tags = merged
+		tools[toolID] = cache
+	})
+}
+
+// SetRemoteVersions remembers the listed remote versions of a tool
+func (c *RemoteVersionsCache) SetRemoteVersions(toolID string, versions []models.ToolVersion) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	versions = append([]models.ToolVersion(nil), versions...)
+	c.update(func(tools map[string]ToolVersionCache) {
+		cache := tools[toolID]
+		cache.RemoteVersions = versions
+		tools[toolID] = cache
+	})
+}
+
+// GetRemoteVersions returns the last listed remote versions of a tool
+func (c *RemoteVersionsCache) GetRemoteVersions(toolID string) ([]models.ToolVersion, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	versions := c.Tools[toolID].RemoteVersions
+	return append([]models.ToolVersion(nil), versions...), len(versions) > 0
+}
+
+// GetTag returns the upstream tag a version of a tool was derived from
+func (c *RemoteVersionsCache) GetTag(toolID string, version models.ToolVersion) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	tag, ok := c.Tools[toolID].Tags[version]
+	return tag, ok
+}
+
+// IsStale reports whether the cached version of a tool in a channel is missing or older than maxAge
+func (c *RemoteVersionsCache) IsStale(toolID string, channel models.Channel, maxAge time.Duration) bool {
+	_, lastChecked, found := c.GetCachedVersion(toolID, channel)
+	if !found {
+		return true
+	}
+	return time.Since(lastChecked) > maxAge
+}
+
+// SetFetchError records a failed fetch for a tool. The previously cached version (if any) is kept.
+func (c *RemoteVersionsCache) SetFetchError(toolID string, fetchErr error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lastError, failedAt := fetchErr.Error(), time.Now()
+	c.update(func(tools map[string]ToolVersionCache) {
+		cache := tools[toolID]
+		cache.LastError = lastError
+		cache.LastErrorAt = failedAt
+		tools[toolID] = cache
+	})
 }
 
-// SetCachedVersion updates the cached latest version for a tool
-func (c *RemoteVersionsCache) SetCachedVersion(toolID string, version models.ToolVersion) {
-	c.Tools[toolID] = ToolVersionCache{
-		LatestVersion: version,
-		LastChecked:   time.Now(),
+// GetFetchError returns the last fetch error for a tool, if it failed after the last successful fetch
+func (c *RemoteVersionsCache) GetFetchError(toolID string) (string, time.Time, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cache, ok := c.Tools[toolID]
+	if !ok || cache.LastError == "" || cache.LastErrorAt.Before(cache.LastChecked) {
+		return "", time.Time{}, false
 	}
+	return cache.LastError, cache.LastErrorAt, true
 }
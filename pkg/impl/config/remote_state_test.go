@@ -0,0 +1,93 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"rayyanriaz/tool-version-manager/pkg/models"
+)
+
+func TestRemoteVersionsCacheSaveMerges(t *testing.T) {
+	tests := []struct {
+		name string
+		// first and second change two caches loaded from the same file, saved in that order
+		first, second func(c *RemoteVersionsCache)
+		check         func(t *testing.T, c *RemoteVersionsCache)
+	}{
+		{
+			name:   "different tools",
+			first:  func(c *RemoteVersionsCache) { c.SetCachedVersion("rg", "14.1.0", models.ChannelStable) },
+			second: func(c *RemoteVersionsCache) { c.SetCachedVersion("fd", "10.0.0", models.ChannelStable) },
+			check: func(t *testing.T, c *RemoteVersionsCache) {
+				for tool, want := range map[string]models.ToolVersion{"rg": "14.1.0", "fd": "10.0.0"} {
+					if got, _, ok := c.GetCachedVersion(tool, models.ChannelStable); !ok || got != want {
+						t.Errorf("GetCachedVersion(%s) = %q, %v, want %q", tool, got, ok, want)
+					}
+				}
+			},
+		},
+		{
+			name:   "version and fetch error of the same tool",
+			first:  func(c *RemoteVersionsCache) { c.SetCachedVersion("rg", "14.1.0", models.ChannelStable) },
+			second: func(c *RemoteVersionsCache) { c.SetFetchError("rg", errors.New("rate limited")) },
+			check: func(t *testing.T, c *RemoteVersionsCache) {
+				if got, _, ok := c.GetCachedVersion("rg", models.ChannelStable); !ok || got != "14.1.0" {
+					t.Errorf("GetCachedVersion(rg) = %q, %v, want 14.1.0", got, ok)
+				}
+				if got, _, ok := c.GetFetchError("rg"); !ok || got != "rate limited" {
+					t.Errorf("GetFetchError(rg) = %q, %v, want the second process's error", got, ok)
+				}
+			},
+		},
+		{
+			name:   "tags are added to",
+			first:  func(c *RemoteVersionsCache) { c.SetTags("k", map[models.ToolVersion]string{"1.0": "k/v1.0"}) },
+			second: func(c *RemoteVersionsCache) { c.SetTags("k", map[models.ToolVersion]string{"2.0": "k/v2.0"}) },
+			check: func(t *testing.T, c *RemoteVersionsCache) {
+				for version, want := range map[models.ToolVersion]string{"1.0": "k/v1.0", "2.0": "k/v2.0"} {
+					if got, ok := c.GetTag("k", version); !ok || got != want {
+						t.Errorf("GetTag(k, %s) = %q, %v, want %q", version, got, ok, want)
+					}
+				}
+			},
+		},
+		{
+			name:   "later save of the same entry wins",
+			first:  func(c *RemoteVersionsCache) { c.SetRemoteVersions("rg", []models.ToolVersion{"1.0"}) },
+			second: func(c *RemoteVersionsCache) { c.SetRemoteVersions("rg", []models.ToolVersion{"1.0", "2.0"}) },
+			check: func(t *testing.T, c *RemoteVersionsCache) {
+				if got, _ := c.GetRemoteVersions("rg"); len(got) != 2 {
+					t.Errorf("GetRemoteVersions(rg) = %v, want both versions", got)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "cache.yaml")
+			first, second := NewRemoteVersionsCache(filePath), NewRemoteVersionsCache(filePath)
+			for _, c := range []*RemoteVersionsCache{first, second} {
+				if err := c.Load(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			tt.first(first)
+			tt.second(second)
+			if err := first.Save(); err != nil {
+				t.Fatal(err)
+			}
+			if err := second.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			// the saving cache sees the merged state, as does a fresh one
+			tt.check(t, second)
+			reloaded := NewRemoteVersionsCache(filePath)
+			if err := reloaded.Load(); err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, reloaded)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"rayyanriaz/tool-version-manager/pkg/impl/httpclient"
+)
+
+const (
+	defaultBaseURL  = "https://api.github.com"
+	releasesPerPage = 100
+)
+
+// Release is a GitHub release, as returned by the releases API
+type Release struct {
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Body        string    `json:"body"`
+	HTMLURL     string    `json:"html_url"`
+	Draft       bool      `json:"draft"`
+	Prerelease  bool      `json:"prerelease"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// Client reads releases from the GitHub API, authenticated if a token is set
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *httpclient.Client
+}
+
+// NewClient creates a GitHub client that sends its requests with httpClient
+func NewClient(token string, httpClient *httpclient.Client) *Client {
+	return &Client{
+		baseURL:    defaultBaseURL,
+		token:      token,
+		httpClient: httpClient,
+	}
+}
+
+// ListReleases lists the releases of a repository (owner/name), newest first, reading at most maxPages pages
+func (c *Client) ListReleases(repo string, maxPages int) ([]Release, error) {
+	if strings.Count(repo, "/") != 1 {
+		return nil, fmt.Errorf("invalid repository %q, expected owner/name", repo)
+	}
+	var releases []Release
+	for page := 1; page <= maxPages; page++ {
+		var batch []Release
+		url := fmt.Sprintf("%s/repos/%s/releases?per_page=%d&page=%d", c.baseURL, repo, releasesPerPage, page)
+		if err := c.getJSON(url, &batch); err != nil {
+			return nil, fmt.Errorf("failed to list releases of %s: %w", repo, err)
+		}
+		releases = append(releases, batch...)
+		if len(batch) < releasesPerPage {
+			break
+		}
+	}
+	return releases, nil
+}
+
+// getJSON decodes the response of a GET request into v
+func (c *Client) getJSON(url string, v any) error {
+	header := http.Header{}
+	header.Set("Accept", "application/vnd.github+json")
+	if c.token != "" {
+		header.Set("Authorization", "token "+c.token)
+	}
+	resp, err := c.httpClient.Get(url, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response of %s: %w", url, err)
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"rayyanriaz/tool-version-manager/pkg/utils"
+)
+
+// Default is the client shared by tvm's own downloads and API calls
+var Default = NewClient()
+
+// Client is an HTTP client for downloads and API calls. It applies the configured mirrors, retries server errors
+// with backoff, waits for short rate limit resets and fails with an actionable error for long ones, and returns
+// non-2xx responses as errors, never as data.
+type Client struct {
+	httpClient *http.Client
+	// Mirrors rewrite the URLs of requests, nil rewrites none
+	Mirrors *utils.Mirrors
+	// MaxRetries is how often a request is retried after a network or 5xx error
+	MaxRetries int
+	// Backoff is the wait before the first retry, doubled for every further one
+	Backoff time.Duration
+	// MaxRateLimitWait is the longest a request waits for a rate limit to reset, longer waits fail instead
+	MaxRateLimitWait time.Duration
+
+	mu sync.Mutex
+	// exhausted remembers hosts whose rate limit is used up, until when, so that later requests don't hit them
+	exhausted map[string]time.Time
+}
+
+func NewClient() *Client {
+	return &Client{
+		httpClient:       &http.Client{Timeout: 5 * time.Minute},
+		MaxRetries:       3,
+		Backoff:          time.Second,
+		MaxRateLimitWait: time.Minute,
+		exhausted:        make(map[string]time.Time),
+	}
+}
+
+// StatusError is a response with a non-2xx status
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("GET %s: %s", e.URL, e.Status)
+	}
+	return fmt.Sprintf("GET %s: %s: %s", e.URL, e.Status, e.Body)
+}
+
+// RateLimitError is returned when a host's rate limit is exhausted for longer than the client waits
+type RateLimitError struct {
+	URL           string
+	Reset         time.Time
+	Authenticated bool
+}
+
+func (e *RateLimitError) Error() string {
+	msg := fmt.Sprintf("rate limit exhausted for %s until %s (in %s)", e.URL, e.Reset.Local().Format("15:04:05"), time.Until(e.Reset).Round(time.Second))
+	if !e.Authenticated && IsGitHubAPI(e.URL) {
+		return msg + ", set github_token in the config or GITHUB_TOKEN to raise the limit"
+	}
+	return msg + ", try again later or use --offline to work from the cache"
+}
+
+// Get fetches url, going through the mirrors. The response has a 2xx status, the caller must close its body.
+// Credentials in the Authorization header are only sent to the host of url, never to a mirror on another host.
+func (c *Client) Get(rawURL string, header http.Header) (*http.Response, error) {
+	var errs []error
+	candidates := c.Mirrors.Candidates(rawURL)
+	for i, candidate := range candidates {
+		resp, err := c.get(candidate, headerFor(candidate, rawURL, header))
+		if err == nil {
+			return resp, nil
+		}
+		errs = append(errs, err)
+		if i < len(candidates)-1 {
+			slog.Warn("Mirror failed, falling back to the original URL", "url", rawURL, "error", err)
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// headerFor returns the header to send to candidate, a mirror of rawURL, without the Authorization header
+// when candidate is on another host
+func headerFor(candidate, rawURL string, header http.Header) http.Header {
+	if header.Get("Authorization") == "" || candidate == rawURL {
+		return header
+	}
+	candidateURL, err := url.Parse(candidate)
+	if err == nil {
+		if originalURL, err := url.Parse(rawURL); err == nil && strings.EqualFold(candidateURL.Host, originalURL.Host) {
+			return header
+		}
+	}
+	header = header.Clone()
+	header.Del("Authorization")
+	return header
+}
+
+func (c *Client) get(rawURL string, header http.Header) (*http.Response, error) {
+	authenticated := header.Get("Authorization") != ""
+	backoff := c.Backoff
+	rateLimitWaited := false
+	for attempt := 0; ; attempt++ {
+		if err := c.waitForRateLimit(rawURL, authenticated, &rateLimitWaited); err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			if attempt < c.MaxRetries {
+				slog.Warn("Request failed, retrying", "url", rawURL, "in", backoff, "error", err)
+				time.Sleep(backoff)
+				backoff *= 2
+				continue
+			}
+			return nil, fmt.Errorf("GET %s: %w", rawURL, err)
+		}
+
+		if reset, limited := rateLimitReset(resp); limited {
+			c.markExhausted(rawURL, reset)
+			if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests {
+				resp.Body.Close()
+				if rateLimitWaited || attempt >= c.MaxRetries {
+					return nil, &RateLimitError{URL: rawURL, Reset: reset, Authenticated: authenticated}
+				}
+				// waitForRateLimit either waits for the reset or fails
+				continue
+			}
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+			return resp, nil
+		}
+		statusErr := newStatusError(rawURL, resp)
+		if resp.StatusCode >= 500 && attempt < c.MaxRetries {
+			wait := backoff
+			if retryAfter, ok := retryAfter(resp); ok {
+				if retryAfter > c.MaxRateLimitWait {
+					return nil, fmt.Errorf("%w (the server asked to retry in %s, longer than the %s tvm waits)", statusErr, retryAfter.Round(time.Second), c.MaxRateLimitWait)
+				}
+				wait = retryAfter
+			}
+			slog.Warn("Server error, retrying", "url", rawURL, "status", resp.Status, "in", wait)
+			time.Sleep(wait)
+			backoff *= 2
+			continue
+		}
+		return nil, statusErr
+	}
+}
+
+// waitForRateLimit waits until the rate limit of url's host resets if that is soon enough, and fails otherwise.
+// It waits at most once per request.
+func (c *Client) waitForRateLimit(rawURL string, authenticated bool, waited *bool) error {
+	host := hostOf(rawURL)
+	c.mu.Lock()
+	reset, exhausted := c.exhausted[host]
+	c.mu.Unlock()
+	if !exhausted {
+		return nil
+	}
+	wait := time.Until(reset)
+	if wait <= 0 {
+		c.mu.Lock()
+		delete(c.exhausted, host)
+		c.mu.Unlock()
+		return nil
+	}
+	if *waited || wait > c.MaxRateLimitWait {
+		return &RateLimitError{URL: rawURL, Reset: reset, Authenticated: authenticated}
+	}
+	slog.Warn("Rate limit exhausted, waiting for it to reset", "host", host, "wait", wait.Round(time.Second))
+	time.Sleep(wait)
+	*waited = true
+	return nil
+}
+
+func (c *Client) markExhausted(rawURL string, reset time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.exhausted[hostOf(rawURL)] = reset
+}
+
+// rateLimitReset reports whether a response says the rate limit is used up, and when it resets
+func rateLimitReset(resp *http.Response) (time.Time, bool) {
+	limited := resp.Header.Get("X-RateLimit-Remaining") == "0" || resp.StatusCode == http.StatusTooManyRequests
+	if !limited {
+		return time.Time{}, false
+	}
+	if wait, ok := retryAfter(resp); ok {
+		return time.Now().Add(wait), true
+	}
+	if seconds, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		return time.Unix(seconds, 0), true
+	}
+	// without a reset time, assume the usual one minute window
+	return time.Now().Add(time.Minute), true
+}
+
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at), true
+	}
+	return 0, false
+}
+
+func newStatusError(rawURL string, resp *http.Response) *StatusError {
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return &StatusError{URL: rawURL, StatusCode: resp.StatusCode, Status: resp.Status, Body: strings.TrimSpace(string(body))}
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Host
+}
+
+// IsGitHubAPI reports whether url is a GitHub API URL, which accepts the GitHub token
+func IsGitHubAPI(rawURL string) bool {
+	return hostOf(rawURL) == "api.github.com"
+}
+
+// RequestedURL returns the URL a response was requested from, before any redirects. Behind a mirror, it is the
+// mirrored URL.
+func RequestedURL(resp *http.Response) string {
+	req := resp.Request
+	for req.Response != nil && req.Response.Request != nil {
+		req = req.Response.Request
+	}
+	return req.URL.String()
+}
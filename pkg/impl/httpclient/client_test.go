@@ -0,0 +1,168 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// response is what the test server answers to one request
+type response struct {
+	status  int
+	headers map[string]string
+}
+
+// serve answers requests with responses in order, repeating the last one, and counts the requests
+func serve(t *testing.T, responses ...response) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(requests.Add(1)) - 1
+		resp := responses[min(i, len(responses)-1)]
+		for name, value := range resp.headers {
+			w.Header().Set(name, value)
+		}
+		w.WriteHeader(resp.status)
+		io.WriteString(w, http.StatusText(resp.status))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func testClient() *Client {
+	c := NewClient()
+	c.Backoff = time.Millisecond
+	c.MaxRateLimitWait = 2 * time.Second
+	return c
+}
+
+func TestGet(t *testing.T) {
+	ok := response{status: http.StatusOK}
+	tests := []struct {
+		name         string
+		responses    []response
+		wantRequests int32
+		wantStatus   int // of the StatusError, 0 for success
+		wantRate     bool
+		minDuration  time.Duration
+	}{
+		{name: "success", responses: []response{ok}, wantRequests: 1},
+		{name: "server errors are retried", responses: []response{{status: 500}, {status: 502}, ok}, wantRequests: 3},
+		{name: "retries run out", responses: []response{{status: 503}}, wantRequests: 4, wantStatus: 503},
+		{name: "client errors are not retried", responses: []response{{status: 404}}, wantRequests: 1, wantStatus: 404},
+		{
+			name:         "short Retry-After on a server error is waited for",
+			responses:    []response{{status: 503, headers: map[string]string{"Retry-After": "1"}}, ok},
+			wantRequests: 2,
+			minDuration:  time.Second,
+		},
+		{
+			name:         "long Retry-After on a server error fails",
+			responses:    []response{{status: 503, headers: map[string]string{"Retry-After": "3600"}}, ok},
+			wantRequests: 1,
+			wantStatus:   503,
+		},
+		{
+			name:         "short rate limit is waited for",
+			responses:    []response{{status: 429, headers: map[string]string{"Retry-After": "1"}}, ok},
+			wantRequests: 2,
+			minDuration:  time.Second,
+		},
+		{
+			name:         "long rate limit fails",
+			responses:    []response{{status: 429, headers: map[string]string{"Retry-After": "3600"}}, ok},
+			wantRequests: 1,
+			wantRate:     true,
+		},
+		{
+			name: "exhausted rate limit with a reset time fails",
+			responses: []response{{status: 403, headers: map[string]string{
+				"X-RateLimit-Remaining": "0",
+				"X-RateLimit-Reset":     "4102444800",
+			}}, ok},
+			wantRequests: 1,
+			wantRate:     true,
+		},
+		{
+			name:         "forbidden without a rate limit is an error",
+			responses:    []response{{status: 403}},
+			wantRequests: 1,
+			wantStatus:   403,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := serve(t, tt.responses...)
+			start := time.Now()
+			resp, err := testClient().Get(server.URL, http.Header{})
+			if resp != nil {
+				resp.Body.Close()
+			}
+
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("made %d requests, want %d", got, tt.wantRequests)
+			}
+			if elapsed := time.Since(start); elapsed < tt.minDuration {
+				t.Errorf("took %s, want at least %s", elapsed, tt.minDuration)
+			}
+			var statusErr *StatusError
+			var rateErr *RateLimitError
+			switch {
+			case tt.wantStatus != 0:
+				if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.wantStatus {
+					t.Errorf("Get() error = %v, want a %d StatusError", err, tt.wantStatus)
+				}
+			case tt.wantRate:
+				if !errors.As(err, &rateErr) {
+					t.Errorf("Get() error = %v, want a RateLimitError", err)
+				}
+			case err != nil:
+				t.Errorf("Get() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestGetRemembersExhaustedRateLimit(t *testing.T) {
+	server, requests := serve(t, response{status: 429, headers: map[string]string{"Retry-After": "3600"}}, response{status: http.StatusOK})
+	c := testClient()
+	for i := 0; i < 2; i++ {
+		var rateErr *RateLimitError
+		if _, err := c.Get(server.URL, http.Header{}); !errors.As(err, &rateErr) {
+			t.Fatalf("Get() #%d error = %v, want a RateLimitError", i+1, err)
+		}
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("made %d requests, want 1: the host's rate limit is known to be exhausted", got)
+	}
+}
+
+func TestHeaderFor(t *testing.T) {
+	auth := http.Header{"Authorization": []string{"Bearer token"}}
+	tests := []struct {
+		name      string
+		candidate string
+		header    http.Header
+		wantAuth  bool
+	}{
+		{name: "original URL", candidate: "https://api.github.com/repos/a/b", header: auth, wantAuth: true},
+		{name: "mirror on the same host", candidate: "https://API.github.com/mirror/a/b", header: auth, wantAuth: true},
+		{name: "mirror on another host", candidate: "https://mirror.example.com/repos/a/b", header: auth, wantAuth: false},
+		{name: "no credentials", candidate: "https://mirror.example.com/repos/a/b", header: http.Header{}, wantAuth: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := headerFor(tt.candidate, "https://api.github.com/repos/a/b", tt.header)
+			if hasAuth := got.Get("Authorization") != ""; hasAuth != tt.wantAuth {
+				t.Errorf("headerFor(%s) sends Authorization = %v, want %v", tt.candidate, hasAuth, tt.wantAuth)
+			}
+			if auth.Get("Authorization") == "" {
+				t.Errorf("headerFor removed Authorization from the caller's header")
+			}
+		})
+	}
+}
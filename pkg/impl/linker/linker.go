@@ -0,0 +1,355 @@
+package linker
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"rayyanriaz/tool-version-manager/pkg/models"
+)
+
+// CurrentLinkName is the link inside a tool's directory that points to its linked version
+const CurrentLinkName = "current"
+
+// Linker links tools natively from their ToolBase.Symlinks, using the following layout:
+//
+//	<toolDir>/current            -> <toolDir>/<version>
+//	<symlinksDir>/<symlink.To>   -> <toolDir>/current/<symlink.From>
+//
+// Every change is snapshotted first and restored exactly if any step fails.
+type Linker struct {
+	SymlinksDir string
+}
+
+func New(symlinksDir string) *Linker {
+	return &Linker{SymlinksDir: symlinksDir}
+}
+
+// link is one symlink managed by the linker
+type link struct {
+	path   string
+	target string
+}
+
+// snapshot is the state of a link path before it was changed
+type snapshot struct {
+	path       string
+	exists     bool
+	target     string // set if the path was a symlink
+	backupPath string // set if the path was something else, which was moved aside
+}
+
+// LinkedVersion returns the version the tool's current link points to, and when it was linked.
+// An empty version means the tool is not linked.
+func (l *Linker) LinkedVersion(toolDir string) (models.ToolVersion, time.Time, error) {
+	currentPath := filepath.Join(toolDir, CurrentLinkName)
+	info, err := os.Lstat(currentPath)
+	if os.IsNotExist(err) {
+		return "", time.Time{}, nil
+	}
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	target, err := os.Readlink(currentPath)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%s is not a symlink: %w", currentPath, err)
+	}
+	return models.ToolVersion(filepath.Base(target)), info.ModTime(), nil
+}
+
+func (l *Linker) plan(tool models.Tool, toolDir string, version models.ToolVersion) ([]link, error) {
+	absToolDir, err := filepath.Abs(toolDir)
+	if err != nil {
+		return nil, err
+	}
+	absSymlinksDir, err := filepath.Abs(l.SymlinksDir)
+	if err != nil {
+		return nil, err
+	}
+	currentPath := filepath.Join(absToolDir, CurrentLinkName)
+
+	links := []link{{path: currentPath, target: filepath.Join(absToolDir, string(version))}}
+	for _, symlink := range tool.GetSymlinks() {
+		from := strings.TrimSpace(symlink.From)
+		if from == "" {
+			return nil, fmt.Errorf("symlink of tool %s has an empty `from`", tool.GetId())
+		}
+		links = append(links, link{
+			path:   filepath.Join(absSymlinksDir, symlink.LinkName()),
+			target: filepath.Join(currentPath, from),
+		})
+	}
+	return links, nil
+}
+
+// ConflictError is returned when linking would overwrite a file in the symlinks dir that the tool doesn't own
+type ConflictError struct {
+	Path string
+	// Owner is the tool that owns the file, or empty if the file isn't managed by tvm
+	Owner string
+}
+
+func (e *ConflictError) Error() string {
+	if e.Owner != "" {
+		return fmt.Sprintf("refusing to overwrite %s, it belongs to tool %s (use --force to overwrite it)", e.Path, e.Owner)
+	}
+	return fmt.Sprintf("refusing to overwrite %s, it is not managed by tvm (use --force to overwrite it)", e.Path)
+}
+
+// Link points the tool's current link at the version directory inside toolDir, and every symlink of the tool
+// at the current link. Unless force is set, files in the symlinks dir that belong to other tools or aren't
+// managed by tvm are not overwritten. If any link can't be created or verified, all links are restored to
+// their previous state.
+func (l *Linker) Link(tool models.Tool, toolDir string, version models.ToolVersion, force bool) (err error) {
+	if version == "" {
+		return fmt.Errorf("version cannot be empty")
+	}
+	links, err := l.plan(tool, toolDir, version)
+	if err != nil {
+		return err
+	}
+
+	// verify the version has everything we are about to link, before touching anything
+	versionDir := links[0].target
+	if info, err := os.Stat(versionDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("version %s of tool %s is not installed at %s", version, tool.GetId(), versionDir)
+	}
+	for _, symlink := range tool.GetSymlinks() {
+		if _, err := os.Stat(filepath.Join(versionDir, strings.TrimSpace(symlink.From))); err != nil {
+			return fmt.Errorf("version %s of tool %s has no %s to link", version, tool.GetId(), symlink.From)
+		}
+	}
+
+	if !force {
+		// links[0] is the tool's own current link, the rest live in the shared symlinks dir
+		for _, lnk := range links[1:] {
+			if err := checkOwnership(lnk.path, filepath.Dir(links[0].path)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := os.MkdirAll(l.SymlinksDir, 0755); err != nil {
+		return fmt.Errorf("failed to create symlinks directory: %w", err)
+	}
+
+	var snapshots []snapshot
+	defer func() {
+		if err != nil {
+			if restoreErr := restore(snapshots); restoreErr != nil {
+				err = fmt.Errorf("%w; additionally failed to restore previous links: %v", err, restoreErr)
+			}
+			return
+		}
+		dropBackups(snapshots)
+	}()
+
+	for _, lnk := range links {
+		snap, err := take(lnk.path)
+		if err != nil {
+			return err
+		}
+		snapshots = append(snapshots, snap)
+		if err := replaceWithSymlink(lnk.path, lnk.target); err != nil {
+			return fmt.Errorf("failed to link %s: %w", lnk.path, err)
+		}
+	}
+
+	for _, lnk := range links {
+		if err := verify(lnk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unlink removes the tool's current link, and every symlink of the tool that points into toolDir.
+// Links in the symlinks dir that point elsewhere are left alone. On failure, all links are restored.
+func (l *Linker) Unlink(tool models.Tool, toolDir string) (err error) {
+	version, _, err := l.LinkedVersion(toolDir)
+	if err != nil {
+		return err
+	}
+	links, err := l.plan(tool, toolDir, version)
+	if err != nil {
+		return err
+	}
+	absToolDir := filepath.Dir(links[0].path)
+
+	var snapshots []snapshot
+	defer func() {
+		if err != nil {
+			if restoreErr := restore(snapshots); restoreErr != nil {
+				err = fmt.Errorf("%w; additionally failed to restore previous links: %v", err, restoreErr)
+			}
+			return
+		}
+		dropBackups(snapshots)
+	}()
+
+	// remove the binaries first and `current` last, so that an interrupted unlink still shows as linked
+	for i := len(links) - 1; i >= 0; i-- {
+		lnk := links[i]
+		target, readErr := os.Readlink(lnk.path)
+		if os.IsNotExist(readErr) {
+			continue
+		}
+		if readErr != nil || !isWithin(target, absToolDir) {
+			slog.Warn("Leaving link that doesn't belong to the tool", "tool", tool.GetId(), "path", lnk.path, "target", target)
+			continue
+		}
+		snap, err := take(lnk.path)
+		if err != nil {
+			return err
+		}
+		snapshots = append(snapshots, snap)
+		if err := os.Remove(lnk.path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", lnk.path, err)
+		}
+	}
+	return nil
+}
+
+// checkOwnership returns a ConflictError if path exists and isn't a link into toolDir.
+// Links into sibling directories of toolDir belong to other tools.
+func checkOwnership(path, toolDir string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return &ConflictError{Path: path}
+	}
+	target, err := os.Readlink(path)
+	if err != nil {
+		return err
+	}
+	if isWithin(target, toolDir) {
+		return nil
+	}
+	toolsDir := filepath.Dir(toolDir)
+	if isWithin(target, toolsDir) {
+		rel, _ := filepath.Rel(toolsDir, target)
+		return &ConflictError{Path: path, Owner: strings.Split(rel, string(filepath.Separator))[0]}
+	}
+	return &ConflictError{Path: path}
+}
+
+// Verify checks that every link of the tool exists and resolves to the expected target
+func (l *Linker) Verify(tool models.Tool, toolDir string) error {
+	version, _, err := l.LinkedVersion(toolDir)
+	if err != nil {
+		return err
+	}
+	if version == "" {
+		return fmt.Errorf("tool %s is not linked", tool.GetId())
+	}
+	links, err := l.plan(tool, toolDir, version)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, lnk := range links {
+		errs = append(errs, verify(lnk))
+	}
+	return errors.Join(errs...)
+}
+
+func verify(lnk link) error {
+	target, err := os.Readlink(lnk.path)
+	if err != nil {
+		return fmt.Errorf("%s is not a symlink: %w", lnk.path, err)
+	}
+	if target != lnk.target {
+		return fmt.Errorf("%s points to %s instead of %s", lnk.path, target, lnk.target)
+	}
+	if _, err := os.Stat(lnk.path); err != nil {
+		return fmt.Errorf("%s is dangling: %w", lnk.path, err)
+	}
+	return nil
+}
+
+// take snapshots a path. Anything that isn't a symlink is moved aside, so that it can be restored.
+func take(path string) (snapshot, error) {
+	snap := snapshot{path: path}
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return snap, nil
+	}
+	if err != nil {
+		return snap, err
+	}
+	snap.exists = true
+	if info.IsDir() {
+		return snap, fmt.Errorf("%s is a directory, refusing to replace it with a link", path)
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		snap.target, err = os.Readlink(path)
+		return snap, err
+	}
+	snap.backupPath = backupPath(path)
+	if err := os.Rename(path, snap.backupPath); err != nil {
+		return snap, fmt.Errorf("failed to move %s aside: %w", path, err)
+	}
+	return snap, nil
+}
+
+// restore puts every snapshotted path back the way it was, in reverse order
+func restore(snapshots []snapshot) error {
+	var errs []error
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		snap := snapshots[i]
+		if err := os.Remove(snap.path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+			continue
+		}
+		switch {
+		case !snap.exists:
+		case snap.backupPath != "":
+			errs = append(errs, os.Rename(snap.backupPath, snap.path))
+		default:
+			errs = append(errs, os.Symlink(snap.target, snap.path))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func dropBackups(snapshots []snapshot) {
+	for _, snap := range snapshots {
+		if snap.backupPath != "" {
+			if err := os.RemoveAll(snap.backupPath); err != nil {
+				slog.Warn("Failed to remove backup", "path", snap.backupPath, "error", err)
+			}
+		}
+	}
+}
+
+// replaceWithSymlink atomically replaces path with a symlink to target
+func replaceWithSymlink(path, target string) error {
+	tmp := fmt.Sprintf("%s.tvm-tmp-%d", path, os.Getpid())
+	_ = os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func backupPath(path string) string {
+	return fmt.Sprintf("%s.tvm-backup-%d", path, os.Getpid())
+}
+
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,261 @@
+package linker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"rayyanriaz/tool-version-manager/pkg/models"
+)
+
+// setup creates a downloads dir with the given versions of a tool "tool" that ship bin/a and bin/b,
+// and returns a linker for a symlinks dir next to it
+func setup(t *testing.T, versions ...models.ToolVersion) (*Linker, models.Tool, string) {
+	t.Helper()
+	root := t.TempDir()
+	toolDir := filepath.Join(root, "downloads", "tool")
+	for _, version := range versions {
+		binDir := filepath.Join(toolDir, string(version), "bin")
+		if err := os.MkdirAll(binDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"a", "b"} {
+			if err := os.WriteFile(filepath.Join(binDir, name), []byte(version), 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	tool := &models.ToolBase{Id: "tool", Symlinks: []models.ToolSymlink{{From: "bin/a"}, {From: "bin/b", To: "bee"}}}
+	return New(filepath.Join(root, "bin")), tool, toolDir
+}
+
+// content reads what a link in the symlinks dir resolves to
+func content(t *testing.T, l *Linker, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(l.SymlinksDir, name))
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", name, err)
+	}
+	return string(data)
+}
+
+func TestLink(t *testing.T) {
+	tests := []struct {
+		name    string
+		link    []models.ToolVersion
+		want    models.ToolVersion
+		wantErr bool
+	}{
+		{name: "first link", link: []models.ToolVersion{"1.0"}, want: "1.0"},
+		{name: "relink", link: []models.ToolVersion{"1.0", "2.0"}, want: "2.0"},
+		{name: "relink same version", link: []models.ToolVersion{"2.0", "2.0"}, want: "2.0"},
+		{name: "not installed", link: []models.ToolVersion{"1.0", "3.0"}, want: "1.0", wantErr: true},
+		{name: "empty version", link: []models.ToolVersion{""}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, tool, toolDir := setup(t, "1.0", "2.0")
+			var err error
+			for _, version := range tt.link {
+				err = l.Link(tool, toolDir, version, false)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Link() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			linked, _, err := l.LinkedVersion(toolDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if linked != tt.want {
+				t.Errorf("LinkedVersion() = %q, want %q", linked, tt.want)
+			}
+			if tt.want == "" {
+				return
+			}
+			if got := content(t, l, "a"); got != string(tt.want) {
+				t.Errorf("a resolves to version %q, want %q", got, tt.want)
+			}
+			if got := content(t, l, "bee"); got != string(tt.want) {
+				t.Errorf("bee resolves to version %q, want %q", got, tt.want)
+			}
+			if err := l.Verify(tool, toolDir); err != nil {
+				t.Errorf("Verify() = %v", err)
+			}
+		})
+	}
+}
+
+func TestLinkConflicts(t *testing.T) {
+	tests := []struct {
+		name      string
+		existing  func(t *testing.T, path, toolsDir string)
+		force     bool
+		wantOwner string
+		wantErr   bool
+	}{
+		{
+			name: "file not managed by tvm",
+			existing: func(t *testing.T, path, _ string) {
+				if err := os.WriteFile(path, []byte("mine"), 0755); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "link owned by another tool",
+			existing: func(t *testing.T, path, toolsDir string) {
+				if err := os.Symlink(filepath.Join(toolsDir, "other", "current", "a"), path); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantOwner: "other",
+			wantErr:   true,
+		},
+		{
+			name: "link outside of tvm",
+			existing: func(t *testing.T, path, _ string) {
+				if err := os.Symlink("/usr/bin/true", path); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "forced over a file",
+			existing: func(t *testing.T, path, _ string) {
+				if err := os.WriteFile(path, []byte("mine"), 0755); err != nil {
+					t.Fatal(err)
+				}
+			},
+			force: true,
+		},
+		{
+			name: "forced over another tool",
+			existing: func(t *testing.T, path, toolsDir string) {
+				if err := os.Symlink(filepath.Join(toolsDir, "other", "current", "a"), path); err != nil {
+					t.Fatal(err)
+				}
+			},
+			force: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, tool, toolDir := setup(t, "1.0")
+			if err := os.MkdirAll(l.SymlinksDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(l.SymlinksDir, "a")
+			tt.existing(t, path, filepath.Dir(toolDir))
+
+			err := l.Link(tool, toolDir, "1.0", tt.force)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("Link() error = %v", err)
+				}
+				if got := content(t, l, "a"); got != "1.0" {
+					t.Errorf("a resolves to version %q, want 1.0", got)
+				}
+				backups, _ := filepath.Glob(filepath.Join(l.SymlinksDir, "*.tvm-backup-*"))
+				if len(backups) != 0 {
+					t.Errorf("backups left behind: %v", backups)
+				}
+				return
+			}
+
+			var conflict *ConflictError
+			if !errors.As(err, &conflict) {
+				t.Fatalf("Link() error = %v, want a ConflictError", err)
+			}
+			if conflict.Path != path || conflict.Owner != tt.wantOwner {
+				t.Errorf("ConflictError = %+v, want path %s and owner %q", conflict, path, tt.wantOwner)
+			}
+			if linked, _, _ := l.LinkedVersion(toolDir); linked != "" {
+				t.Errorf("tool was linked to %q despite the conflict", linked)
+			}
+		})
+	}
+}
+
+func TestLinkRestoresOnFailure(t *testing.T) {
+	l, tool, toolDir := setup(t, "1.0", "2.0")
+	if err := l.Link(tool, toolDir, "1.0", false); err != nil {
+		t.Fatal(err)
+	}
+	// a directory where the second link goes makes linking fail after the first links were switched
+	if err := os.Remove(filepath.Join(l.SymlinksDir, "bee")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(l.SymlinksDir, "bee"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Link(tool, toolDir, "2.0", true); err == nil {
+		t.Fatal("Link() succeeded over a directory")
+	}
+	if linked, _, _ := l.LinkedVersion(toolDir); linked != "1.0" {
+		t.Errorf("LinkedVersion() = %q after a failed link, want 1.0", linked)
+	}
+	if got := content(t, l, "a"); got != "1.0" {
+		t.Errorf("a resolves to version %q after a failed link, want 1.0", got)
+	}
+	if info, err := os.Lstat(filepath.Join(l.SymlinksDir, "bee")); err != nil || !info.IsDir() {
+		t.Errorf("the directory in the way was not kept: %v", err)
+	}
+}
+
+func TestUnlink(t *testing.T) {
+	tests := []struct {
+		name string
+		// foreign replaces the tool's link to bee with a link that isn't the tool's
+		foreign   bool
+		wantLinks []string
+	}{
+		{name: "removes own links", wantLinks: nil},
+		{name: "leaves foreign links", foreign: true, wantLinks: []string{"bee"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, tool, toolDir := setup(t, "1.0")
+			if err := l.Link(tool, toolDir, "1.0", false); err != nil {
+				t.Fatal(err)
+			}
+			if tt.foreign {
+				bee := filepath.Join(l.SymlinksDir, "bee")
+				if err := os.Remove(bee); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink("/usr/bin/true", bee); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := l.Unlink(tool, toolDir); err != nil {
+				t.Fatalf("Unlink() error = %v", err)
+			}
+			if linked, _, _ := l.LinkedVersion(toolDir); linked != "" {
+				t.Errorf("LinkedVersion() = %q after unlinking", linked)
+			}
+			entries, err := os.ReadDir(l.SymlinksDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var links []string
+			for _, entry := range entries {
+				links = append(links, entry.Name())
+			}
+			if len(links) != len(tt.wantLinks) || (len(links) > 0 && links[0] != tt.wantLinks[0]) {
+				t.Errorf("links left = %v, want %v", links, tt.wantLinks)
+			}
+		})
+	}
+}
+
+func TestUnlinkNotLinked(t *testing.T) {
+	l, tool, toolDir := setup(t, "1.0")
+	if err := l.Unlink(tool, toolDir); err != nil {
+		t.Errorf("Unlink() of a tool that isn't linked = %v", err)
+	}
+}
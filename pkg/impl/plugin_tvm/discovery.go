@@ -0,0 +1,79 @@
+package plugintvm
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"rayyanriaz/tool-version-manager/pkg/plugin"
+)
+
+var toolTypePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Plugin is a plugin executable that was found
+type Plugin struct {
+	Type string
+	Path string
+}
+
+// FindPlugin looks for the executable of a tool type's plugin in pluginsDir, then on PATH
+func FindPlugin(toolType, pluginsDir string) (string, error) {
+	if !toolTypePattern.MatchString(toolType) {
+		return "", fmt.Errorf("invalid tool type %q, plugin types use lowercase letters, digits, '-' and '_'", toolType)
+	}
+	name := plugin.ExecutablePrefix + toolType
+	if pluginsDir != "" {
+		path := filepath.Join(pluginsDir, name)
+		if isExecutable(path) {
+			return path, nil
+		}
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("no %s in %s or on PATH", name, pluginsDir)
+	}
+	return path, nil
+}
+
+// ListPlugins lists the plugins in pluginsDir and on PATH. A plugin in pluginsDir shadows one on PATH.
+func ListPlugins(pluginsDir string) []Plugin {
+	found := make(map[string]string)
+	dirs := filepath.SplitList(os.Getenv("PATH"))
+	// earlier directories win, like they do for exec.LookPath
+	for i := len(dirs) - 1; i >= 0; i-- {
+		collectPlugins(dirs[i], found)
+	}
+	if pluginsDir != "" {
+		collectPlugins(pluginsDir, found)
+	}
+
+	var plugins []Plugin
+	for toolType, path := range found {
+		plugins = append(plugins, Plugin{Type: toolType, Path: path})
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Type < plugins[j].Type })
+	return plugins
+}
+
+func collectPlugins(dir string, found map[string]string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		toolType, ok := strings.CutPrefix(entry.Name(), plugin.ExecutablePrefix)
+		path := filepath.Join(dir, entry.Name())
+		if ok && toolTypePattern.MatchString(toolType) && isExecutable(path) {
+			found[toolType] = path
+		}
+	}
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir() && info.Mode().Perm()&0111 != 0
+}
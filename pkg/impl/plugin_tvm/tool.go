@@ -0,0 +1,9 @@
+package plugintvm
+
+import "rayyanriaz/tool-version-manager/pkg/models"
+
+// PluginTool is a tool managed by an external plugin. Extra holds settings only the plugin understands.
+type PluginTool struct {
+	models.ToolBase `yaml:",inline"`
+	Extra           map[string]interface{} `json:"extra,omitempty"`
+}
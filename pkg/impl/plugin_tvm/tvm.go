@@ -0,0 +1,431 @@
+package plugintvm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"rayyanriaz/tool-version-manager/pkg/impl/config"
+	"rayyanriaz/tool-version-manager/pkg/impl/linker"
+	"rayyanriaz/tool-version-manager/pkg/impl/state"
+	"rayyanriaz/tool-version-manager/pkg/models"
+	"rayyanriaz/tool-version-manager/pkg/plugin"
+	"rayyanriaz/tool-version-manager/pkg/utils"
+)
+
+// PluginTVM manages the tools of one type through an external plugin executable, see package plugin.
+// Operations the plugin doesn't implement fall back to tvm's native implementation.
+type PluginTVM struct {
+	toolType      string
+	configService *config.LocalFileConfig
+	installStore  *state.InstallStore
+	offline       bool
+	// refetch tells the plugin not to use cached downloads, for rebuilt versions
+	refetch bool
+
+	// the plugin is located and described on first use, once the config is loaded
+	resolveOnce sync.Once
+	path        string
+	operations  []string
+	resolveErr  error
+}
+
+// NewPluginTVM creates the manager of a tool type whose plugin is located in the config's plugins dir or on PATH
+func NewPluginTVM(toolType string, configService *config.LocalFileConfig) *PluginTVM {
+	return &PluginTVM{
+		toolType:      toolType,
+		configService: configService,
+	}
+}
+
+// NewPluginTVMAt creates the manager of a tool type whose plugin is the executable at path
+func NewPluginTVMAt(toolType, path string, configService *config.LocalFileConfig) *PluginTVM {
+	t := NewPluginTVM(toolType, configService)
+	t.path = path
+	return t
+}
+
+// UseInstallStore makes the TVM record installs in the store and skip broken versions
+func (t *PluginTVM) UseInstallStore(store *state.InstallStore) {
+	t.installStore = store
+}
+
+// SetOffline tells the plugin not to use the network, in its request context and with TVM_OFFLINE=1 in its environment
+func (t *PluginTVM) SetOffline(offline bool) {
+	t.offline = offline
+}
+
+// Resolve locates the plugin and checks that it speaks the protocol and implements the required operations
+func (t *PluginTVM) Resolve() error {
+	t.resolveOnce.Do(func() {
+		if t.path == "" {
+			if t.path, t.resolveErr = FindPlugin(t.toolType, t.configService.PluginsDir); t.resolveErr != nil {
+				return
+			}
+		}
+		resp, err := t.run(plugin.Request{Operation: plugin.OpDescribe})
+		if err != nil {
+			t.resolveErr = err
+			return
+		}
+		if resp.ProtocolVersion != plugin.ProtocolVersion {
+			t.resolveErr = fmt.Errorf("plugin %s speaks protocol version %d, tvm speaks %d", t.path, resp.ProtocolVersion, plugin.ProtocolVersion)
+			return
+		}
+		for _, op := range plugin.RequiredOperations {
+			if !slices.Contains(resp.Operations, op) {
+				t.resolveErr = fmt.Errorf("plugin %s doesn't implement the required operation %s", t.path, op)
+				return
+			}
+		}
+		t.operations = resp.Operations
+	})
+	return t.resolveErr
+}
+
+// Path returns the plugin executable
+func (t *PluginTVM) Path() (string, error) {
+	if err := t.Resolve(); err != nil {
+		return "", err
+	}
+	return t.path, nil
+}
+
+// Supports reports whether the plugin implements an operation itself
+func (t *PluginTVM) Supports(op string) bool {
+	return t.Resolve() == nil && slices.Contains(t.operations, op)
+}
+
+// SymlinksDir is where the tools' symlinks go
+func (t *PluginTVM) SymlinksDir() string {
+	return t.configService.SymlinksDir
+}
+
+func (t *PluginTVM) toolDir(tool models.Tool) string {
+	return filepath.Join(t.configService.DownloadsDir, tool.GetId())
+}
+
+func (t *PluginTVM) versionDir(tool models.Tool, version models.ToolVersion) string {
+	return filepath.Join(t.toolDir(tool), string(version))
+}
+
+func (t *PluginTVM) linker() *linker.Linker {
+	return linker.New(t.configService.SymlinksDir)
+}
+
+// Call runs any operation on a tool with the plugin, for checking plugins. tool may be nil.
+func (t *PluginTVM) Call(op string, tool models.Tool, req plugin.Request) (plugin.Response, error) {
+	if tool == nil {
+		if err := t.Resolve(); err != nil {
+			return plugin.Response{}, err
+		}
+		req.Operation = op
+		return t.run(req)
+	}
+	return t.call(op, tool, req)
+}
+
+// call runs an operation on a tool with the plugin
+func (t *PluginTVM) call(op string, tool models.Tool, req plugin.Request) (plugin.Response, error) {
+	if err := t.Resolve(); err != nil {
+		return plugin.Response{}, err
+	}
+	toolJSON, err := json.Marshal(tool)
+	if err != nil {
+		return plugin.Response{}, fmt.Errorf("failed to encode tool %s: %w", tool.GetId(), err)
+	}
+	req.Operation = op
+	req.Tool = toolJSON
+	req.Context.ToolDir = t.toolDir(tool)
+	if req.Version != "" {
+		req.Context.VersionDir = t.versionDir(tool, req.Version)
+	}
+	resp, err := t.run(req)
+	if err != nil {
+		return plugin.Response{}, fmt.Errorf("tool %s: %w", tool.GetId(), err)
+	}
+	return resp, nil
+}
+
+// run sends a request to the plugin and reads its response
+func (t *PluginTVM) run(req plugin.Request) (plugin.Response, error) {
+	req.ProtocolVersion = plugin.ProtocolVersion
+	req.Context.DownloadsDir = t.configService.DownloadsDir
+	req.Context.SymlinksDir = t.configService.SymlinksDir
+	req.Context.Offline = t.offline
+	req.Context.Refetch = t.refetch
+	input, err := json.Marshal(req)
+	if err != nil {
+		return plugin.Response{}, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(t.path)
+	cmd.Env = append(os.Environ(), utils.TvmEnv(t.configService.GetConfigFilePath(), t.offline, t.refetch)...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+	slog.Debug("Ran plugin", "plugin", t.path, "operation", req.Operation, "output", stdout.String(), "stderr", stderr.String(), "error", runErr)
+
+	var resp plugin.Response
+	decodeErr := json.Unmarshal(stdout.Bytes(), &resp)
+	if decodeErr == nil && resp.Error != "" {
+		err := errors.New(resp.Error)
+		if resp.ErrorCode == plugin.ErrorCodeOffline {
+			err = fmt.Errorf("%s: %w", resp.Error, models.ErrOffline)
+		}
+		return plugin.Response{}, fmt.Errorf("plugin %s failed to %s: %w", t.toolType, req.Operation, err)
+	}
+	if runErr != nil {
+		if tail := utils.OutputTail(stderr.String()); tail != "" {
+			return plugin.Response{}, fmt.Errorf("plugin %s failed to %s: %w: %s", t.toolType, req.Operation, runErr, tail)
+		}
+		return plugin.Response{}, fmt.Errorf("plugin %s failed to %s: %w", t.toolType, req.Operation, runErr)
+	}
+	if decodeErr != nil {
+		return plugin.Response{}, fmt.Errorf("plugin %s sent an invalid response to %s: %w", t.toolType, req.Operation, decodeErr)
+	}
+	return resp, nil
+}
+
+func (t *PluginTVM) CreateNewTool() models.Tool {
+	return &PluginTool{}
+}
+
+func (t *PluginTVM) GetAllLocalVersions(tool models.Tool) ([]models.ToolVersion, error) {
+	var vs []models.ToolVersion
+	if t.Supports(plugin.OpListLocalVersions) {
+		resp, err := t.call(plugin.OpListLocalVersions, tool, plugin.Request{})
+		if err != nil {
+			return nil, err
+		}
+		vs = resp.Versions
+	} else {
+		// natively, every version directory is a local version
+		entries, err := os.ReadDir(t.toolDir(tool))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to get all local versions for tool %s: %w", tool.GetId(), err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() && entry.Name() != linker.CurrentLinkName && !strings.HasPrefix(entry.Name(), ".") {
+				vs = append(vs, models.ToolVersion(entry.Name()))
+			}
+		}
+	}
+
+	if t.installStore != nil {
+		vs = slices.DeleteFunc(vs, func(v models.ToolVersion) bool {
+			record, found := t.installStore.Get(tool.GetId(), v)
+			return found && record.Broken != ""
+		})
+	}
+	// newest first, like remote versions
+	sort.SliceStable(vs, func(i, j int) bool {
+		result, err := t.CompareVersions(tool, vs[i], vs[j])
+		return err == nil && result > 0
+	})
+	return vs, nil
+}
+
+func (t *PluginTVM) GetAllRemoteVersions(tool models.Tool) ([]models.ToolVersion, error) {
+	resp, err := t.call(plugin.OpListRemoteVersions, tool, plugin.Request{})
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range resp.Versions {
+		if _, err := models.ParseVersionOutput(string(v)); err != nil {
+			return nil, fmt.Errorf("plugin %s listed an invalid version of tool %s: %w", t.toolType, tool.GetId(), err)
+		}
+	}
+	return resp.Versions, nil
+}
+
+func (t *PluginTVM) GetLatestRemoteVersion(tool models.Tool) (models.ToolVersion, error) {
+	resp, err := t.call(plugin.OpLatestRemoteVersion, tool, plugin.Request{})
+	if err != nil {
+		return "", err
+	}
+	version, err := models.ParseVersionOutput(string(resp.Version))
+	if err != nil {
+		return "", fmt.Errorf("failed to get latest remote version for tool %s: %w", tool.GetId(), err)
+	}
+	return version, nil
+}
+
+func (t *PluginTVM) InstallToolForVersion(tool models.Tool, version models.ToolVersion) error {
+	sourceURL, err := t.install(tool, version)
+	if err != nil {
+		return err
+	}
+	t.recordInstall(tool, version, sourceURL)
+	return nil
+}
+
+// ReinstallToolForVersion installs a fresh build of an installed version into a temporary downloads dir and
+// swaps it in only once the install succeeded, see models.ToolReinstaller
+func (t *PluginTVM) ReinstallToolForVersion(tool models.Tool, version models.ToolVersion) error {
+	if err := t.Resolve(); err != nil {
+		return err
+	}
+	// stage next to the version directories, so the new build can be moved into place with a rename
+	stagingDir, err := os.MkdirTemp(t.configService.DownloadsDir, ".reinstall-*")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	stagingConfig := *t.configService
+	stagingConfig.DownloadsDir = stagingDir
+	staged := NewPluginTVMAt(t.toolType, t.path, &stagingConfig)
+	staged.SetOffline(t.offline)
+	// the cached artifact of a moving tag is the old build, so it is downloaded again
+	staged.refetch = models.IsMovingTag(version) && !t.offline
+	sourceURL, err := staged.install(tool, version)
+	if err != nil {
+		return err
+	}
+
+	if err := utils.ReplaceDir(t.versionDir(tool, version), staged.versionDir(tool, version)); err != nil {
+		return fmt.Errorf("failed to replace tool %s version %s with its new build: %w", tool.GetId(), version, err)
+	}
+	t.recordInstall(tool, version, sourceURL)
+	return nil
+}
+
+// install has the plugin install a version and returns the URL it reported downloading it from
+func (t *PluginTVM) install(tool models.Tool, version models.ToolVersion) (string, error) {
+	if version == "" {
+		return "", fmt.Errorf("version cannot be empty")
+	}
+	if err := os.MkdirAll(t.toolDir(tool), 0755); err != nil {
+		return "", err
+	}
+	resp, err := t.call(plugin.OpInstall, tool, plugin.Request{Version: version})
+	if err != nil {
+		return "", fmt.Errorf("failed to install tool %s for version %s: %w", tool.GetId(), version, err)
+	}
+	if _, err := os.Stat(t.versionDir(tool, version)); err != nil {
+		return "", fmt.Errorf("plugin %s reported tool %s version %s as installed, but %s doesn't exist", t.toolType, tool.GetId(), version, t.versionDir(tool, version))
+	}
+	return resp.SourceURL, nil
+}
+
+// recordInstall stores the metadata of a freshly installed version, if the TVM has an install store
+func (t *PluginTVM) recordInstall(tool models.Tool, version models.ToolVersion, sourceURL string) {
+	if t.installStore == nil {
+		return
+	}
+	record := state.InstallRecord{Version: version, InstalledAt: time.Now(), SourceURL: sourceURL}
+	var err error
+	if record.Digest, record.SizeBytes, err = utils.DirDigest(t.versionDir(tool, version)); err != nil {
+		slog.Warn("Failed to hash installed version", "tool", tool.GetId(), "version", version, "error", err)
+	}
+	if err := t.installStore.Put(tool.GetId(), record); err != nil {
+		slog.Warn("Failed to record install metadata", "tool", tool.GetId(), "version", version, "error", err)
+	}
+}
+
+// UninstallToolVersion removes an installed version and its install record
+func (t *PluginTVM) UninstallToolVersion(tool models.Tool, version models.ToolVersion) error {
+	if version == "" {
+		return fmt.Errorf("version cannot be empty")
+	}
+	if t.Supports(plugin.OpUninstall) {
+		if _, err := t.call(plugin.OpUninstall, tool, plugin.Request{Version: version}); err != nil {
+			return err
+		}
+	} else if err := os.RemoveAll(t.versionDir(tool, version)); err != nil {
+		return fmt.Errorf("failed to remove tool %s version %s: %w", tool.GetId(), version, err)
+	}
+	if t.installStore != nil {
+		if err := t.installStore.Remove(tool.GetId(), version); err != nil {
+			return fmt.Errorf("failed to remove install record of tool %s version %s: %w", tool.GetId(), version, err)
+		}
+	}
+	return nil
+}
+
+func (t *PluginTVM) LinkTool(tool models.Tool, version models.ToolVersion, force bool) error {
+	if version == "" {
+		return fmt.Errorf("version cannot be empty")
+	}
+	if t.installStore != nil {
+		if record, found := t.installStore.Get(tool.GetId(), version); found && record.Broken != "" {
+			return fmt.Errorf("tool %s version %s is broken: %s", tool.GetId(), version, record.Broken)
+		}
+	}
+	if t.Supports(plugin.OpLink) {
+		_, err := t.call(plugin.OpLink, tool, plugin.Request{Version: version, Force: force})
+		return err
+	}
+	if err := t.linker().Link(tool, t.toolDir(tool), version, force); err != nil {
+		return fmt.Errorf("failed to link tool %s to version %s: %w", tool.GetId(), version, err)
+	}
+	return nil
+}
+
+func (t *PluginTVM) UnlinkTool(tool models.Tool) error {
+	if t.Supports(plugin.OpUnlink) {
+		_, err := t.call(plugin.OpUnlink, tool, plugin.Request{})
+		return err
+	}
+	currentVersion, _, err := t.linker().LinkedVersion(t.toolDir(tool))
+	if err != nil {
+		return fmt.Errorf("failed to get linked version: %w", err)
+	}
+	if currentVersion == "" {
+		return fmt.Errorf("tool %s is not linked to any version", tool.GetId())
+	}
+	if err := t.linker().Unlink(tool, t.toolDir(tool)); err != nil {
+		return fmt.Errorf("failed to unlink tool %s: %w", tool.GetId(), err)
+	}
+	return nil
+}
+
+func (t *PluginTVM) GetLinkInfo(tool models.Tool) (*models.ToolLinkInfo, error) {
+	if t.Supports(plugin.OpLinkInfo) {
+		resp, err := t.call(plugin.OpLinkInfo, tool, plugin.Request{})
+		if err != nil {
+			return nil, err
+		}
+		if resp.LinkInfo == nil {
+			return &models.ToolLinkInfo{}, nil
+		}
+		return resp.LinkInfo, nil
+	}
+	version, linkedAt, err := t.linker().LinkedVersion(t.toolDir(tool))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get link info for tool %s: %w", tool.GetId(), err)
+	}
+	linkInfo := &models.ToolLinkInfo{Version: version}
+	if version != "" {
+		linkInfo.LinkedAt = linkedAt.Format("2006-01-02 15:04:05.000000000 -0700")
+	}
+	return linkInfo, nil
+}
+
+func (t *PluginTVM) CompareVersions(tool models.Tool, v1, v2 models.ToolVersion) (int, error) {
+	if t.Supports(plugin.OpCompare) {
+		resp, err := t.call(plugin.OpCompare, tool, plugin.Request{V1: v1, V2: v2})
+		if err != nil {
+			return 0, err
+		}
+		return resp.Comparison, nil
+	}
+	return models.CompareVersions(tool, v1, v2)
+}
+
+var _ models.ToolVersionManager = (*PluginTVM)(nil)
+var _ models.ToolUninstaller = (*PluginTVM)(nil)
+var _ models.ToolReinstaller = (*PluginTVM)(nil)
@@ -17,12 +17,33 @@ type ScriptsDrivenTool struct {
 			GetAllRemoteVersions   []utils.ScriptStep `json:"getAllRemoteVersions"`
 			GetLatestRemoteVersion []utils.ScriptStep `json:"getLatestRemoteVersion"`
 			FetchToolForVersion    []utils.ScriptStep `json:"fetchToolForVersion"`
-			GetLinkInfo            []utils.ScriptStep `json:"getLinkInfo"`
-			LinkTool               []utils.ScriptStep `json:"linkTool"`
-			UnlinkTool             []utils.ScriptStep `json:"unlinkTool"`
+			GetLinkInfo            []utils.ScriptStep `json:"getLinkInfo,omitempty"`
+			// CompareVersions orders {{.Compare.V1}} and {{.Compare.V2}} for the "script" version scheme,
+			// printing a negative number, zero or a positive number
+			CompareVersions []utils.ScriptStep `json:"compareVersions,omitempty"`
+			// GetRemoteDigest prints a fingerprint of the upstream artifact of version {{.Arg}}, e.g. its sha256 or
+			// last-modified date, so that moving tags like `nightly` are refetched when upstream rebuilds them
+			GetRemoteDigest []utils.ScriptStep `json:"getRemoteDigest,omitempty"`
 		} `json:"scripts"`
 	} `json:"source"`
+	// Hooks run around installing, linking and unlinking, with the same template variables as the scripts
+	Hooks ToolHooks              `json:"hooks,omitempty"`
 	Extra map[string]interface{} `json:"extra,omitempty"`
+
+	// tvm is the manager that created the tool, which runs its compareVersions script
+	tvm *ScriptsDrivenTVM
+}
+
+// ToolHooks are optional script steps run before and after the lifecycle operations of a tool.
+// A failing pre hook aborts the operation. A failing post_install rolls the install back,
+// post_link and post_unlink failures are only reported since the links are already in place.
+type ToolHooks struct {
+	PreInstall  []utils.ScriptStep `json:"pre_install,omitempty"`
+	PostInstall []utils.ScriptStep `json:"post_install,omitempty"`
+	PreLink     []utils.ScriptStep `json:"pre_link,omitempty"`
+	PostLink    []utils.ScriptStep `json:"post_link,omitempty"`
+	PreUnlink   []utils.ScriptStep `json:"pre_unlink,omitempty"`
+	PostUnlink  []utils.ScriptStep `json:"post_unlink,omitempty"`
 }
 
 func (t ScriptsDrivenTool) ShellFriendlySymlinks() string {
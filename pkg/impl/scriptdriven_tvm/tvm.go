@@ -4,32 +4,91 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"rayyanriaz/tool-version-manager/pkg/impl/config"
+	"rayyanriaz/tool-version-manager/pkg/impl/github"
+	"rayyanriaz/tool-version-manager/pkg/impl/httpclient"
+	"rayyanriaz/tool-version-manager/pkg/impl/linker"
+	"rayyanriaz/tool-version-manager/pkg/impl/state"
 	"rayyanriaz/tool-version-manager/pkg/models"
 	"rayyanriaz/tool-version-manager/pkg/utils"
 )
 
 type ScriptsDrivenTVM struct {
-	configService *config.LocalFileConfig
+	configService      *config.LocalFileConfig
+	installStore       *state.InstallStore
+	remoteVersionCache *config.RemoteVersionsCache
+	offline            bool
+	tvmExecutable      string
+	httpClient         *httpclient.Client
+	// refetch makes install scripts download again instead of using cached artifacts, for rebuilt versions
+	refetch bool
 }
 
-func NewScriptsDrivenTVM() *ScriptsDrivenTVM {
+func init() {
+	// tools with the "script" version scheme are ordered by the TVM that created them
+	models.VersionSchemes.Register("script", models.ToolComparerFunc(compareVersionsWithScript))
+}
+
+func NewScriptsDrivenTVM(configService *config.LocalFileConfig) *ScriptsDrivenTVM {
 	slog.Debug("Creating new ScriptsDrivenTVM")
-	configService, err := models.ToolRegistrar.GetConfig("scripts_driven")
-	if err != nil {
-		slog.Error("Failed to get config service for ScriptsDrivenTVM", "error", err)
-		return nil
-	}
 	return &ScriptsDrivenTVM{
-		configService: configService.(*config.LocalFileConfig),
+		configService: configService,
+		httpClient:    httpclient.Default,
 	}
 }
 
+// UseHTTPClient makes the TVM send its own requests, like those for release notes, with client
+func (t *ScriptsDrivenTVM) UseHTTPClient(client *httpclient.Client) {
+	t.httpClient = client
+}
+
+// UseInstallStore makes the TVM record installs in the store and list local versions from it
+func (t *ScriptsDrivenTVM) UseInstallStore(store *state.InstallStore) {
+	t.installStore = store
+}
+
+// UseRemoteVersionsCache makes the TVM persist which upstream tag each version was derived from in the cache,
+// for tools with a tag_filter or tag_transform
+func (t *ScriptsDrivenTVM) UseRemoteVersionsCache(cache *config.RemoteVersionsCache) {
+	t.remoteVersionCache = cache
+}
+
+// SetOffline makes the TVM answer from the remote versions cache instead of running discovery scripts.
+// Scripts see TVM_OFFLINE=1 in their environment and install scripts must only use cached artifacts then.
+func (t *ScriptsDrivenTVM) SetOffline(offline bool) {
+	t.offline = offline
+}
+
+// SetTvmExecutable sets the tvm binary that scripts call for helpers like `tvm _download`. By default it is
+// the running executable, which is only right when that is tvm itself.
+func (t *ScriptsDrivenTVM) SetTvmExecutable(path string) {
+	t.tvmExecutable = path
+}
+
+// toolDir holds the installed versions of a tool and its `current` link
+func (t *ScriptsDrivenTVM) toolDir(tool models.Tool) string {
+	return filepath.Join(t.configService.DownloadsDir, tool.GetId())
+}
+
+// versionDir is where the scripts install a version of a tool
+func (t *ScriptsDrivenTVM) versionDir(tool models.Tool, version models.ToolVersion) string {
+	return filepath.Join(t.toolDir(tool), string(version))
+}
+
+func (t *ScriptsDrivenTVM) linker() *linker.Linker {
+	return linker.New(t.configService.SymlinksDir)
+}
+
 func (t *ScriptsDrivenTVM) CreateNewTool() models.Tool {
 	slog.Debug("Creating new ScriptsDrivenTool")
-	return &ScriptsDrivenTool{}
+	return &ScriptsDrivenTool{tvm: t}
 }
 
 func (t *ScriptsDrivenTVM) buildTemplateVars(tool models.Tool, argToFirstStep string) map[string]any {
@@ -38,6 +97,8 @@ func (t *ScriptsDrivenTVM) buildTemplateVars(tool models.Tool, argToFirstStep st
 			"DownloadsDir": t.configService.DownloadsDir,
 			"SymlinksDir":  t.configService.SymlinksDir,
 			"GitHubToken":  t.configService.GitHubToken,
+			"Offline":      t.offline,
+			"Tvm":          t.tvmExecutablePath(),
 		},
 		"Tool": tool,
 		"Arg":  argToFirstStep,
@@ -45,11 +106,97 @@ func (t *ScriptsDrivenTVM) buildTemplateVars(tool models.Tool, argToFirstStep st
 	return vars
 }
 
+// executeScript runs script steps of a tool. Scripts get TVM_CONFIG and TVM_OFFLINE in their environment, so
+// that the tvm helpers they call use this TVM's config and offline mode, and the config's mirrors in templates.
+func (t *ScriptsDrivenTVM) executeScript(steps []utils.ScriptStep, vars map[string]any) (string, error) {
+	return utils.ExecuteBashScriptStepsWith(steps, vars, utils.ScriptOptions{
+		Env:     utils.TvmEnv(t.configService.GetConfigFilePath(), t.offline, t.refetch),
+		Mirrors: t.configService.GetMirrors(),
+	})
+}
+
+// tvmExecutablePath is the path of tvm, so that scripts can call helpers like `tvm _download`
+func (t *ScriptsDrivenTVM) tvmExecutablePath() string {
+	if t.tvmExecutable != "" {
+		return t.tvmExecutable
+	}
+	if exe, err := os.Executable(); err == nil {
+		return exe
+	}
+	return "tvm"
+}
+
+// buildVersionTemplateVars adds the version and the upstream tag it was derived from, for scripts that download it
+func (t *ScriptsDrivenTVM) buildVersionTemplateVars(tool models.Tool, version models.ToolVersion) map[string]any {
+	vars := t.buildTemplateVars(tool, string(version))
+	vars["Version"] = string(version)
+	vars["Tag"] = t.TagOf(tool, version)
+	return vars
+}
+
+// tagsToVersions filters upstream tags and maps them to versions with the tool's tag_filter and tag_transform,
+// remembering the tag of each version so that downloads can use it
+func (t *ScriptsDrivenTVM) tagsToVersions(tool models.Tool, tags []string) ([]models.ToolVersion, error) {
+	var vs []models.ToolVersion
+	mapping := make(map[models.ToolVersion]string)
+	for _, tag := range tags {
+		version, ok, err := models.TagToVersion(tool, tag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to map tags of tool %s: %w", tool.GetId(), err)
+		}
+		if !ok {
+			continue
+		}
+		vs = append(vs, version)
+		if string(version) != strings.TrimSpace(tag) {
+			mapping[version] = strings.TrimSpace(tag)
+		}
+	}
+	if len(mapping) > 0 && t.remoteVersionCache != nil {
+		t.remoteVersionCache.SetTags(tool.GetId(), mapping)
+		if err := t.remoteVersionCache.Save(); err != nil {
+			slog.Warn("Failed to save tags of versions", "tool", tool.GetId(), "error", err)
+		}
+	}
+	return vs, nil
+}
+
+// TagOf returns the upstream tag a version was derived from, listing the remote versions if it isn't known yet
+func (t *ScriptsDrivenTVM) TagOf(tool models.Tool, version models.ToolVersion) string {
+	if !models.HasTagMapping(tool) || t.remoteVersionCache == nil {
+		return string(version)
+	}
+	if tag, ok := t.remoteVersionCache.GetTag(tool.GetId(), version); ok {
+		return tag
+	}
+	if _, err := t.GetAllRemoteVersions(tool); err != nil {
+		slog.Warn("Failed to list remote versions to find the tag of a version", "tool", tool.GetId(), "version", version, "error", err)
+	}
+	if tag, ok := t.remoteVersionCache.GetTag(tool.GetId(), version); ok {
+		return tag
+	}
+	// the version is its own tag
+	return string(version)
+}
+
 func (t *ScriptsDrivenTVM) GetLinkInfo(tool models.Tool) (*models.ToolLinkInfo, error) {
 
 	script := tool.(*ScriptsDrivenTool).Source.Scripts.GetLinkInfo
+	if len(script) == 0 {
+		// no script, read the links created by the native linker
+		version, linkedAt, err := t.linker().LinkedVersion(t.toolDir(tool))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get link info for tool %s: %w", tool.GetId(), err)
+		}
+		linkInfo := &models.ToolLinkInfo{Version: version}
+		if version != "" {
+			linkInfo.LinkedAt = linkedAt.Format("2006-01-02 15:04:05.000000000 -0700")
+		}
+		return linkInfo, nil
+	}
+
 	vars := t.buildTemplateVars(tool, "")
-	out, err := utils.ExecuteBashScriptSteps(script, vars)
+	out, err := t.executeScript(script, vars)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get link info for tool %s: %w", tool.GetId(), err)
 	}
@@ -62,133 +209,478 @@ func (t *ScriptsDrivenTVM) GetLinkInfo(tool models.Tool) (*models.ToolLinkInfo,
 }
 
 func (t *ScriptsDrivenTVM) GetAllLocalVersions(tool models.Tool) ([]models.ToolVersion, error) {
+	if t.installStore == nil {
+		return t.scanLocalVersions(tool)
+	}
+
+	// versions that existed before the store did are adopted once, afterwards the store is the source of truth
+	if !t.installStore.IsScanned(tool.GetId()) {
+		if err := t.adoptLocalVersions(tool); err != nil {
+			return nil, err
+		}
+	}
+
+	var vs, removed []models.ToolVersion
+	for _, record := range t.installStore.List(tool.GetId()) {
+		if _, err := os.Stat(t.versionDir(tool, record.Version)); err != nil {
+			removed = append(removed, record.Version)
+			continue
+		}
+		if record.Broken != "" {
+			slog.Debug("Skipping broken version", "tool", tool.GetId(), "version", record.Version, "reason", record.Broken)
+			continue
+		}
+		vs = append(vs, record.Version)
+	}
+	if len(removed) > 0 {
+		slog.Debug("Dropping install records of removed versions", "tool", tool.GetId(), "versions", removed)
+		if err := t.installStore.Remove(tool.GetId(), removed...); err != nil {
+			slog.Warn("Failed to drop install records", "tool", tool.GetId(), "error", err)
+		}
+	}
+
+	// newest first, like `sort -r -V` in the scripts
+	sort.SliceStable(vs, func(i, j int) bool {
+		result, err := t.CompareVersions(tool, vs[i], vs[j])
+		return err == nil && result > 0
+	})
+	return vs, nil
+}
+
+// scanLocalVersions lists the local versions with the tool's getAllLocalVersions script
+func (t *ScriptsDrivenTVM) scanLocalVersions(tool models.Tool) ([]models.ToolVersion, error) {
 	script := tool.(*ScriptsDrivenTool).Source.Scripts.GetAllLocalVersions
 	vars := t.buildTemplateVars(tool, "")
 
-	out, err := utils.ExecuteBashScriptSteps(script, vars)
+	out, err := t.executeScript(script, vars)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all local versions for tool %s: %w", tool.GetId(), err)
 	}
 	var vs []models.ToolVersion
 	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
-		vs = append(vs, models.ToolVersion(line))
+		if line = strings.TrimSpace(line); line != "" {
+			vs = append(vs, models.ToolVersion(line))
+		}
 	}
 	return vs, nil
 }
 
+// adoptLocalVersions records the versions found by scanLocalVersions in the install store
+func (t *ScriptsDrivenTVM) adoptLocalVersions(tool models.Tool) error {
+	vs, err := t.scanLocalVersions(tool)
+	if err != nil {
+		return err
+	}
+	var records []state.InstallRecord
+	for _, v := range vs {
+		dir := t.versionDir(tool, v)
+		info, err := os.Stat(dir)
+		if err != nil {
+			slog.Warn("Skipping local version without a version directory", "tool", tool.GetId(), "version", v, "dir", dir)
+			continue
+		}
+		size, err := utils.DirSize(dir)
+		if err != nil {
+			slog.Warn("Failed to compute size of local version", "tool", tool.GetId(), "version", v, "error", err)
+		}
+		records = append(records, state.InstallRecord{
+			Version:     v,
+			InstalledAt: info.ModTime(),
+			SizeBytes:   size,
+		})
+	}
+	if err := t.installStore.Adopt(tool.GetId(), records); err != nil {
+		return fmt.Errorf("failed to record local versions of tool %s: %w", tool.GetId(), err)
+	}
+	return nil
+}
+
 func (t *ScriptsDrivenTVM) GetAllRemoteVersions(tool models.Tool) ([]models.ToolVersion, error) {
+	if t.offline {
+		if t.remoteVersionCache != nil {
+			if vs, ok := t.remoteVersionCache.GetRemoteVersions(tool.GetId()); ok {
+				return vs, nil
+			}
+		}
+		return nil, fmt.Errorf("remote versions of tool %s are not cached: %w", tool.GetId(), models.ErrOffline)
+	}
+
 	script := tool.(*ScriptsDrivenTool).Source.Scripts.GetAllRemoteVersions
 	vars := t.buildTemplateVars(tool, "")
-	out, err := utils.ExecuteBashScriptSteps(script, vars)
+	out, err := t.executeScript(script, vars)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all remote versions for tool %s: %w", tool.GetId(), err)
 	}
-	var vs []models.ToolVersion
-	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
-		vs = append(vs, models.ToolVersion(line))
+	vs, err := models.ParseVersionsOutput(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all remote versions for tool %s: %w", tool.GetId(), err)
+	}
+	if models.HasTagMapping(tool) {
+		tags := make([]string, len(vs))
+		for i, tag := range vs {
+			tags[i] = string(tag)
+		}
+		if vs, err = t.tagsToVersions(tool, tags); err != nil {
+			return nil, err
+		}
+	}
+
+	// remembered for offline mode
+	if t.remoteVersionCache != nil {
+		t.remoteVersionCache.SetRemoteVersions(tool.GetId(), vs)
+		if err := t.remoteVersionCache.Save(); err != nil {
+			slog.Warn("Failed to save remote versions", "tool", tool.GetId(), "error", err)
+		}
 	}
 	return vs, nil
 }
 
 func (t *ScriptsDrivenTVM) GetLatestRemoteVersion(tool models.Tool) (models.ToolVersion, error) {
+	if t.offline {
+		return t.cachedLatestRemoteVersion(tool)
+	}
+
 	script := tool.(*ScriptsDrivenTool).Source.Scripts.GetLatestRemoteVersion
 	vars := t.buildTemplateVars(tool, "")
-	out, err := utils.ExecuteBashScriptSteps(script, vars)
-	return models.ToolVersion(strings.TrimSpace(out)), err
+	out, err := t.executeScript(script, vars)
+	if err != nil {
+		return "", err
+	}
+	latest, err := models.ParseVersionOutput(out)
+	if err != nil {
+		return "", fmt.Errorf("failed to get latest remote version for tool %s: %w", tool.GetId(), err)
+	}
+	if !models.HasTagMapping(tool) {
+		return latest, nil
+	}
+
+	vs, err := t.tagsToVersions(tool, []string{string(latest)})
+	if err != nil {
+		return "", err
+	}
+	if len(vs) > 0 {
+		return vs[0], nil
+	}
+	// upstream's latest tag belongs to another component, pick the newest of this tool's own tags
+	slog.Debug("Latest upstream tag doesn't pass the tag filter, looking through all remote versions", "tool", tool.GetId(), "tag", strings.TrimSpace(out))
+	vs, err = t.GetAllRemoteVersions(tool)
+	if err != nil {
+		return "", err
+	}
+	if len(vs) == 0 {
+		return "", fmt.Errorf("no remote tags of tool %s pass its tag_filter", tool.GetId())
+	}
+	return models.NewestVersion(t, tool, vs), nil
+}
+
+// cachedLatestRemoteVersion answers GetLatestRemoteVersion in offline mode, from the cached stable latest version
+// or else the cached remote versions
+func (t *ScriptsDrivenTVM) cachedLatestRemoteVersion(tool models.Tool) (models.ToolVersion, error) {
+	if t.remoteVersionCache != nil {
+		if version, _, ok := t.remoteVersionCache.GetCachedVersion(tool.GetId(), models.ChannelStable); ok {
+			return version, nil
+		}
+	}
+	vs, err := t.GetAllRemoteVersions(tool)
+	if err != nil {
+		return "", fmt.Errorf("latest version of tool %s is not cached: %w", tool.GetId(), models.ErrOffline)
+	}
+	return models.LatestInChannel(t, tool, vs, models.ChannelStable)
+}
+
+// GetRemoteDigest fingerprints the upstream artifact of a version with the tool's getRemoteDigest script.
+// Without a script, or offline, the digest is unknown.
+func (t *ScriptsDrivenTVM) GetRemoteDigest(tool models.Tool, version models.ToolVersion) (string, error) {
+	script := tool.(*ScriptsDrivenTool).Source.Scripts.GetRemoteDigest
+	if len(script) == 0 || t.offline {
+		return "", nil
+	}
+	vars := t.buildVersionTemplateVars(tool, version)
+	out, err := t.executeScript(script, vars)
+	if err != nil {
+		return "", fmt.Errorf("failed to get remote digest for tool %s version %s: %w", tool.GetId(), version, err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// releaseNotesMaxPages bounds how many pages of GitHub releases are read for release notes
+const releaseNotesMaxPages = 5
+
+// GetReleaseNotes reads the notes of the GitHub releases of tools with an extra.Repo, like the GitHub scripts do.
+// Release tags are mapped to versions with the tool's tag_filter and tag_transform.
+func (t *ScriptsDrivenTVM) GetReleaseNotes(tool models.Tool) ([]models.ReleaseNote, error) {
+	repo, _ := tool.(*ScriptsDrivenTool).Extra["Repo"].(string)
+	if repo == "" {
+		return nil, fmt.Errorf("tool %s has no extra.Repo, release notes are only available for GitHub releases", tool.GetId())
+	}
+	if t.offline {
+		return nil, fmt.Errorf("release notes of tool %s can't be fetched: %w", tool.GetId(), models.ErrOffline)
+	}
+	releases, err := github.NewClient(t.configService.GitHubToken, t.httpClient).ListReleases(repo, releaseNotesMaxPages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get release notes for tool %s: %w", tool.GetId(), err)
+	}
+
+	var notes []models.ReleaseNote
+	for _, release := range releases {
+		if release.Draft {
+			continue
+		}
+		version, ok, err := models.TagToVersion(tool, release.TagName)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		notes = append(notes, models.ReleaseNote{
+			Version:     version,
+			Tag:         release.TagName,
+			Title:       release.Name,
+			Body:        release.Body,
+			URL:         release.HTMLURL,
+			PublishedAt: release.PublishedAt,
+		})
+	}
+	return notes, nil
 }
 
 func (t *ScriptsDrivenTVM) CompareVersions(tool models.Tool, v1, v2 models.ToolVersion) (int, error) {
-	comparator := &models.ToolComparerWithVersionParsing{}
-	res, err := comparator.CompareVersions(tool, v1, v2)
-	return res, err
+	return models.CompareVersions(tool, v1, v2)
+}
+
+// compareVersionsWithScript orders versions with the tool's compareVersions script, for the "script" version scheme
+func compareVersionsWithScript(tool models.Tool, v1, v2 models.ToolVersion) (int, error) {
+	scriptsTool, ok := tool.(*ScriptsDrivenTool)
+	if !ok || len(scriptsTool.Source.Scripts.CompareVersions) == 0 || scriptsTool.tvm == nil {
+		return 0, fmt.Errorf("the script version scheme needs a compareVersions script on tool %s", tool.GetId())
+	}
+	t := scriptsTool.tvm
+	vars := t.buildTemplateVars(tool, "")
+	vars["Compare"] = map[string]any{
+		"V1": string(v1),
+		"V2": string(v2),
+	}
+	out, err := t.executeScript(scriptsTool.Source.Scripts.CompareVersions, vars)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compare versions %s and %s of tool %s: %w", v1, v2, tool.GetId(), err)
+	}
+	result, err := strconv.Atoi(strings.TrimSpace(out))
+	if err != nil {
+		return 0, fmt.Errorf("compareVersions script of tool %s printed %q, expected a number", tool.GetId(), strings.TrimSpace(out))
+	}
+	return result, nil
 }
 
+// InstallToolForVersion installs a version. A version that is already installed is reinstalled with
+// ReinstallToolForVersion, so that it stays as it is if the install fails.
 func (t *ScriptsDrivenTVM) InstallToolForVersion(tool models.Tool, version models.ToolVersion) error {
-	script := tool.(*ScriptsDrivenTool).Source.Scripts.FetchToolForVersion
-	vars := t.buildTemplateVars(tool, string(version))
-	out, err := utils.ExecuteBashScriptSteps(script, vars)
+	if _, err := os.Stat(t.versionDir(tool, version)); err == nil {
+		return t.ReinstallToolForVersion(tool, version)
+	}
+	sourceURL, err := t.install(tool, version)
 	if err != nil {
-		return fmt.Errorf("failed to install tool %s for version %s: %w", tool.GetId(), version, err)
+		return err
 	}
-	if out != "" {
-		return fmt.Errorf("script output: %s", out)
+	if t.installStore != nil {
+		if err := t.recordInstall(tool, version, sourceURL); err != nil {
+			slog.Warn("Failed to record install metadata", "tool", tool.GetId(), "version", version, "error", err)
+		}
 	}
 	return nil
 }
 
-func (t *ScriptsDrivenTVM) LinkTool(tool models.Tool, version models.ToolVersion) error {
-	if version == "" {
-		return fmt.Errorf("version cannot be empty")
+// ReinstallToolForVersion installs a fresh build of an installed version into a temporary downloads dir and
+// swaps it in only once the install succeeded, see models.ToolReinstaller
+func (t *ScriptsDrivenTVM) ReinstallToolForVersion(tool models.Tool, version models.ToolVersion) error {
+	// stage next to the version directories, so the new build can be moved into place with a rename
+	stagingDir, err := os.MkdirTemp(t.configService.DownloadsDir, ".reinstall-*")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	// the staged install, hooks included, isn't recorded: a failure must not mark the installed build as broken.
+	// The cached artifact of a moving tag may be the old build, so it is downloaded again.
+	stagingConfig := *t.configService
+	stagingConfig.DownloadsDir = stagingDir
+	staged := *t
+	staged.configService = &stagingConfig
+	staged.installStore = nil
+	staged.refetch = models.IsMovingTag(version) && !t.offline
+	sourceURL, err := staged.install(tool, version)
+	if err != nil {
+		return fmt.Errorf("%w; the installed build was kept", err)
 	}
 
-	toolInfo, err := t.GetLinkInfo(tool)
-	if err != nil { // log the error but continue
-		return fmt.Errorf("failed to get linked version for tool %s: %w", tool.GetId(), err)
+	if err := utils.ReplaceDir(t.versionDir(tool, version), staged.versionDir(tool, version)); err != nil {
+		return fmt.Errorf("failed to replace tool %s version %s with its new build: %w", tool.GetId(), version, err)
+	}
+	if t.installStore != nil {
+		if err := t.recordInstall(tool, version, sourceURL); err != nil {
+			slog.Warn("Failed to record install metadata", "tool", tool.GetId(), "version", version, "error", err)
+		}
 	}
-	currentVersion := toolInfo.Version
+	return nil
+}
 
-	linkScript := tool.(*ScriptsDrivenTool).Source.Scripts.LinkTool
-	vars := t.buildTemplateVars(tool, string(version))
+// install runs the install scripts and hooks of a version and returns the URL the scripts reported downloading it from.
+// If the post_install hook fails, the version is rolled back only if this install created it.
+func (t *ScriptsDrivenTVM) install(tool models.Tool, version models.ToolVersion) (string, error) {
+	_, statErr := os.Stat(t.versionDir(tool, version))
+	created := os.IsNotExist(statErr)
 
-	var successfullyLinked = false
-	defer func() {
-		if !successfullyLinked {
-			if currentVersion != "" {
-				// revert to the previous version
-				_, revertErr := utils.ExecuteBashScriptSteps(linkScript, vars)
-				if revertErr != nil {
-					fmt.Printf("failed to revert to previous version %s: %v\n", currentVersion, revertErr)
-				}
-			} else {
-				// if there was no previous version, just unlink the tool
-				if unlinkErr := t.UnlinkTool(tool); unlinkErr != nil {
-					fmt.Printf("failed to unlink tool %s: %v\n", tool.GetId(), unlinkErr)
-				}
-			}
-		}
-	}()
-	_, err = utils.ExecuteBashScriptSteps(linkScript, vars)
+	// scripts can report where they downloaded the tool from by writing the URL to this file
+	sourceURLFile, err := os.CreateTemp("", "tvm-source-url-*")
 	if err != nil {
-		return fmt.Errorf("failed to link tool %s to version %s: %w", tool.GetId(), version, err)
+		return "", fmt.Errorf("failed to create source url file: %w", err)
+	}
+	sourceURLFile.Close()
+	defer os.Remove(sourceURLFile.Name())
+
+	scriptsTool := tool.(*ScriptsDrivenTool)
+	vars := t.buildVersionTemplateVars(tool, version)
+	vars["Install"] = map[string]any{
+		"SourceURLFile": sourceURLFile.Name(),
+	}
+	if err := t.runHook(tool, "pre_install", scriptsTool.Hooks.PreInstall, vars); err != nil {
+		return "", err
+	}
+	out, err := t.executeScript(scriptsTool.Source.Scripts.FetchToolForVersion, vars)
+	if err != nil {
+		return "", fmt.Errorf("failed to install tool %s for version %s: %w", tool.GetId(), version, err)
+	}
+	if out != "" {
+		return "", fmt.Errorf("script output: %s", out)
+	}
+	if err := t.runHook(tool, "post_install", scriptsTool.Hooks.PostInstall, vars); err != nil {
+		if !created {
+			return "", fmt.Errorf("%w, the version existed before and was left in place", err)
+		}
+		return "", t.rollbackInstall(tool, version, err)
+	}
+
+	sourceURL, _ := os.ReadFile(sourceURLFile.Name())
+	return strings.TrimSpace(string(sourceURL)), nil
+}
+
+// runHook runs a lifecycle hook of a tool, if it has one
+func (t *ScriptsDrivenTVM) runHook(tool models.Tool, name string, steps []utils.ScriptStep, vars map[string]any) error {
+	if len(steps) == 0 {
+		return nil
+	}
+	slog.Debug("Running hook", "tool", tool.GetId(), "hook", name)
+	if _, err := t.executeScript(steps, vars); err != nil {
+		return fmt.Errorf("%s hook of tool %s failed: %w", name, tool.GetId(), err)
 	}
-	successfullyLinked = true
 	return nil
 }
 
-func (t *ScriptsDrivenTVM) UnlinkTool(tool models.Tool) error {
-	var successfullyUnlinked = false
+// rollbackInstall marks a version whose install failed as broken and removes it. If it can't be removed,
+// the broken record keeps it from being listed or linked.
+func (t *ScriptsDrivenTVM) rollbackInstall(tool models.Tool, version models.ToolVersion, cause error) error {
+	if t.installStore != nil {
+		record := state.InstallRecord{Version: version, InstalledAt: time.Now(), Broken: cause.Error()}
+		if err := t.installStore.Put(tool.GetId(), record); err != nil {
+			slog.Warn("Failed to mark version as broken", "tool", tool.GetId(), "version", version, "error", err)
+		}
+	}
+	if err := t.UninstallToolVersion(tool, version); err != nil {
+		return fmt.Errorf("%w (and failed to roll back the install: %v)", cause, err)
+	}
+	return fmt.Errorf("%w, the install was rolled back", cause)
+}
 
-	toolInfo, err := t.GetLinkInfo(tool)
-	if err != nil { // log the error but continue
-		fmt.Printf("failed to get linked version for tool %s: %v\n", tool.GetId(), err)
+// recordInstall stores the metadata of a freshly installed version
+func (t *ScriptsDrivenTVM) recordInstall(tool models.Tool, version models.ToolVersion, sourceURL string) error {
+	record := state.InstallRecord{
+		Version:     version,
+		InstalledAt: time.Now(),
+		SourceURL:   sourceURL,
 	}
+	digest, size, err := utils.DirDigest(t.versionDir(tool, version))
 	if err != nil {
-		return fmt.Errorf("failed to get linked version: %w", err)
+		return err
 	}
-	currentVersion := toolInfo.Version
-	if currentVersion == "" {
-		return fmt.Errorf("tool %s is not linked to any version", tool.GetId())
+	record.Digest = digest
+	record.SizeBytes = size
+	if models.IsMovingTag(version) {
+		// remembered so that a rebuilt moving tag is noticed and refetched
+		if record.RemoteDigest, err = t.GetRemoteDigest(tool, version); err != nil {
+			slog.Warn("Failed to get remote digest", "tool", tool.GetId(), "version", version, "error", err)
+		}
 	}
+	return t.installStore.Put(tool.GetId(), record)
+}
 
-	defer func() {
-		if !successfullyUnlinked {
-			// if unlinking fails, try to revert to the previous version
-			if revertErr := t.LinkTool(tool, currentVersion); revertErr != nil {
-				fmt.Printf("failed to revert to previous version %s: %v\n", currentVersion, revertErr)
-			}
+// UninstallToolVersion removes the version directory of a tool and its install record
+func (t *ScriptsDrivenTVM) UninstallToolVersion(tool models.Tool, version models.ToolVersion) error {
+	if version == "" {
+		return fmt.Errorf("version cannot be empty")
+	}
+	if err := os.RemoveAll(t.versionDir(tool, version)); err != nil {
+		return fmt.Errorf("failed to remove tool %s version %s: %w", tool.GetId(), version, err)
+	}
+	if t.installStore != nil {
+		if err := t.installStore.Remove(tool.GetId(), version); err != nil {
+			return fmt.Errorf("failed to remove install record of tool %s version %s: %w", tool.GetId(), version, err)
 		}
-	}()
+	}
+	return nil
+}
 
-	unlinkScript := tool.(*ScriptsDrivenTool).Source.Scripts.UnlinkTool
-	vars := t.buildTemplateVars(tool, string(currentVersion))
+// LinkTool links a version natively from the tool's symlinks, restoring the previous links exactly on failure
+func (t *ScriptsDrivenTVM) LinkTool(tool models.Tool, version models.ToolVersion, force bool) error {
+	if version == "" {
+		return fmt.Errorf("version cannot be empty")
+	}
+	if t.installStore != nil {
+		if record, found := t.installStore.Get(tool.GetId(), version); found && record.Broken != "" {
+			return fmt.Errorf("tool %s version %s is broken: %s", tool.GetId(), version, record.Broken)
+		}
+	}
+
+	hooks := tool.(*ScriptsDrivenTool).Hooks
+	vars := t.buildVersionTemplateVars(tool, version)
+	if err := t.runHook(tool, "pre_link", hooks.PreLink, vars); err != nil {
+		return err
+	}
+	if err := t.linker().Link(tool, t.toolDir(tool), version, force); err != nil {
+		return fmt.Errorf("failed to link tool %s to version %s: %w", tool.GetId(), version, err)
+	}
+	if err := t.runHook(tool, "post_link", hooks.PostLink, vars); err != nil {
+		slog.Warn("Linked, but the post_link hook failed", "tool", tool.GetId(), "version", version, "error", err)
+	}
+	return nil
+}
 
-	_, err = utils.ExecuteBashScriptSteps(unlinkScript, vars)
+// UnlinkTool removes the tool's current link and its binaries from the symlinks dir, restoring them on failure
+func (t *ScriptsDrivenTVM) UnlinkTool(tool models.Tool) error {
+	currentVersion, _, err := t.linker().LinkedVersion(t.toolDir(tool))
 	if err != nil {
-		return fmt.Errorf("failed to unlink tool %s: %w", tool.GetId(), err)
+		return fmt.Errorf("failed to get linked version: %w", err)
+	}
+	if currentVersion == "" {
+		return fmt.Errorf("tool %s is not linked to any version", tool.GetId())
 	}
-	successfullyUnlinked = true
 
+	hooks := tool.(*ScriptsDrivenTool).Hooks
+	vars := t.buildVersionTemplateVars(tool, currentVersion)
+	if err := t.runHook(tool, "pre_unlink", hooks.PreUnlink, vars); err != nil {
+		return err
+	}
+	if err := t.linker().Unlink(tool, t.toolDir(tool)); err != nil {
+		return fmt.Errorf("failed to unlink tool %s: %w", tool.GetId(), err)
+	}
+	if err := t.runHook(tool, "post_unlink", hooks.PostUnlink, vars); err != nil {
+		slog.Warn("Unlinked, but the post_unlink hook failed", "tool", tool.GetId(), "version", currentVersion, "error", err)
+	}
 	return nil
 }
 
 var _ models.ToolVersionManager = (*ScriptsDrivenTVM)(nil)
+var _ models.ToolUninstaller = (*ScriptsDrivenTVM)(nil)
+var _ models.ToolReinstaller = (*ScriptsDrivenTVM)(nil)
+var _ models.TagMapper = (*ScriptsDrivenTVM)(nil)
+var _ models.RemoteDigester = (*ScriptsDrivenTVM)(nil)
+var _ models.ReleaseNotesProvider = (*ScriptsDrivenTVM)(nil)
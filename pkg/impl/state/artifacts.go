@@ -0,0 +1,190 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"rayyanriaz/tool-version-manager/pkg/impl/httpclient"
+	"rayyanriaz/tool-version-manager/pkg/utils"
+)
+
+// Artifact is a downloaded file kept in the artifact cache
+type Artifact struct {
+	// Digest is the sha256 of the file as "sha256:<hex>", which is also where it is stored
+	Digest    string    `json:"digest"`
+	URL       string    `json:"url,omitempty"`
+	SizeBytes int64     `json:"size_bytes"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// ArtifactCache keeps downloaded files content-addressed under dir/sha256, with an index mapping keys like
+// `<tool>/<version>/<asset>` to them, so that versions can be reinstalled without the network.
+// It is safe for concurrent use, also across tvm processes.
+type ArtifactCache struct {
+	mu         sync.RWMutex        `json:"-"`
+	dir        string              `json:"-"`
+	httpClient *httpclient.Client  `json:"-"`
+	Artifacts  map[string]Artifact `json:"artifacts"`
+}
+
+func NewArtifactCache(dir string) *ArtifactCache {
+	return &ArtifactCache{
+		dir:        dir,
+		httpClient: httpclient.Default,
+		Artifacts:  make(map[string]Artifact),
+	}
+}
+
+// UseHTTPClient makes the cache download with client, e.g. one with the config's mirrors
+func (c *ArtifactCache) UseHTTPClient(client *httpclient.Client) {
+	c.httpClient = client
+}
+
+func (c *ArtifactCache) indexPath() string {
+	return filepath.Join(c.dir, "index.yaml")
+}
+
+// BlobPath is where the file with a digest is stored
+func (c *ArtifactCache) BlobPath(digest string) string {
+	return filepath.Join(c.dir, "sha256", strings.TrimPrefix(digest, "sha256:"))
+}
+
+// Load reads the index from disk. Returns nil error if it doesn't exist.
+func (c *ArtifactCache) Load() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.load()
+}
+
+func (c *ArtifactCache) load() error {
+	c.Artifacts = make(map[string]Artifact)
+	if _, err := os.Stat(c.indexPath()); os.IsNotExist(err) {
+		return nil
+	}
+	if err := utils.LoadFile(c.indexPath(), c); err != nil {
+		return fmt.Errorf("failed to load artifact cache index: %w", err)
+	}
+	if c.Artifacts == nil {
+		c.Artifacts = make(map[string]Artifact)
+	}
+	return nil
+}
+
+// Lookup returns the cached artifact of a key, if its file is still in the cache
+func (c *ArtifactCache) Lookup(key string) (Artifact, bool) {
+	c.mu.RLock()
+	artifact, ok := c.Artifacts[key]
+	c.mu.RUnlock()
+	if !ok {
+		return Artifact{}, false
+	}
+	if _, err := os.Stat(c.BlobPath(artifact.Digest)); err != nil {
+		return Artifact{}, false
+	}
+	return artifact, true
+}
+
+// Keys returns the keys of cached artifacts that equal prefix or start with prefix + "/", sorted
+func (c *ArtifactCache) Keys(prefix string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var keys []string
+	for key := range c.Artifacts {
+		if key == prefix || strings.HasPrefix(key, prefix+"/") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Download fetches url into the cache under key. A file with the same content is stored only once.
+// The returned artifact has the URL it was actually fetched from, which is the mirrored one behind a mirror.
+func (c *ArtifactCache) Download(key, url string) (Artifact, error) {
+	resp, err := c.httpClient.Get(url, nil)
+	if err != nil {
+		return Artifact{}, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	return c.Add(key, httpclient.RequestedURL(resp), resp.Body)
+}
+
+// Add stores the content of r in the cache under key
+func (c *ArtifactCache) Add(key, url string, r io.Reader) (Artifact, error) {
+	blobDir := filepath.Join(c.dir, "sha256")
+	if err := os.MkdirAll(blobDir, 0755); err != nil {
+		return Artifact{}, fmt.Errorf("failed to create artifact cache: %w", err)
+	}
+	tmp, err := os.CreateTemp(blobDir, ".download-*")
+	if err != nil {
+		return Artifact{}, fmt.Errorf("failed to create artifact file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return Artifact{}, fmt.Errorf("failed to store artifact %s: %w", key, err)
+	}
+
+	artifact := Artifact{
+		Digest:    "sha256:" + hex.EncodeToString(hash.Sum(nil)),
+		URL:       url,
+		SizeBytes: size,
+		FetchedAt: time.Now(),
+	}
+	if err := os.Rename(tmp.Name(), c.BlobPath(artifact.Digest)); err != nil {
+		return Artifact{}, fmt.Errorf("failed to store artifact %s: %w", key, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err = updateFile(c.indexPath(), c, c.load, func() {
+		c.Artifacts[key] = artifact
+	})
+	if err != nil {
+		return Artifact{}, err
+	}
+	return artifact, nil
+}
+
+// CopyTo writes a cached artifact to dest, failing if its content no longer matches its digest
+func (c *ArtifactCache) CopyTo(artifact Artifact, dest string) error {
+	src, err := os.Open(c.BlobPath(artifact.Digest))
+	if err != nil {
+		return fmt.Errorf("failed to open cached artifact: %w", err)
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(out, hash), src)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to copy cached artifact to %s: %w", dest, err)
+	}
+	if digest := "sha256:" + hex.EncodeToString(hash.Sum(nil)); digest != artifact.Digest {
+		os.Remove(dest)
+		return fmt.Errorf("cached artifact %s is corrupted: its digest is %s", artifact.Digest, digest)
+	}
+	return nil
+}
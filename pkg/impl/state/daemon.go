@@ -0,0 +1,89 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"rayyanriaz/tool-version-manager/pkg/models"
+	"rayyanriaz/tool-version-manager/pkg/utils"
+)
+
+// DaemonUpgrade is a tool that `tvm daemon` upgraded
+type DaemonUpgrade struct {
+	Tool string             `json:"tool"`
+	From models.ToolVersion `json:"from,omitempty"`
+	To   models.ToolVersion `json:"to"`
+}
+
+// DaemonStatus is what the last background check of `tvm daemon` did
+type DaemonStatus struct {
+	Pid       int       `json:"pid"`
+	CheckedAt time.Time `json:"checked_at"`
+	// Fetched is how many latest versions were stale and refetched, FetchErrors are the tools that failed
+	Fetched       int               `json:"fetched"`
+	FetchErrors   map[string]string `json:"fetch_errors,omitempty"`
+	Upgraded      []DaemonUpgrade   `json:"upgraded,omitempty"`
+	UpgradeErrors map[string]string `json:"upgrade_errors,omitempty"`
+	// Error is why the check couldn't run at all, e.g. an invalid config
+	Error       string    `json:"error,omitempty"`
+	NextCheckAt time.Time `json:"next_check_at,omitempty"`
+	// StoppedAt is set when the daemon shut down, after which NextCheckAt won't happen
+	StoppedAt time.Time `json:"stopped_at,omitempty"`
+}
+
+// DaemonStatusStore keeps the status of the last background check, so that other commands can show it.
+// It is safe for concurrent use.
+type DaemonStatusStore struct {
+	mu       sync.Mutex   `json:"-"`
+	filePath string       `json:"-"`
+	Status   DaemonStatus `json:"status"`
+}
+
+func NewDaemonStatusStore(filePath string) *DaemonStatusStore {
+	return &DaemonStatusStore{filePath: filePath}
+}
+
+// Load reads the status from disk. Returns nil error if file doesn't exist.
+func (s *DaemonStatusStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+func (s *DaemonStatusStore) load() error {
+	s.Status = DaemonStatus{}
+	if _, err := os.Stat(s.filePath); os.IsNotExist(err) {
+		return nil
+	}
+	if err := utils.LoadFile(s.filePath, s); err != nil {
+		return fmt.Errorf("failed to load daemon status: %w", err)
+	}
+	return nil
+}
+
+// Get returns the status of the last background check, if there was one
+func (s *DaemonStatusStore) Get() (DaemonStatus, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Status, !s.Status.CheckedAt.IsZero()
+}
+
+// Put records the status of a background check
+func (s *DaemonStatusStore) Put(status DaemonStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return updateFile(s.filePath, s, s.load, func() {
+		s.Status = status
+	})
+}
+
+// MarkStopped records that the daemon shut down
+func (s *DaemonStatusStore) MarkStopped() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return updateFile(s.filePath, s, s.load, func() {
+		s.Status.StoppedAt = time.Now()
+	})
+}
@@ -0,0 +1,96 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"rayyanriaz/tool-version-manager/pkg/models"
+	"rayyanriaz/tool-version-manager/pkg/utils"
+)
+
+type LinkAction string
+
+const (
+	LinkActionLink   LinkAction = "link"
+	LinkActionUnlink LinkAction = "unlink"
+)
+
+// LinkEvent is one link or unlink of a tool
+type LinkEvent struct {
+	Action          LinkAction         `json:"action"`
+	Version         models.ToolVersion `json:"version,omitempty"`
+	PreviousVersion models.ToolVersion `json:"previous_version,omitempty"`
+	At              time.Time          `json:"at"`
+}
+
+// LinkHistory persists the link and unlink events of all tools, oldest first. It is safe for concurrent use.
+type LinkHistory struct {
+	mu       sync.RWMutex           `json:"-"`
+	filePath string                 `json:"-"`
+	Tools    map[string][]LinkEvent `json:"tools"`
+}
+
+func NewLinkHistory(filePath string) *LinkHistory {
+	return &LinkHistory{
+		filePath: filePath,
+		Tools:    make(map[string][]LinkEvent),
+	}
+}
+
+// Load reads the history from disk. Returns nil error if file doesn't exist.
+func (h *LinkHistory) Load() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.load()
+}
+
+func (h *LinkHistory) load() error {
+	h.Tools = make(map[string][]LinkEvent)
+	if _, err := os.Stat(h.filePath); os.IsNotExist(err) {
+		return nil
+	}
+	if err := utils.LoadFile(h.filePath, h); err != nil {
+		return fmt.Errorf("failed to load link history: %w", err)
+	}
+	if h.Tools == nil {
+		h.Tools = make(map[string][]LinkEvent)
+	}
+	return nil
+}
+
+// Record appends an event to the history of a tool. A zero At is set to now.
+func (h *LinkHistory) Record(toolID string, event LinkEvent) error {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return updateFile(h.filePath, h, h.load, func() {
+		h.Tools[toolID] = append(h.Tools[toolID], event)
+	})
+}
+
+// List returns the events of a tool, oldest first
+func (h *LinkHistory) List(toolID string) []LinkEvent {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]LinkEvent(nil), h.Tools[toolID]...)
+}
+
+// RollbackTarget returns the version that was linked before the last `steps` events of a tool
+func (h *LinkHistory) RollbackTarget(toolID string, steps int) (models.ToolVersion, error) {
+	if steps < 1 {
+		return "", fmt.Errorf("steps must be at least 1")
+	}
+	events := h.List(toolID)
+	if len(events) < steps {
+		return "", fmt.Errorf("tool %s has only %d link events in its history, cannot go back %d", toolID, len(events), steps)
+	}
+	event := events[len(events)-steps]
+	if event.PreviousVersion == "" {
+		return "", fmt.Errorf("no version of tool %s was linked before the %s at %s", toolID, event.Action, event.At.Local().Format(time.DateTime))
+	}
+	return event.PreviousVersion, nil
+}
@@ -0,0 +1,177 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"rayyanriaz/tool-version-manager/pkg/models"
+	"rayyanriaz/tool-version-manager/pkg/utils"
+)
+
+// InstallRecord holds what tvm knows about one installed version of a tool
+type InstallRecord struct {
+	Version      models.ToolVersion `json:"version"`
+	InstalledAt  time.Time          `json:"installed_at"`
+	SourceURL    string             `json:"source_url,omitempty"`
+	Digest       string             `json:"digest,omitempty"`
+	SizeBytes    int64              `json:"size_bytes"`
+	TvmVersion   string             `json:"tvm_version,omitempty"`
+	ConfigDigest string             `json:"config_digest,omitempty"`
+	// RemoteDigest fingerprints the upstream artifact of moving tags like `nightly`, to notice when they are rebuilt
+	RemoteDigest string `json:"remote_digest,omitempty"`
+	// Adopted is set for versions that were found on disk instead of being installed through tvm
+	Adopted bool `json:"adopted,omitempty"`
+	// Broken is why the version can't be used, e.g. a failed post_install hook. Broken versions are not listed
+	// as installed, the record only stays if they couldn't be removed.
+	Broken string `json:"broken,omitempty"`
+	// Unverified is why the content of the version may differ from what was installed, e.g. an interrupted
+	// reinstall. It is cleared when the version is installed again.
+	Unverified string `json:"unverified,omitempty"`
+}
+
+// Installer identifies the tvm build and config that install new versions
+type Installer struct {
+	TvmVersion   string
+	ConfigDigest string
+}
+
+// ToolInstalls holds the install records of a single tool
+type ToolInstalls struct {
+	// Scanned is set once the tool's downloads directory has been scanned for pre-existing versions
+	Scanned  bool            `json:"scanned"`
+	Versions []InstallRecord `json:"versions"`
+}
+
+// InstallStore persists install records for all tools. It is safe for concurrent use,
+// and changes are merged with the file on disk so that concurrent tvm processes don't lose records.
+type InstallStore struct {
+	mu        sync.RWMutex            `json:"-"`
+	filePath  string                  `json:"-"`
+	installer Installer               `json:"-"`
+	Tools     map[string]ToolInstalls `json:"tools"`
+}
+
+// NewInstallStore creates a store backed by filePath. Every new record is stamped with the installer info.
+func NewInstallStore(filePath string, installer Installer) *InstallStore {
+	return &InstallStore{
+		filePath:  filePath,
+		installer: installer,
+		Tools:     make(map[string]ToolInstalls),
+	}
+}
+
+// Load reads the store from disk. Returns nil error if file doesn't exist.
+func (s *InstallStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+func (s *InstallStore) load() error {
+	s.Tools = make(map[string]ToolInstalls)
+	if _, err := os.Stat(s.filePath); os.IsNotExist(err) {
+		return nil
+	}
+	if err := utils.LoadFile(s.filePath, s); err != nil {
+		return fmt.Errorf("failed to load install store: %w", err)
+	}
+	if s.Tools == nil {
+		s.Tools = make(map[string]ToolInstalls)
+	}
+	return nil
+}
+
+// modify reloads the store under a file lock, applies fn and saves it back
+func (s *InstallStore) modify(fn func()) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return updateFile(s.filePath, s, s.load, fn)
+}
+
+// List returns the install records of a tool
+func (s *InstallStore) List(toolID string) []InstallRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]InstallRecord(nil), s.Tools[toolID].Versions...)
+}
+
+// Get returns the install record of a specific version of a tool
+func (s *InstallStore) Get(toolID string, version models.ToolVersion) (InstallRecord, bool) {
+	for _, record := range s.List(toolID) {
+		if record.Version == version {
+			return record, true
+		}
+	}
+	return InstallRecord{}, false
+}
+
+// IsScanned reports whether the tool's downloads directory was already scanned for pre-existing versions
+func (s *InstallStore) IsScanned(toolID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Tools[toolID].Scanned
+}
+
+// Put adds or replaces the record for record.Version. New records are stamped with the installer info.
+func (s *InstallStore) Put(toolID string, record InstallRecord) error {
+	if !record.Adopted {
+		record.TvmVersion = s.installer.TvmVersion
+		record.ConfigDigest = s.installer.ConfigDigest
+	}
+	return s.modify(func() {
+		installs := s.Tools[toolID]
+		installs.Versions = withoutVersion(installs.Versions, record.Version)
+		installs.Versions = append(installs.Versions, record)
+		s.Tools[toolID] = installs
+	})
+}
+
+// Adopt records versions found on disk that are not in the store yet, and marks the tool as scanned
+func (s *InstallStore) Adopt(toolID string, records []InstallRecord) error {
+	return s.modify(func() {
+		installs := s.Tools[toolID]
+		for _, record := range records {
+			if !containsVersion(installs.Versions, record.Version) {
+				record.Adopted = true
+				installs.Versions = append(installs.Versions, record)
+			}
+		}
+		installs.Scanned = true
+		s.Tools[toolID] = installs
+	})
+}
+
+// Remove drops the records of the given versions of a tool
+func (s *InstallStore) Remove(toolID string, versions ...models.ToolVersion) error {
+	return s.modify(func() {
+		installs, ok := s.Tools[toolID]
+		if !ok {
+			return
+		}
+		for _, version := range versions {
+			installs.Versions = withoutVersion(installs.Versions, version)
+		}
+		s.Tools[toolID] = installs
+	})
+}
+
+func withoutVersion(records []InstallRecord, version models.ToolVersion) []InstallRecord {
+	var kept []InstallRecord
+	for _, record := range records {
+		if record.Version != version {
+			kept = append(kept, record)
+		}
+	}
+	return kept
+}
+
+func containsVersion(records []InstallRecord, version models.ToolVersion) bool {
+	for _, record := range records {
+		if record.Version == version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"rayyanriaz/tool-version-manager/pkg/models"
+	"rayyanriaz/tool-version-manager/pkg/utils"
+)
+
+type JournalOperation string
+
+const (
+	JournalOperationInstall JournalOperation = "install"
+	JournalOperationLink    JournalOperation = "link"
+	JournalOperationUnlink  JournalOperation = "unlink"
+)
+
+// JournalEntry is an operation that was started but not (yet) completed
+type JournalEntry struct {
+	ID              string             `json:"id"`
+	Operation       JournalOperation   `json:"operation"`
+	ToolID          string             `json:"tool_id"`
+	Version         models.ToolVersion `json:"version,omitempty"`
+	PreviousVersion models.ToolVersion `json:"previous_version,omitempty"`
+	// PreExisting is set on installs of a version that was already installed, which recovery must not remove
+	PreExisting bool      `json:"pre_existing,omitempty"`
+	StartedAt   time.Time `json:"started_at"`
+	Pid         int       `json:"pid"`
+}
+
+// RecoveredEntry is an incomplete journal entry that was found and resolved on a later startup
+type RecoveredEntry struct {
+	Entry       JournalEntry `json:"entry"`
+	RecoveredAt time.Time    `json:"recovered_at"`
+	Resolution  string       `json:"resolution"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// Journal is a write-ahead log of install, link and unlink operations. Every operation is written to its own
+// file before anything is mutated and removed once it completes, so leftover files mark interrupted operations.
+type Journal struct {
+	mu            sync.Mutex
+	dir           string
+	recoveredPath string
+}
+
+func NewJournal(dir string, recoveredPath string) *Journal {
+	return &Journal{dir: dir, recoveredPath: recoveredPath}
+}
+
+func (j *Journal) entryPath(id string) string {
+	return filepath.Join(j.dir, id+".yaml")
+}
+
+// Begin records the intent to perform an operation. Call Complete once the operation has finished, whether
+// it succeeded or failed cleanly.
+func (j *Journal) Begin(operation JournalOperation, toolID string, version, previousVersion models.ToolVersion) (*JournalEntry, error) {
+	return j.begin(&JournalEntry{Operation: operation, ToolID: toolID, Version: version, PreviousVersion: previousVersion})
+}
+
+// BeginInstall records the intent to install a version, noting whether it was installed before
+func (j *Journal) BeginInstall(toolID string, version models.ToolVersion, preExisting bool) (*JournalEntry, error) {
+	return j.begin(&JournalEntry{Operation: JournalOperationInstall, ToolID: toolID, Version: version, PreExisting: preExisting})
+}
+
+func (j *Journal) begin(entry *JournalEntry) (*JournalEntry, error) {
+	now := time.Now()
+	entry.ID = fmt.Sprintf("%d-%d-%s", now.UnixNano(), os.Getpid(), entry.ToolID)
+	entry.StartedAt = now
+	entry.Pid = os.Getpid()
+	if err := os.MkdirAll(j.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	if err := utils.SaveFile(j.entryPath(entry.ID), entry); err != nil {
+		return nil, fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	return entry, nil
+}
+
+// Complete removes the entry from the journal
+func (j *Journal) Complete(entry *JournalEntry) error {
+	if err := os.Remove(j.entryPath(entry.ID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove journal entry %s: %w", entry.ID, err)
+	}
+	return nil
+}
+
+// Pending returns the entries that have not been completed, oldest first
+func (j *Journal) Pending() ([]JournalEntry, error) {
+	files, err := os.ReadDir(j.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal directory: %w", err)
+	}
+
+	var entries []JournalEntry
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".yaml") || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		var entry JournalEntry
+		if err := utils.LoadFile(filepath.Join(j.dir, file.Name()), &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].StartedAt.Before(entries[b].StartedAt)
+	})
+	return entries, nil
+}
+
+type recoveredLog struct {
+	Entries []RecoveredEntry `json:"entries"`
+}
+
+// MarkRecovered moves an entry out of the journal into the log of recovered entries
+func (j *Journal) MarkRecovered(entry JournalEntry, resolution string, recoveryErr error) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	recovered := RecoveredEntry{Entry: entry, RecoveredAt: time.Now(), Resolution: resolution}
+	if recoveryErr != nil {
+		recovered.Error = recoveryErr.Error()
+	}
+	var log recoveredLog
+	load := func() error {
+		log = recoveredLog{}
+		if _, err := os.Stat(j.recoveredPath); os.IsNotExist(err) {
+			return nil
+		}
+		return utils.LoadFile(j.recoveredPath, &log)
+	}
+	if err := updateFile(j.recoveredPath, &log, load, func() {
+		log.Entries = append(log.Entries, recovered)
+	}); err != nil {
+		return fmt.Errorf("failed to record recovered journal entry: %w", err)
+	}
+	return j.Complete(&entry)
+}
+
+// Recovered returns the entries that were recovered on earlier startups, oldest first
+func (j *Journal) Recovered() ([]RecoveredEntry, error) {
+	if _, err := os.Stat(j.recoveredPath); os.IsNotExist(err) {
+		return nil, nil
+	}
+	var log recoveredLog
+	if err := utils.LoadFile(j.recoveredPath, &log); err != nil {
+		return nil, err
+	}
+	return log.Entries, nil
+}
+
+// ClearRecovered empties the log of recovered entries
+func (j *Journal) ClearRecovered() error {
+	if err := os.Remove(j.recoveredPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
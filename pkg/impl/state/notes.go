@@ -0,0 +1,75 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"rayyanriaz/tool-version-manager/pkg/models"
+	"rayyanriaz/tool-version-manager/pkg/utils"
+)
+
+// ToolReleaseNotes holds the cached release notes of a single tool
+type ToolReleaseNotes struct {
+	FetchedAt time.Time            `json:"fetched_at"`
+	Notes     []models.ReleaseNote `json:"notes"`
+}
+
+// ReleaseNotesStore caches release notes of all tools, so that they can be read offline. It is safe for concurrent use.
+type ReleaseNotesStore struct {
+	mu       sync.RWMutex                `json:"-"`
+	filePath string                      `json:"-"`
+	Tools    map[string]ToolReleaseNotes `json:"tools"`
+}
+
+func NewReleaseNotesStore(filePath string) *ReleaseNotesStore {
+	return &ReleaseNotesStore{
+		filePath: filePath,
+		Tools:    make(map[string]ToolReleaseNotes),
+	}
+}
+
+// Load reads the store from disk. Returns nil error if file doesn't exist.
+func (s *ReleaseNotesStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+func (s *ReleaseNotesStore) load() error {
+	s.Tools = make(map[string]ToolReleaseNotes)
+	if _, err := os.Stat(s.filePath); os.IsNotExist(err) {
+		return nil
+	}
+	if err := utils.LoadFile(s.filePath, s); err != nil {
+		return fmt.Errorf("failed to load release notes: %w", err)
+	}
+	if s.Tools == nil {
+		s.Tools = make(map[string]ToolReleaseNotes)
+	}
+	return nil
+}
+
+// Get returns the cached release notes of a tool
+func (s *ReleaseNotesStore) Get(toolID string) (ToolReleaseNotes, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	notes, ok := s.Tools[toolID]
+	return notes, ok
+}
+
+// Put caches freshly fetched release notes of a tool. Cached notes of versions that upstream no longer lists are kept.
+func (s *ReleaseNotesStore) Put(toolID string, notes []models.ReleaseNote) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return updateFile(s.filePath, s, s.load, func() {
+		merged := append([]models.ReleaseNote(nil), notes...)
+		for _, cached := range s.Tools[toolID].Notes {
+			if !models.HasReleaseNote(notes, cached.Version) {
+				merged = append(merged, cached)
+			}
+		}
+		s.Tools[toolID] = ToolReleaseNotes{FetchedAt: time.Now(), Notes: merged}
+	})
+}
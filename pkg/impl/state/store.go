@@ -0,0 +1,21 @@
+package state
+
+import (
+	"rayyanriaz/tool-version-manager/pkg/utils"
+)
+
+// updateFile reloads a state file under a file lock, applies fn and saves data back,
+// so that concurrent tvm processes don't overwrite each other's changes
+func updateFile[T any](filePath string, data *T, load func() error, fn func()) error {
+	lock, err := utils.LockFile(filePath + ".lock")
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	if err := load(); err != nil {
+		return err
+	}
+	fn()
+	return utils.SaveFile(filePath, data)
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Channel is the release channel of a version. Each channel includes the ones before it: stable < prerelease < nightly.
+type Channel string
+
+const (
+	ChannelStable     Channel = "stable"
+	ChannelPrerelease Channel = "prerelease"
+	ChannelNightly    Channel = "nightly"
+)
+
+var channelRanks = map[Channel]int{
+	ChannelStable:     0,
+	ChannelPrerelease: 1,
+	ChannelNightly:    2,
+}
+
+// ParseChannel validates a channel name. Empty means ChannelStable.
+func ParseChannel(s string) (Channel, error) {
+	if s == "" {
+		return ChannelStable, nil
+	}
+	channel := Channel(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := channelRanks[channel]; !ok {
+		return "", fmt.Errorf("unknown channel %q. Allowed channels are: %s, %s, %s", s, ChannelStable, ChannelPrerelease, ChannelNightly)
+	}
+	return channel, nil
+}
+
+// Includes reports whether versions of the other channel can be picked when following c
+func (c Channel) Includes(other Channel) bool {
+	return channelRanks[other] <= channelRanks[c]
+}
+
+var (
+	nightlyPattern    = regexp.MustCompile(`(?i)(^|[^a-z])(nightly|canary|snapshot|dev|edge|tip|head|daily)([^a-z]|$)`)
+	prereleasePattern = regexp.MustCompile(`(?i)(^|[^a-z])(alpha|beta|rc|pre|preview|prerelease)([^a-z]|$)|\d(a|b|rc)\d`)
+)
+
+// ClassifyVersion tells the channel of a version from its name, e.g. `v1.2.0-rc1` is a prerelease
+// and `nightly-2024-05-01` is nightly. Anything else is stable.
+func ClassifyVersion(version ToolVersion) Channel {
+	switch {
+	case nightlyPattern.MatchString(string(version)):
+		return ChannelNightly
+	case prereleasePattern.MatchString(string(version)):
+		return ChannelPrerelease
+	}
+	return ChannelStable
+}
+
+// IsMovingTag reports whether a version is a tag that upstream moves to new builds, like `nightly` or `edge`.
+// Its contents can change without the version changing.
+func IsMovingTag(version ToolVersion) bool {
+	return ClassifyVersion(version) == ChannelNightly && !strings.ContainsAny(string(version), "0123456789")
+}
+
+// LatestInChannel picks the newest version that the channel includes. The nightly channel prefers nightly builds
+// when there are any.
+func LatestInChannel(comparer ToolComparer, tool Tool, versions []ToolVersion, channel Channel) (ToolVersion, error) {
+	var candidates, nightlies []ToolVersion
+	for _, v := range versions {
+		v = ToolVersion(strings.TrimSpace(string(v)))
+		if v == "" || !channel.Includes(ClassifyVersion(v)) {
+			continue
+		}
+		candidates = append(candidates, v)
+		if ClassifyVersion(v) == ChannelNightly {
+			nightlies = append(nightlies, v)
+		}
+	}
+	if len(nightlies) > 0 {
+		candidates = nightlies
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no %s versions found for tool %s", channel, tool.GetId())
+	}
+
+	return NewestVersion(comparer, tool, candidates), nil
+}
+
+// NewestVersion picks the newest of a non-empty list of versions. Versions that can't be compared keep their
+// upstream order, so the first one wins.
+func NewestVersion(comparer ToolComparer, tool Tool, versions []ToolVersion) ToolVersion {
+	newest := versions[0]
+	for _, v := range versions[1:] {
+		result, err := comparer.CompareVersions(tool, v, newest)
+		if err != nil {
+			slog.Debug("Failed to compare versions, keeping upstream order", "tool", tool.GetId(), "v1", v, "v2", newest, "error", err)
+			continue
+		}
+		if result > 0 {
+			newest = v
+		}
+	}
+	return newest
+}
+
+// LatestRemoteVersionInChannel asks the tvm for the latest remote version of a tool in a channel.
+// Upstream's own latest version is used when it is stable and the stable channel is followed,
+// otherwise all remote versions are listed and the newest one in the channel is picked.
+func LatestRemoteVersionInChannel(tvm ToolVersionManager, tool Tool, channel Channel) (ToolVersion, error) {
+	if channel == ChannelStable {
+		latest, err := tvm.GetLatestRemoteVersion(tool)
+		if err != nil {
+			return "", err
+		}
+		if latest != "" && ClassifyVersion(latest) == ChannelStable {
+			return latest, nil
+		}
+		slog.Debug("Upstream latest version is not stable, looking through all remote versions", "tool", tool.GetId(), "version", latest)
+	}
+
+	versions, err := tvm.GetAllRemoteVersions(tool)
+	if err != nil {
+		return "", err
+	}
+	return LatestInChannel(tvm, tool, versions, channel)
+}
@@ -0,0 +1,86 @@
+package models
+
+import "testing"
+
+func TestClassifyVersion(t *testing.T) {
+	tests := []struct {
+		version ToolVersion
+		want    Channel
+	}{
+		{version: "1.2.3", want: ChannelStable},
+		{version: "v1.2.0", want: ChannelStable},
+		{version: "2024.05.01", want: ChannelStable},
+		{version: "v1.2.0-rc1", want: ChannelPrerelease},
+		{version: "1.0.0-beta.2", want: ChannelPrerelease},
+		{version: "3.13.0a1", want: ChannelPrerelease},
+		{version: "2.0.0-preview", want: ChannelPrerelease},
+		{version: "nightly", want: ChannelNightly},
+		{version: "nightly-2024-05-01", want: ChannelNightly},
+		{version: "edge", want: ChannelNightly},
+		{version: "1.2.3-SNAPSHOT", want: ChannelNightly},
+		{version: "0.11.0-dev.3+abc", want: ChannelNightly},
+		// words merely containing a channel name are stable
+		{version: "develop-free-1.0", want: ChannelStable},
+		{version: "prettier-3.0.0", want: ChannelStable},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.version), func(t *testing.T) {
+			if got := ClassifyVersion(tt.version); got != tt.want {
+				t.Errorf("ClassifyVersion(%q) = %s, want %s", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsMovingTag(t *testing.T) {
+	tests := []struct {
+		version ToolVersion
+		want    bool
+	}{
+		{version: "nightly", want: true},
+		{version: "edge", want: true},
+		{version: "canary", want: true},
+		{version: "nightly-2024-05-01", want: false},
+		{version: "1.2.3", want: false},
+		{version: "v1.2.0-rc1", want: false},
+		{version: "latest", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.version), func(t *testing.T) {
+			if got := IsMovingTag(tt.version); got != tt.want {
+				t.Errorf("IsMovingTag(%q) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatestInChannel(t *testing.T) {
+	versions := []ToolVersion{"1.0.0", "1.2.0", "1.3.0-rc1", "1.1.0", "nightly-2024-05-01", "nightly-2024-05-02", " "}
+	tests := []struct {
+		name     string
+		versions []ToolVersion
+		channel  Channel
+		want     ToolVersion
+		wantErr  bool
+	}{
+		{name: "stable skips prereleases and nightlies", versions: versions, channel: ChannelStable, want: "1.2.0"},
+		{name: "prerelease includes stable", versions: versions, channel: ChannelPrerelease, want: "1.3.0-rc1"},
+		{name: "prerelease older than stable", versions: []ToolVersion{"1.0.0-rc1", "1.1.0"}, channel: ChannelPrerelease, want: "1.1.0"},
+		{name: "nightly prefers nightly builds", versions: versions, channel: ChannelNightly, want: "nightly-2024-05-02"},
+		{name: "nightly falls back to other channels", versions: []ToolVersion{"1.0.0", "1.1.0-rc1"}, channel: ChannelNightly, want: "1.1.0-rc1"},
+		{name: "nothing in channel", versions: []ToolVersion{"nightly", "2.0.0-beta"}, channel: ChannelStable, wantErr: true},
+		{name: "empty", versions: nil, channel: ChannelNightly, wantErr: true},
+	}
+	comparer := ToolComparerFunc(compareCalver)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LatestInChannel(comparer, &ToolBase{Id: "tool"}, tt.versions, tt.channel)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LatestInChannel(%v, %s) error = %v, wantErr %v", tt.versions, tt.channel, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("LatestInChannel(%v, %s) = %q, want %q", tt.versions, tt.channel, got, tt.want)
+			}
+		})
+	}
+}
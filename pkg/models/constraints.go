@@ -0,0 +1,87 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+)
+
+// UpdateKind tells how far apart two versions are
+type UpdateKind string
+
+const (
+	UpdateMajor   UpdateKind = "major"
+	UpdateMinor   UpdateKind = "minor"
+	UpdatePatch   UpdateKind = "patch"
+	UpdateUnknown UpdateKind = "unknown"
+)
+
+// parseNumericVersion parses a version after any prefix before the first digit, like `v` or `jq-`
+func parseNumericVersion(v ToolVersion) (*version.Version, error) {
+	return version.NewVersion(strings.TrimLeftFunc(string(v), func(r rune) bool { return r < '0' || r > '9' }))
+}
+
+// ParseConstraint parses a tool's constraint, e.g. `~> 1.2` or `>= 1.0, < 2.0`. It returns nil without a constraint.
+func ParseConstraint(tool Tool) (version.Constraints, error) {
+	if tool.GetConstraint() == "" {
+		return nil, nil
+	}
+	constraints, err := version.NewConstraint(tool.GetConstraint())
+	if err != nil {
+		return nil, fmt.Errorf("invalid constraint %q: %w", tool.GetConstraint(), err)
+	}
+	return constraints, nil
+}
+
+// AllowedByConstraint reports whether a version satisfies the tool's constraint.
+// Every version is allowed without a constraint, and versions that can't be parsed never are with one.
+func AllowedByConstraint(tool Tool, v ToolVersion) (bool, error) {
+	constraints, err := ParseConstraint(tool)
+	if err != nil || constraints == nil {
+		return constraints == nil, err
+	}
+	parsed, err := parseNumericVersion(v)
+	if err != nil {
+		return false, nil
+	}
+	return constraints.Check(parsed), nil
+}
+
+// NewestAllowed picks the newest version that the channel includes and the tool's constraint allows
+func NewestAllowed(comparer ToolComparer, tool Tool, versions []ToolVersion, channel Channel) (ToolVersion, error) {
+	var allowed []ToolVersion
+	for _, v := range versions {
+		ok, err := AllowedByConstraint(tool, v)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			allowed = append(allowed, v)
+		}
+	}
+	if len(allowed) == 0 {
+		return "", fmt.Errorf("no remote versions of tool %s satisfy its constraint %q", tool.GetId(), tool.GetConstraint())
+	}
+	return LatestInChannel(comparer, tool, allowed, channel)
+}
+
+// ClassifyUpdate tells whether going from one version to another is a major, minor or patch update
+func ClassifyUpdate(from, to ToolVersion) UpdateKind {
+	fromParsed, err := parseNumericVersion(from)
+	if err != nil {
+		return UpdateUnknown
+	}
+	toParsed, err := parseNumericVersion(to)
+	if err != nil {
+		return UpdateUnknown
+	}
+	fromSegments, toSegments := fromParsed.Segments(), toParsed.Segments()
+	switch {
+	case fromSegments[0] != toSegments[0]:
+		return UpdateMajor
+	case fromSegments[1] != toSegments[1]:
+		return UpdateMinor
+	}
+	return UpdatePatch
+}
@@ -0,0 +1,60 @@
+package models
+
+import "testing"
+
+func TestNewestAllowed(t *testing.T) {
+	versions := []ToolVersion{"1.1.0", "1.2.5", "1.3.0-rc1", "2.0.0", "nightly"}
+	tests := []struct {
+		name       string
+		constraint string
+		channel    Channel
+		versions   []ToolVersion
+		want       ToolVersion
+		wantErr    bool
+	}{
+		{name: "no constraint", channel: ChannelStable, versions: versions, want: "2.0.0"},
+		{name: "upper bound", constraint: "< 2.0", channel: ChannelStable, versions: versions, want: "1.2.5"},
+		{name: "pessimistic", constraint: "~> 1.1.0", channel: ChannelStable, versions: versions, want: "1.1.0"},
+		{name: "range", constraint: ">= 1.2, < 2.0", channel: ChannelStable, versions: versions, want: "1.2.5"},
+		{name: "unparsable versions are never allowed", constraint: ">= 0", channel: ChannelNightly, versions: versions, want: "2.0.0"},
+		{name: "nothing allowed", constraint: "> 3.0", channel: ChannelStable, versions: versions, wantErr: true},
+		{name: "allowed but not in channel", constraint: "< 2.0", channel: ChannelStable, versions: []ToolVersion{"1.0.0-rc1"}, wantErr: true},
+		{name: "invalid constraint", constraint: "not a constraint", channel: ChannelStable, versions: versions, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := &ToolBase{Id: "tool", Constraint: tt.constraint}
+			got, err := NewestAllowed(ToolComparerFunc(compareSemver), tool, tt.versions, tt.channel)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewestAllowed(%q) error = %v, wantErr %v", tt.constraint, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NewestAllowed(%q) = %q, want %q", tt.constraint, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyUpdate(t *testing.T) {
+	tests := []struct {
+		from, to ToolVersion
+		want     UpdateKind
+	}{
+		{from: "1.2.3", to: "2.0.0", want: UpdateMajor},
+		{from: "1.2.3", to: "1.3.0", want: UpdateMinor},
+		{from: "1.2.3", to: "1.2.4", want: UpdatePatch},
+		{from: "v1.2", to: "v1.2.1", want: UpdatePatch},
+		{from: "jq-1.6", to: "jq-1.7.1", want: UpdateMinor},
+		{from: "1", to: "2", want: UpdateMajor},
+		{from: "1.2.3", to: "1.2.3-rc1", want: UpdatePatch},
+		{from: "nightly", to: "1.0.0", want: UpdateUnknown},
+		{from: "1.0.0", to: "edge", want: UpdateUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"_to_"+string(tt.to), func(t *testing.T) {
+			if got := ClassifyUpdate(tt.from, tt.to); got != tt.want {
+				t.Errorf("ClassifyUpdate(%q, %q) = %s, want %s", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,59 +1,120 @@
 package models
 
-import "fmt"
-
-type tvmRegistry map[string]ToolVersionManager
-type configRegistry map[string]Config
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
 
+// ToolRegistry maps tool types to the managers and configs of their tools. Every tvm.Manager has its own.
+// It is safe for concurrent use.
 type ToolRegistry struct {
-	*tvmRegistry
-	*configRegistry
+	mu       sync.RWMutex
+	tvms     map[string]ToolVersionManager
+	configs  map[string]Config
+	resolver TVMResolver
+}
+
+// TVMResolver creates the manager of a tool type that isn't registered yet, e.g. from an external plugin.
+// It is consulted by GetTVM, so that types are registered as the config references them.
+type TVMResolver func(toolType string) (ToolVersionManager, error)
+
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{
+		tvms:    make(map[string]ToolVersionManager),
+		configs: make(map[string]Config),
+	}
+}
+
+// SetResolver sets the resolver for tool types that aren't registered
+func (r *ToolRegistry) SetResolver(resolver TVMResolver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resolver = resolver
 }
 
-func (r *ToolRegistry) RegisterTVM(toolType string, manager ToolVersionManager) {
-	if _, exists := (*r.tvmRegistry)[toolType]; exists {
-		panic("Tool already registered: " + toolType)
+func (r *ToolRegistry) RegisterTVM(toolType string, manager ToolVersionManager) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.tvms[toolType]; exists {
+		return fmt.Errorf("tool manager already registered for %s", toolType)
 	}
-	(*r.tvmRegistry)[toolType] = manager
+	r.tvms[toolType] = manager
+	return nil
 }
 
-func (r *ToolRegistry) RegisterConfig(toolType string, config Config) {
-	if _, exists := (*r.configRegistry)[toolType]; exists {
-		panic("Config service already registered for tool type: " + toolType)
+func (r *ToolRegistry) RegisterConfig(toolType string, config Config) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.configs[toolType]; exists {
+		return fmt.Errorf("config service already registered for tool type %s", toolType)
 	}
-	(*r.configRegistry)[toolType] = config
+	r.configs[toolType] = config
+	return nil
 }
 
 func (r *ToolRegistry) GetTVM(toolType string) (ToolVersionManager, error) {
-	if manager, exists := (*r.tvmRegistry)[toolType]; exists {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if manager, exists := r.tvms[toolType]; exists {
 		return manager, nil
 	}
-	return nil, fmt.Errorf("Tool manager not registered for %s. Allowed types are: %v", toolType, ToolRegistrar.GetRegisteredToolTypes())
+	if r.resolver != nil && toolType != "" {
+		manager, err := r.resolver(toolType)
+		if err != nil {
+			return nil, fmt.Errorf("Tool manager not registered for %s: %w", toolType, err)
+		}
+		r.tvms[toolType] = manager
+		return manager, nil
+	}
+	return nil, fmt.Errorf("Tool manager not registered for %s. Allowed types are: %v", toolType, r.toolTypes())
 }
 
 func (r *ToolRegistry) GetRegisteredToolTypes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.toolTypes()
+}
+
+func (r *ToolRegistry) toolTypes() []string {
 	types := []string{}
-	for k := range *r.tvmRegistry {
+	for k := range r.tvms {
 		types = append(types, k)
 	}
+	sort.Strings(types)
 	return types
 }
+
 func (r *ToolRegistry) GetConfig(toolType string) (Config, error) {
-	if config, exists := (*r.configRegistry)[toolType]; exists {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if config, exists := r.configs[toolType]; exists {
 		return config, nil
 	}
 	return nil, fmt.Errorf("Config service not registered for tool type: %s", toolType)
 }
 
 func (r *ToolRegistry) GetRegisteredConfigTypes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	types := []string{}
-	for k := range *r.configRegistry {
+	for k := range r.configs {
 		types = append(types, k)
 	}
+	sort.Strings(types)
 	return types
 }
 
-var ToolRegistrar = ToolRegistry{
-	tvmRegistry:    &tvmRegistry{},
-	configRegistry: &configRegistry{},
+type toolRegistryKey struct{}
+
+// WithToolRegistry returns a context that decodes tools with the registry, see ToolWrapper
+func WithToolRegistry(ctx context.Context, registry *ToolRegistry) context.Context {
+	return context.WithValue(ctx, toolRegistryKey{}, registry)
+}
+
+// ToolRegistryFromContext returns the registry set by WithToolRegistry
+func ToolRegistryFromContext(ctx context.Context) (*ToolRegistry, bool) {
+	registry, ok := ctx.Value(toolRegistryKey{}).(*ToolRegistry)
+	return registry, ok && registry != nil
 }
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// ReleaseNote holds the notes of one released version of a tool
+type ReleaseNote struct {
+	Version     ToolVersion `json:"version"`
+	Tag         string      `json:"tag,omitempty"`
+	Title       string      `json:"title,omitempty"`
+	Body        string      `json:"body,omitempty"`
+	URL         string      `json:"url,omitempty"`
+	PublishedAt time.Time   `json:"published_at,omitempty"`
+}
+
+// ReleaseNotesBetween returns the notes of the versions after from, up to and including to, newest first.
+// With an empty from, only the notes of to are returned.
+func ReleaseNotesBetween(comparer ToolComparer, tool Tool, notes []ReleaseNote, from, to ToolVersion) []ReleaseNote {
+	var between []ReleaseNote
+	for _, note := range notes {
+		if from == "" {
+			if note.Version == to {
+				between = append(between, note)
+			}
+			continue
+		}
+		afterFrom, err := comparer.CompareVersions(tool, note.Version, from)
+		if err != nil || afterFrom <= 0 {
+			continue
+		}
+		upToTo, err := comparer.CompareVersions(tool, note.Version, to)
+		if err != nil || upToTo > 0 {
+			continue
+		}
+		between = append(between, note)
+	}
+	sort.SliceStable(between, func(i, j int) bool {
+		result, err := comparer.CompareVersions(tool, between[i].Version, between[j].Version)
+		return err == nil && result > 0
+	})
+	return between
+}
+
+// HasReleaseNote reports whether notes include the notes of a version
+func HasReleaseNote(notes []ReleaseNote, version ToolVersion) bool {
+	for _, note := range notes {
+		if note.Version == version {
+			return true
+		}
+	}
+	return false
+}
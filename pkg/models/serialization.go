@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -30,13 +31,18 @@ type toolWrapperPeek struct {
 	Type string `json:"type"`
 }
 
-func (t *ToolWrapper) unmarshal(unmarshal func(any) error) error {
+// UnmarshalYAML creates the tool with the manager of its type, from the registry set with WithToolRegistry
+func (t *ToolWrapper) UnmarshalYAML(ctx context.Context, unmarshal func(any) error) error {
 	var peek toolWrapperPeek
 	if err := unmarshal(&peek); err != nil {
 		return err
 	}
 
-	tvm, err := ToolRegistrar.GetTVM(peek.Type)
+	registry, ok := ToolRegistryFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("cannot decode tool of type %s: no tool registry to create it with", peek.Type)
+	}
+	tvm, err := registry.GetTVM(peek.Type)
 	if err != nil {
 		return err
 	}
@@ -45,21 +51,14 @@ func (t *ToolWrapper) unmarshal(unmarshal func(any) error) error {
 	if err := unmarshal(tool); err != nil {
 		return err
 	}
+	if _, ok := tvm.(TagMapper); !ok && HasTagMapping(tool) {
+		return fmt.Errorf("tool %s: tools of type %s don't support tag_filter and tag_transform", tool.GetId(), peek.Type)
+	}
 
 	t.Wrapped = tool
 	return nil
 }
 
-func (t *ToolWrapper) UnmarshalJSON(data []byte) error {
-	return t.unmarshal(func(v any) error {
-		return json.Unmarshal(data, v)
-	})
-}
-
-func (t *ToolWrapper) UnmarshalYAML(unmarshal func(any) error) error {
-	return t.unmarshal(unmarshal)
-}
-
 /*
 Next, we define the unmarshal methods for UniqueToolWrappers.
 This type is a slice of ToolWrapper that ensures all tools have unique IDs.
@@ -76,6 +75,8 @@ func (tools *UniqueToolWrappers) unmarshal(unmarshal func(any) error) error {
 
 	// validate the uniqueness of IDs
 	ids := make(map[string]bool)
+	// link names (in the symlinks dir) must be unique across tools too, mapped to the tool that declares them
+	linkNames := make(map[string]string)
 	for _, tool := range *tools {
 		id := tool.Wrapped.GetId()
 		if ids[id] {
@@ -93,6 +94,33 @@ func (tools *UniqueToolWrappers) unmarshal(unmarshal func(any) error) error {
 			return fmt.Errorf("`all` is a reserved tool-type")
 		}
 		ids[id] = true
+
+		if err := validateVersionScheme(tool.Wrapped); err != nil {
+			return fmt.Errorf("tool %s: %w", id, err)
+		}
+		if _, err := ParseChannel(tool.Wrapped.GetChannel()); err != nil {
+			return fmt.Errorf("tool %s: %w", id, err)
+		}
+		if err := validateTagMapping(tool.Wrapped); err != nil {
+			return fmt.Errorf("tool %s: %w", id, err)
+		}
+		if _, err := ParseConstraint(tool.Wrapped); err != nil {
+			return fmt.Errorf("tool %s: %w", id, err)
+		}
+		if err := validateVerify(tool.Wrapped); err != nil {
+			return fmt.Errorf("tool %s: %w", id, err)
+		}
+
+		for _, symlink := range tool.Wrapped.GetSymlinks() {
+			name := symlink.LinkName()
+			if owner, exists := linkNames[name]; exists {
+				if owner == id {
+					return fmt.Errorf("tool %s declares the link name %q more than once", id, name)
+				}
+				return fmt.Errorf("link name %q is declared by both tools %s and %s", name, owner, id)
+			}
+			linkNames[name] = id
+		}
 	}
 	return nil
 
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TagTransform rewrites upstream tags matching Pattern with Replace, which can refer to capture groups
+// like `$1` or `${name}`. For example `^cli-v(.*)$` -> `$1` maps `cli-v1.2.3` to `1.2.3`.
+type TagTransform struct {
+	Pattern string `json:"pattern"`
+	Replace string `json:"replace"`
+}
+
+// HasTagMapping reports whether a tool's versions differ from its upstream tags
+func HasTagMapping(tool Tool) bool {
+	return tool.GetTagFilter() != "" || tool.GetTagTransform() != nil
+}
+
+// TagToVersion maps an upstream tag to a version of the tool. Tags not matching the tool's tag_filter are
+// not versions of the tool. Tags not matching its tag_transform pattern are used as they are.
+func TagToVersion(tool Tool, tag string) (ToolVersion, bool, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return "", false, nil
+	}
+	if filter := tool.GetTagFilter(); filter != "" {
+		re, err := compilePattern(filter)
+		if err != nil {
+			return "", false, fmt.Errorf("invalid tag_filter %q: %w", filter, err)
+		}
+		if !re.MatchString(tag) {
+			return "", false, nil
+		}
+	}
+	transform := tool.GetTagTransform()
+	if transform == nil {
+		return ToolVersion(tag), true, nil
+	}
+	re, err := compilePattern(transform.Pattern)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid tag_transform pattern %q: %w", transform.Pattern, err)
+	}
+	if !re.MatchString(tag) {
+		return ToolVersion(tag), true, nil
+	}
+	version := strings.TrimSpace(re.ReplaceAllString(tag, transform.Replace))
+	return ToolVersion(version), version != "", nil
+}
+
+// validateTagMapping checks that a tool's tag_filter and tag_transform compile
+func validateTagMapping(tool Tool) error {
+	if filter := tool.GetTagFilter(); filter != "" {
+		if _, err := compilePattern(filter); err != nil {
+			return fmt.Errorf("invalid tag_filter %q: %w", filter, err)
+		}
+	}
+	if transform := tool.GetTagTransform(); transform != nil {
+		if transform.Pattern == "" {
+			return fmt.Errorf("tag_transform needs a pattern")
+		}
+		if _, err := compilePattern(transform.Pattern); err != nil {
+			return fmt.Errorf("invalid tag_transform pattern %q: %w", transform.Pattern, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package models
+
+import "testing"
+
+func TestTagToVersion(t *testing.T) {
+	kustomize := &TagTransform{Pattern: `^kustomize/(.*)$`, Replace: "$1"}
+	tests := []struct {
+		name      string
+		filter    string
+		transform *TagTransform
+		tag       string
+		want      ToolVersion
+		wantOK    bool
+		wantErr   bool
+	}{
+		{name: "no mapping", tag: "v1.2.3", want: "v1.2.3", wantOK: true},
+		{name: "whitespace is trimmed", tag: "  v1.2.3\n", want: "v1.2.3", wantOK: true},
+		{name: "empty tag", tag: " ", wantOK: false},
+		{name: "filter matches", filter: `^kustomize/`, tag: "kustomize/v5.0.0", want: "kustomize/v5.0.0", wantOK: true},
+		{name: "filter rejects", filter: `^kustomize/`, tag: "api/v0.13.0", wantOK: false},
+		{name: "filter and transform", filter: `^kustomize/`, transform: kustomize, tag: "kustomize/v5.0.0", want: "v5.0.0", wantOK: true},
+		{name: "transform without match keeps the tag", transform: kustomize, tag: "v5.0.0", want: "v5.0.0", wantOK: true},
+		{name: "transform to empty", transform: &TagTransform{Pattern: `^skip-.*$`, Replace: ""}, tag: "skip-1", wantOK: false},
+		{name: "several groups", transform: &TagTransform{Pattern: `^release-(\d+)_(\d+)$`, Replace: "$1.$2"}, tag: "release-1_2", want: "1.2", wantOK: true},
+		{name: "invalid filter", filter: `(`, tag: "v1", wantErr: true},
+		{name: "invalid transform", transform: &TagTransform{Pattern: `(`}, tag: "v1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := &ToolBase{Id: "tool", TagFilter: tt.filter, TagTransform: tt.transform}
+			got, ok, err := TagToVersion(tool, tt.tag)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TagToVersion(%q) error = %v, wantErr %v", tt.tag, err, tt.wantErr)
+			}
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("TagToVersion(%q) = %q, %v, want %q, %v", tt.tag, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
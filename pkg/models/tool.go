@@ -1,25 +1,99 @@
 package models
 
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
 // symlinks
 type ToolSymlink struct {
 	From string `json:"from"`
 	To   string `json:"to,omitempty"`
 }
 
+// LinkName is the name of the link in the symlinks dir, defaulting to the base name of From
+func (s ToolSymlink) LinkName() string {
+	if to := strings.TrimSpace(s.To); to != "" {
+		return to
+	}
+	return filepath.Base(strings.TrimSpace(s.From))
+}
+
 // version. simple string for now
 type ToolVersion string
 
+// ParseVersionOutput reads a version printed by a script. Empty output, several words and `null` (which jq prints
+// for a field missing from e.g. an API error body) are rejected, so that they are never taken for a version.
+func ParseVersionOutput(out string) (ToolVersion, error) {
+	version := strings.TrimSpace(out)
+	switch {
+	case version == "":
+		return "", fmt.Errorf("no version was printed")
+	case version == "null":
+		return "", fmt.Errorf("%q was printed instead of a version, the upstream API probably returned an error", version)
+	case strings.ContainsAny(version, " \t\n"):
+		return "", fmt.Errorf("%q is not a version", firstLine(version))
+	}
+	return ToolVersion(version), nil
+}
+
+// ParseVersionsOutput reads the versions a script printed, one per line, rejecting them as ParseVersionOutput does
+func ParseVersionsOutput(out string) ([]ToolVersion, error) {
+	var versions []ToolVersion
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		version, err := ParseVersionOutput(line)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
 // Tool interface
 type Tool interface {
 	GetId() string
 	GetType() string
 	GetSymlinks() []ToolSymlink
+	GetVersionScheme() string
+	GetVersionPattern() string
+	GetChannel() string
+	GetTagFilter() string
+	GetTagTransform() *TagTransform
+	GetConstraint() string
+	GetEnv() map[string]string
+	GetVerify() *ToolVerify
+	GetAutoUpgrade() bool
 }
 
 type ToolBase struct {
 	Id       string        `json:"id"`
 	Type     string        `json:"type"`
 	Symlinks []ToolSymlink `json:"symlinks,omitempty"`
+	// VersionScheme selects how versions are ordered, see VersionSchemes. Empty means DefaultVersionScheme.
+	VersionScheme string `json:"version_scheme,omitempty"`
+	// VersionPattern is the regex whose capture groups order versions, for the "regex" scheme
+	VersionPattern string `json:"version_pattern,omitempty"`
+	// Channel is the release channel that upgrades follow, see Channel. Empty means ChannelStable.
+	Channel string `json:"channel,omitempty"`
+	// TagFilter is a regex that upstream tags must match to be versions of this tool, see TagToVersion
+	TagFilter string `json:"tag_filter,omitempty"`
+	// TagTransform maps upstream tags to clean versions, see TagToVersion
+	TagTransform *TagTransform `json:"tag_transform,omitempty"`
+	// Constraint limits the versions the tool should be on, e.g. `~> 1.2`, see ParseConstraint
+	Constraint string `json:"constraint,omitempty"`
+	// Env holds environment variables that `tvm init` exports, e.g. `GOROOT: "{{.CurrentDir}}"`
+	Env map[string]string `json:"env,omitempty"`
+	// Verify smoke tests linked versions, see ToolVerify
+	Verify *ToolVerify `json:"verify,omitempty"`
+	// AutoUpgrade lets `tvm daemon` upgrade the tool, within its Constraint
+	AutoUpgrade bool `json:"auto_upgrade,omitempty"`
 }
 
 func (t ToolBase) GetId() string {
@@ -34,6 +108,42 @@ func (t ToolBase) GetSymlinks() []ToolSymlink {
 	return t.Symlinks
 }
 
+func (t ToolBase) GetVersionScheme() string {
+	return t.VersionScheme
+}
+
+func (t ToolBase) GetVersionPattern() string {
+	return t.VersionPattern
+}
+
+func (t ToolBase) GetChannel() string {
+	return t.Channel
+}
+
+func (t ToolBase) GetTagFilter() string {
+	return t.TagFilter
+}
+
+func (t ToolBase) GetTagTransform() *TagTransform {
+	return t.TagTransform
+}
+
+func (t ToolBase) GetConstraint() string {
+	return t.Constraint
+}
+
+func (t ToolBase) GetEnv() map[string]string {
+	return t.Env
+}
+
+func (t ToolBase) GetVerify() *ToolVerify {
+	return t.Verify
+}
+
+func (t ToolBase) GetAutoUpgrade() bool {
+	return t.AutoUpgrade
+}
+
 type ToolWrapper struct {
 	Wrapped Tool
 }
@@ -1,5 +1,7 @@
 package models
 
+import "errors"
+
 type ToolFactory func() Tool
 
 type ToolLinkInfo struct {
@@ -14,7 +16,8 @@ type ToolDiscovery interface {
 }
 
 type ToolLinker interface {
-	LinkTool(tool Tool, version ToolVersion) error
+	// LinkTool links a version of a tool. Unless force is set, it must refuse to overwrite links it doesn't own.
+	LinkTool(tool Tool, version ToolVersion, force bool) error
 	UnlinkTool(tool Tool) error
 	GetLinkInfo(tool Tool) (*ToolLinkInfo, error)
 }
@@ -23,6 +26,37 @@ type ToolInstaller interface {
 	InstallToolForVersion(tool Tool, version ToolVersion) error
 }
 
+// ToolUninstaller is an optional capability of a ToolVersionManager to remove an installed version
+type ToolUninstaller interface {
+	UninstallToolVersion(tool Tool, version ToolVersion) error
+}
+
+// ToolReinstaller is an optional capability of a ToolVersionManager to replace an installed version with a fresh
+// build of it, e.g. a moving tag rebuilt upstream. The new build is installed aside and swapped in only once it
+// succeeded, so the installed one and its links keep working until then, and stay if the install fails.
+type ToolReinstaller interface {
+	ReinstallToolForVersion(tool Tool, version ToolVersion) error
+}
+
+// RemoteDigester is an optional capability of a ToolVersionManager to fingerprint the upstream artifact of a version,
+// so that moving tags like `nightly` can be refetched when upstream rebuilds them. An empty digest means unknown.
+type RemoteDigester interface {
+	GetRemoteDigest(tool Tool, version ToolVersion) (string, error)
+}
+
+// TagMapper is an optional capability of a ToolVersionManager to map upstream tags to versions with a tool's
+// tag_filter and tag_transform, see TagToVersion. Tools of types whose manager doesn't have it can't set them.
+type TagMapper interface {
+	// TagOf returns the upstream tag a version was derived from
+	TagOf(tool Tool, version ToolVersion) string
+}
+
+// ReleaseNotesProvider is an optional capability of a ToolVersionManager to fetch the release notes of a tool's
+// versions, see ReleaseNotesBetween
+type ReleaseNotesProvider interface {
+	GetReleaseNotes(tool Tool) ([]ReleaseNote, error)
+}
+
 type ToolComparer interface {
 	CompareVersions(tool Tool, v1 ToolVersion, v2 ToolVersion) (int, error)
 }
@@ -34,3 +68,6 @@ type ToolVersionManager interface {
 	ToolComparer
 	CreateNewTool() Tool
 }
+
+// ErrOffline is returned by backends in offline mode for anything that needs the network and isn't cached
+var ErrOffline = errors.New("not available in offline mode")
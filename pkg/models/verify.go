@@ -0,0 +1,67 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// ToolVerify is a smoke test of a linked version: Command must print output matching Pattern.
+// Both are templates with {{.Version}}; in Pattern it is regex-quoted, e.g. `^ripgrep {{.Version}}\b`.
+type ToolVerify struct {
+	Command string `json:"command"`
+	Pattern string `json:"pattern"`
+}
+
+// RenderCommand renders the command that verifies a version
+func (v ToolVerify) RenderCommand(version ToolVersion) (string, error) {
+	return renderVerifyTemplate(v.Command, string(version))
+}
+
+// ExpectedPattern compiles the pattern that the output of the command must match for a version
+func (v ToolVerify) ExpectedPattern(version ToolVersion) (*regexp.Regexp, error) {
+	pattern, err := renderVerifyTemplate(v.Pattern, regexp.QuoteMeta(string(version)))
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid verify pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+func renderVerifyTemplate(tmplStr string, version string) (string, error) {
+	tmpl, err := template.New("verify").Option("missingkey=error").Parse(tmplStr)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, map[string]any{"Version": version}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// validateVerify checks that a tool's verify command and pattern render and compile
+func validateVerify(tool Tool) error {
+	verify := tool.GetVerify()
+	if verify == nil {
+		return nil
+	}
+	if strings.TrimSpace(verify.Command) == "" {
+		return fmt.Errorf("verify needs a command")
+	}
+	if strings.TrimSpace(verify.Pattern) == "" {
+		return fmt.Errorf("verify needs a pattern")
+	}
+	if _, err := verify.RenderCommand("0.0.0"); err != nil {
+		return fmt.Errorf("invalid verify command: %w", err)
+	}
+	if _, err := verify.ExpectedPattern("0.0.0"); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-version"
+)
+
+// DefaultVersionScheme is used for tools without a version_scheme. It is ToolComparerWithVersionParsing.
+const DefaultVersionScheme = "default"
+
+// ToolComparerFunc adapts a function to a ToolComparer
+type ToolComparerFunc func(tool Tool, v1 ToolVersion, v2 ToolVersion) (int, error)
+
+func (f ToolComparerFunc) CompareVersions(tool Tool, v1 ToolVersion, v2 ToolVersion) (int, error) {
+	return f(tool, v1, v2)
+}
+
+type VersionSchemeRegistry struct {
+	mu          sync.RWMutex
+	comparators map[string]ToolComparer
+}
+
+func (r *VersionSchemeRegistry) Register(scheme string, comparator ToolComparer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.comparators[scheme]; exists {
+		panic("Version scheme already registered: " + scheme)
+	}
+	r.comparators[scheme] = comparator
+}
+
+func (r *VersionSchemeRegistry) Get(scheme string) (ToolComparer, error) {
+	if scheme == "" {
+		scheme = DefaultVersionScheme
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if comparator, exists := r.comparators[scheme]; exists {
+		return comparator, nil
+	}
+	return nil, fmt.Errorf("unknown version scheme %q. Allowed schemes are: %v", scheme, r.getRegisteredSchemes())
+}
+
+func (r *VersionSchemeRegistry) getRegisteredSchemes() []string {
+	schemes := []string{}
+	for k := range r.comparators {
+		schemes = append(schemes, k)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// VersionSchemes holds the comparators that tools can select with `version_scheme`.
+// Backends can register more, e.g. the scripts driven TVM registers "script".
+var VersionSchemes = VersionSchemeRegistry{
+	comparators: map[string]ToolComparer{
+		DefaultVersionScheme: &ToolComparerWithVersionParsing{},
+		"semver":             ToolComparerFunc(compareSemver),
+		"calver":             ToolComparerFunc(compareCalver),
+		"lexical":            ToolComparerFunc(compareLexical),
+		"regex":              ToolComparerFunc(compareRegex),
+	},
+}
+
+// CompareVersions compares two versions of a tool using the comparator of the tool's version scheme
+func CompareVersions(tool Tool, v1 ToolVersion, v2 ToolVersion) (int, error) {
+	comparator, err := VersionSchemes.Get(tool.GetVersionScheme())
+	if err != nil {
+		return 0, fmt.Errorf("tool %s: %w", tool.GetId(), err)
+	}
+	return comparator.CompareVersions(tool, v1, v2)
+}
+
+// validateVersionScheme checks that a tool's version scheme exists and its pattern compiles
+func validateVersionScheme(tool Tool) error {
+	if _, err := VersionSchemes.Get(tool.GetVersionScheme()); err != nil {
+		return err
+	}
+	if tool.GetVersionScheme() == "regex" {
+		if _, err := compileVersionPattern(tool.GetVersionPattern()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compareSemver parses both versions strictly as semantic versions, ignoring any prefix before the first digit
+// (e.g. `v1.2.3`, `jq-1.7.1`). Prereleases sort before their release.
+func compareSemver(tool Tool, v1 ToolVersion, v2 ToolVersion) (int, error) {
+	parse := func(v ToolVersion) (*version.Version, error) {
+		stripped := strings.TrimLeftFunc(string(v), func(r rune) bool { return r < '0' || r > '9' })
+		parsed, err := version.NewSemver(stripped)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a semantic version: %w", v, err)
+		}
+		return parsed, nil
+	}
+	p1, err := parse(v1)
+	if err != nil {
+		return 0, err
+	}
+	p2, err := parse(v2)
+	if err != nil {
+		return 0, err
+	}
+	return p1.Compare(p2), nil
+}
+
+var digitRuns = regexp.MustCompile(`[0-9]+`)
+
+// compareCalver orders versions by their numeric components, whatever separates them
+// (e.g. `2024.05.01`, `release-2024-05-01`, `24.04.1`)
+func compareCalver(tool Tool, v1 ToolVersion, v2 ToolVersion) (int, error) {
+	c1 := digitRuns.FindAllString(string(v1), -1)
+	c2 := digitRuns.FindAllString(string(v2), -1)
+	if len(c1) == 0 || len(c2) == 0 {
+		return 0, fmt.Errorf("cannot compare %q and %q as calendar versions, both need numeric components", v1, v2)
+	}
+	return compareComponents(c1, c2), nil
+}
+
+func compareLexical(tool Tool, v1 ToolVersion, v2 ToolVersion) (int, error) {
+	return strings.Compare(string(v1), string(v2)), nil
+}
+
+var compiledPatterns sync.Map // pattern -> *regexp.Regexp
+
+// compilePattern compiles a regex from the config once and reuses it afterwards
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := compiledPatterns.Load(pattern); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	compiledPatterns.Store(pattern, re)
+	return re, nil
+}
+
+func compileVersionPattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("the regex version scheme needs a version_pattern")
+	}
+	re, err := compilePattern(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version_pattern %q: %w", pattern, err)
+	}
+	if re.NumSubexp() == 0 {
+		return nil, fmt.Errorf("version_pattern %q has no capture groups to order versions by", pattern)
+	}
+	return re, nil
+}
+
+// compareRegex orders versions by the capture groups of the tool's version_pattern, in order.
+// Groups are compared numerically when both are numbers, as strings otherwise.
+func compareRegex(tool Tool, v1 ToolVersion, v2 ToolVersion) (int, error) {
+	re, err := compileVersionPattern(tool.GetVersionPattern())
+	if err != nil {
+		return 0, err
+	}
+	m1 := re.FindStringSubmatch(string(v1))
+	if m1 == nil {
+		return 0, fmt.Errorf("version %q doesn't match version_pattern %q", v1, re)
+	}
+	m2 := re.FindStringSubmatch(string(v2))
+	if m2 == nil {
+		return 0, fmt.Errorf("version %q doesn't match version_pattern %q", v2, re)
+	}
+	return compareComponents(m1[1:], m2[1:]), nil
+}
+
+// compareComponents compares two lists of components pairwise, numerically where possible.
+// If one list is a prefix of the other, the shorter one is smaller.
+func compareComponents(c1, c2 []string) int {
+	for i := 0; i < len(c1) && i < len(c2); i++ {
+		n1, e1 := strconv.ParseUint(c1[i], 10, 64)
+		n2, e2 := strconv.ParseUint(c2[i], 10, 64)
+		var result int
+		if e1 == nil && e2 == nil {
+			switch {
+			case n1 < n2:
+				result = -1
+			case n1 > n2:
+				result = 1
+			}
+		} else {
+			result = strings.Compare(c1[i], c2[i])
+		}
+		if result != 0 {
+			return result
+		}
+	}
+	switch {
+	case len(c1) < len(c2):
+		return -1
+	case len(c1) > len(c2):
+		return 1
+	}
+	return 0
+}
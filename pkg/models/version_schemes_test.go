@@ -0,0 +1,113 @@
+package models
+
+import "testing"
+
+func TestCompareSemver(t *testing.T) {
+	tests := []struct {
+		v1, v2  ToolVersion
+		want    int
+		wantErr bool
+	}{
+		{v1: "1.2.3", v2: "1.2.3", want: 0},
+		{v1: "1.2.3", v2: "1.10.0", want: -1},
+		{v1: "2.0.0", v2: "1.99.99", want: 1},
+		{v1: "v1.2.3", v2: "1.2.3", want: 0},
+		{v1: "jq-1.7.1", v2: "jq-1.7", want: 1},
+		{v1: "1.0.0-rc.1", v2: "1.0.0", want: -1},
+		{v1: "1.0.0-alpha", v2: "1.0.0-beta", want: -1},
+		{v1: "nightly", v2: "1.0.0", wantErr: true},
+		{v1: "1.0.0", v2: "1.0.0.0.0.0.x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.v1)+"_vs_"+string(tt.v2), func(t *testing.T) {
+			got, err := compareSemver(&ToolBase{}, tt.v1, tt.v2)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("compareSemver(%q, %q) error = %v, wantErr %v", tt.v1, tt.v2, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("compareSemver(%q, %q) = %d, want %d", tt.v1, tt.v2, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareCalver(t *testing.T) {
+	tests := []struct {
+		v1, v2  ToolVersion
+		want    int
+		wantErr bool
+	}{
+		{v1: "2024.05.01", v2: "2024.05.01", want: 0},
+		{v1: "2024.05.01", v2: "2024.10.01", want: -1},
+		{v1: "release-2024-05-02", v2: "release-2024-05-01", want: 1},
+		{v1: "24.04.1", v2: "24.04", want: 1},
+		{v1: "2024.5.1", v2: "2024.05.01", want: 0},
+		{v1: "nightly", v2: "2024.05.01", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.v1)+"_vs_"+string(tt.v2), func(t *testing.T) {
+			got, err := compareCalver(&ToolBase{}, tt.v1, tt.v2)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("compareCalver(%q, %q) error = %v, wantErr %v", tt.v1, tt.v2, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("compareCalver(%q, %q) = %d, want %d", tt.v1, tt.v2, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareRegex(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		v1, v2  ToolVersion
+		want    int
+		wantErr bool
+	}{
+		{name: "numeric groups", pattern: `release-(\d+)-(\d+)-(\d+)`, v1: "release-1-2-10", v2: "release-1-2-9", want: 1},
+		{name: "equal", pattern: `release-(\d+)-(\d+)`, v1: "release-3-1", v2: "release-3-1", want: 0},
+		{name: "string group", pattern: `(\d+)-(\w+)`, v1: "1-alpha", v2: "1-beta", want: -1},
+		{name: "first group wins", pattern: `(\d+)\.(\d+)`, v1: "2.0", v2: "1.99", want: 1},
+		{name: "no match", pattern: `release-(\d+)`, v1: "v1", v2: "release-1", wantErr: true},
+		{name: "no pattern", pattern: "", v1: "1", v2: "2", wantErr: true},
+		{name: "no capture groups", pattern: `\d+`, v1: "1", v2: "2", wantErr: true},
+		{name: "invalid pattern", pattern: `(\d+`, v1: "1", v2: "2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := &ToolBase{VersionScheme: "regex", VersionPattern: tt.pattern}
+			got, err := compareRegex(tool, tt.v1, tt.v2)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("compareRegex(%q, %q) error = %v, wantErr %v", tt.v1, tt.v2, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("compareRegex(%q, %q) = %d, want %d", tt.v1, tt.v2, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareComponents(t *testing.T) {
+	tests := []struct {
+		name   string
+		c1, c2 []string
+		want   int
+	}{
+		{name: "equal", c1: []string{"1", "2"}, c2: []string{"1", "2"}, want: 0},
+		{name: "numeric, not lexical", c1: []string{"9"}, c2: []string{"10"}, want: -1},
+		{name: "leading zeros", c1: []string{"01"}, c2: []string{"1"}, want: 0},
+		{name: "strings", c1: []string{"b"}, c2: []string{"a"}, want: 1},
+		{name: "number and string compare as strings", c1: []string{"1"}, c2: []string{"a"}, want: -1},
+		{name: "prefix is smaller", c1: []string{"1", "2"}, c2: []string{"1", "2", "0"}, want: -1},
+		{name: "longer is bigger", c1: []string{"1", "2", "0"}, c2: []string{"1", "2"}, want: 1},
+		{name: "both empty", c1: nil, c2: nil, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareComponents(tt.c1, tt.c2); got != tt.want {
+				t.Errorf("compareComponents(%v, %v) = %d, want %d", tt.c1, tt.c2, got, tt.want)
+			}
+		})
+	}
+}
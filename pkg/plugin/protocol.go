@@ -0,0 +1,98 @@
+// Package plugin defines the protocol between tvm and external backends. A backend for tool type <type> is an
+// executable named tvm-backend-<type>, found in the plugins dir or on PATH. tvm runs it once per operation,
+// writes a Request as JSON to its stdin and reads a Response as JSON from its stdout. Anything on stderr is
+// shown to the user when the operation fails.
+package plugin
+
+import (
+	"encoding/json"
+
+	"rayyanriaz/tool-version-manager/pkg/models"
+)
+
+// ProtocolVersion is the version of the protocol, bumped on incompatible changes
+const ProtocolVersion = 1
+
+// ExecutablePrefix is the prefix of plugin executables, followed by the tool type
+const ExecutablePrefix = "tvm-backend-"
+
+// Operations of the protocol
+const (
+	// OpDescribe reports the protocol version and the operations the plugin implements
+	OpDescribe = "describe"
+	// OpListLocalVersions lists the installed versions, newest first
+	OpListLocalVersions = "list_local_versions"
+	// OpListRemoteVersions lists the versions available upstream, newest first
+	OpListRemoteVersions = "list_remote_versions"
+	// OpLatestRemoteVersion reports the latest version available upstream
+	OpLatestRemoteVersion = "latest_remote_version"
+	// OpInstall installs Request.Version into Context.VersionDir
+	OpInstall = "install"
+	// OpUninstall removes Request.Version
+	OpUninstall = "uninstall"
+	// OpLink links Request.Version, refusing to overwrite links it doesn't own unless Request.Force is set
+	OpLink = "link"
+	// OpUnlink removes the links of the tool
+	OpUnlink = "unlink"
+	// OpLinkInfo reports the linked version
+	OpLinkInfo = "link_info"
+	// OpCompare orders Request.V1 and Request.V2
+	OpCompare = "compare"
+)
+
+// RequiredOperations must be implemented by every plugin. For the others, tvm falls back to its native
+// implementation: the install store and version directories for local versions, removing the version directory
+// for uninstall, the native linker for link, unlink and link_info, and the tool's version_scheme for compare.
+var RequiredOperations = []string{OpDescribe, OpListRemoteVersions, OpLatestRemoteVersion, OpInstall}
+
+// ErrorCodeOffline marks an error of an operation that needs the network in offline mode
+const ErrorCodeOffline = "offline"
+
+// Request is what tvm sends to a plugin
+type Request struct {
+	ProtocolVersion int    `json:"protocol_version"`
+	Operation       string `json:"operation"`
+	// Tool is the tool's config entry, including fields only the plugin knows, like `extra`
+	Tool    json.RawMessage    `json:"tool,omitempty"`
+	Version models.ToolVersion `json:"version,omitempty"`
+	Force   bool               `json:"force,omitempty"`
+	V1      models.ToolVersion `json:"v1,omitempty"`
+	V2      models.ToolVersion `json:"v2,omitempty"`
+	Context Context            `json:"context"`
+}
+
+// Context tells a plugin where things go
+type Context struct {
+	DownloadsDir string `json:"downloads_dir"`
+	SymlinksDir  string `json:"symlinks_dir"`
+	// ToolDir is <downloads_dir>/<tool>, where tvm expects the tool's versions
+	ToolDir string `json:"tool_dir,omitempty"`
+	// VersionDir is <tool_dir>/<version> for operations on a version
+	VersionDir string `json:"version_dir,omitempty"`
+	// Offline is set when the plugin must not use the network, see ErrorCodeOffline
+	Offline bool `json:"offline,omitempty"`
+	// Refetch is set when an installed version is reinstalled because upstream rebuilt it, so cached downloads
+	// of it must not be used. `tvm _download` already skips its cache then.
+	Refetch bool `json:"refetch,omitempty"`
+}
+
+// Response is what a plugin answers. A non-empty Error fails the operation, as does a non-zero exit status.
+type Response struct {
+	Error     string `json:"error,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"`
+
+	// describe
+	ProtocolVersion int      `json:"protocol_version,omitempty"`
+	Operations      []string `json:"operations,omitempty"`
+
+	// list_local_versions, list_remote_versions
+	Versions []models.ToolVersion `json:"versions,omitempty"`
+	// latest_remote_version
+	Version models.ToolVersion `json:"version,omitempty"`
+	// install: where the version was downloaded from, recorded in the install metadata
+	SourceURL string `json:"source_url,omitempty"`
+	// link_info
+	LinkInfo *models.ToolLinkInfo `json:"link_info,omitempty"`
+	// compare: negative, zero or positive as V1 is older than, equal to or newer than V2
+	Comparison int `json:"comparison,omitempty"`
+}
@@ -0,0 +1,53 @@
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"rayyanriaz/tool-version-manager/pkg/models"
+)
+
+// Handler implements the operations of a plugin. It returns the response for a request, or an error that is
+// sent as Response.Error. Errors wrapping models.ErrOffline are sent with ErrorCodeOffline.
+type Handler func(req Request) (Response, error)
+
+// ErrUnsupported is returned for operations a plugin doesn't implement
+var ErrUnsupported = errors.New("unsupported operation")
+
+// Serve answers the request on stdin with handler, for plugins written in Go. Describe is answered from
+// operations, which must list the operations handler implements.
+func Serve(operations []string, handler Handler) {
+	resp := serve(os.Stdin, operations, handler)
+	if err := json.NewEncoder(os.Stdout).Encode(resp); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func serve(r io.Reader, operations []string, handler Handler) Response {
+	var req Request
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return Response{Error: fmt.Sprintf("invalid request: %v", err)}
+	}
+	if req.ProtocolVersion != ProtocolVersion {
+		return Response{Error: fmt.Sprintf("unsupported protocol version %d, this plugin speaks %d", req.ProtocolVersion, ProtocolVersion)}
+	}
+	if req.Operation == OpDescribe {
+		return Response{ProtocolVersion: ProtocolVersion, Operations: append([]string{OpDescribe}, operations...)}
+	}
+	if !slices.Contains(operations, req.Operation) {
+		return Response{Error: fmt.Sprintf("%s: %q", ErrUnsupported, req.Operation)}
+	}
+	resp, err := handler(req)
+	if err != nil {
+		resp = Response{Error: err.Error()}
+		if errors.Is(err, models.ErrOffline) {
+			resp.ErrorCode = ErrorCodeOffline
+		}
+	}
+	return resp
+}
@@ -0,0 +1,203 @@
+package tvm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"time"
+
+	"rayyanriaz/tool-version-manager/pkg/impl/state"
+	"rayyanriaz/tool-version-manager/pkg/models"
+)
+
+// DaemonOptions configure RunDaemon
+type DaemonOptions struct {
+	// Once runs a single check and returns, e.g. to run it from cron instead
+	Once bool
+}
+
+// RunDaemon checks for updates in the background until ctx is done: it refreshes the latest versions whose cache
+// entry is stale, and upgrades the tools marked auto_upgrade that are behind, within their constraints.
+// Checks run every daemon_interval plus up to daemon_jitter, and each one is recorded in the daemon status file.
+//
+// Every check loads the config and the state again, so edits and changes by other tvm processes are picked up.
+// When ctx is done, operations that already started are finished, but no new ones are started.
+func RunDaemon(ctx context.Context, opts Options, daemonOpts DaemonOptions) error {
+	if opts.Offline {
+		return fmt.Errorf("cannot check for updates in the background: %w", models.ErrOffline)
+	}
+
+	var last *Manager
+	first := true
+	for {
+		m, err := New(opts)
+		if err != nil {
+			if last == nil {
+				return err
+			}
+			// keep running on the previous schedule, the config may be fixed by the next check
+			slog.Error("Background check failed", "error", err)
+		} else {
+			last = m
+		}
+
+		var status state.DaemonStatus
+		if err != nil {
+			status = state.DaemonStatus{Pid: os.Getpid(), CheckedAt: time.Now(), Error: err.Error()}
+		} else {
+			for _, r := range m.Recovered() {
+				slog.Warn("Recovered interrupted operation", "operation", r.Entry.Operation, "tool", r.Entry.ToolID, "resolution", r.Resolution, "error", r.Err)
+			}
+			status = m.backgroundCheck(ctx, first)
+			first = false
+		}
+
+		wait := last.config.GetDaemonInterval() + jitter(last.config.GetDaemonJitter())
+		done := daemonOpts.Once || ctx.Err() != nil
+		if !done {
+			status.NextCheckAt = time.Now().Add(wait)
+		}
+		if err := last.daemonStatus.Put(status); err != nil {
+			slog.Warn("Failed to write daemon status", "error", err)
+		}
+		if done {
+			break
+		}
+
+		slog.Info("Next background check", "at", status.NextCheckAt.Format(time.DateTime))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+			continue
+		}
+		break
+	}
+
+	if err := last.daemonStatus.MarkStopped(); err != nil {
+		slog.Warn("Failed to write daemon status", "error", err)
+	}
+	return nil
+}
+
+// jitter returns a random duration in [0, max)
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return rand.N(max)
+}
+
+// backgroundCheck refreshes the stale latest versions, then upgrades the auto_upgrade tools that are behind.
+// Unless all is set, only the tools whose latest version was just refetched are upgraded, so that a tool is tried
+// once per remote_versions_cache_max_age and a failing upgrade isn't retried at every check.
+func (m *Manager) backgroundCheck(ctx context.Context, all bool) state.DaemonStatus {
+	status := state.DaemonStatus{Pid: os.Getpid(), CheckedAt: time.Now()}
+	slog.Info("Checking for updates")
+
+	fetched, err := m.Fetch(ctx, FetchOptions{StaleOnly: true})
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	refetched := make(map[string]bool)
+	for _, result := range fetched {
+		if errors.Is(result.Err, context.Canceled) {
+			continue
+		}
+		status.Fetched++
+		refetched[result.ToolID] = result.Err == nil
+		if result.Err != nil {
+			if status.FetchErrors == nil {
+				status.FetchErrors = make(map[string]string)
+			}
+			status.FetchErrors[result.ToolID] = result.Err.Error()
+			slog.Warn("Failed to fetch the latest version", "tool", result.ToolID, "error", result.Err)
+		}
+	}
+
+	candidates, err := m.autoUpgradeCandidates(ctx)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	var toolIDs []string
+	for _, toolID := range candidates {
+		if all || refetched[toolID] {
+			toolIDs = append(toolIDs, toolID)
+		}
+	}
+	if len(toolIDs) == 0 || ctx.Err() != nil {
+		slog.Info("Background check done", "fetched", status.Fetched, "fetch_errors", len(status.FetchErrors))
+		return status
+	}
+
+	slog.Info("Auto-upgrading", "tools", toolIDs)
+	results, _ := m.Upgrade(ctx, UpgradeOptions{ToolIDs: toolIDs, WithinConstraints: true})
+	for _, result := range results {
+		switch result.Status {
+		case UpgradeUpgraded:
+			status.Upgraded = append(status.Upgraded, state.DaemonUpgrade{Tool: result.ToolID, From: result.From, To: result.To})
+		case UpgradeFailed:
+			if errors.Is(result.Err, context.Canceled) {
+				continue
+			}
+			if status.UpgradeErrors == nil {
+				status.UpgradeErrors = make(map[string]string)
+			}
+			status.UpgradeErrors[result.ToolID] = result.Err.Error()
+			slog.Warn("Failed to auto-upgrade", "tool", result.ToolID, "error", result.Err)
+		}
+	}
+	slog.Info("Background check done", "fetched", status.Fetched, "fetch_errors", len(status.FetchErrors),
+		"upgraded", len(status.Upgraded), "upgrade_errors", len(status.UpgradeErrors))
+	return status
+}
+
+// autoUpgradeCandidates returns the linked tools marked auto_upgrade that can be upgraded: their cached latest
+// version is newer than the linked one and, for tools with a constraint, so is the newest version it allows.
+// Tools that are up to date by the cache cost no requests, only constrained ones behind it list remote versions.
+func (m *Manager) autoUpgradeCandidates(ctx context.Context) ([]string, error) {
+	tools, err := m.Tools()
+	if err != nil {
+		return nil, err
+	}
+	var autoIDs []string
+	for _, tool := range tools {
+		if tool.Wrapped.GetAutoUpgrade() {
+			autoIDs = append(autoIDs, tool.Wrapped.GetId())
+		}
+	}
+	if len(autoIDs) == 0 {
+		return nil, nil
+	}
+
+	statuses, err := m.Status(ctx, StatusOptions{ToolIDs: autoIDs, Refresh: RefreshNone})
+	if err != nil {
+		return nil, err
+	}
+	var candidates []string
+	for _, status := range statuses {
+		if !status.UpdateAvailable {
+			continue
+		}
+		tool, tvm, err := m.ToolWithTVM(status.ID)
+		if err != nil {
+			return nil, err
+		}
+		// the latest version may be beyond the constraint, while the linked one is the newest it allows
+		target, err := m.TargetVersion(tool, tvm, status.LatestVersion)
+		if err != nil {
+			slog.Warn("Failed to resolve the version the constraint allows", "tool", status.ID, "error", err)
+			continue
+		}
+		if UpdateAvailable(tool, tvm, status.LinkedVersion, target) {
+			candidates = append(candidates, status.ID)
+		}
+	}
+	return candidates, nil
+}
@@ -0,0 +1,311 @@
+// Package tvm is tvm as a library. A Manager does for the tools of one config file what the tvm command does:
+// it installs, links, upgrades and reports on them, and keeps the same caches and state on disk, so that a
+// Manager and the tvm command can be used side by side.
+//
+//	m, err := tvm.New(tvm.Options{ConfigPath: "tools.yaml"})
+//	...
+//	results, err := m.Upgrade(ctx, tvm.UpgradeOptions{ToolIDs: []string{"rg"}})
+package tvm
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sync"
+
+	"rayyanriaz/tool-version-manager/pkg/impl/config"
+	"rayyanriaz/tool-version-manager/pkg/impl/httpclient"
+	plugintvm "rayyanriaz/tool-version-manager/pkg/impl/plugin_tvm"
+	scriptdriventvm "rayyanriaz/tool-version-manager/pkg/impl/scriptdriven_tvm"
+	"rayyanriaz/tool-version-manager/pkg/impl/state"
+	"rayyanriaz/tool-version-manager/pkg/models"
+	"rayyanriaz/tool-version-manager/pkg/utils"
+)
+
+// Options configure a Manager
+type Options struct {
+	// ConfigPath is the config file, it is required
+	ConfigPath string
+	// Offline uses only cached versions and artifacts, never the network. Scripts and plugins see TVM_OFFLINE=1.
+	Offline bool
+	// Channel overrides the release channel of every tool when set
+	Channel models.Channel
+	// TvmVersion is the version recorded in install metadata, e.g. of the program embedding the library
+	TvmVersion string
+	// TvmExecutable is the tvm binary that scripts call for helpers like `tvm _download`.
+	// By default it is the running executable, which is only right when that is tvm itself.
+	TvmExecutable string
+	// Progress receives what long operations are doing, e.g. "Upgrading rg to version 14.1.0...".
+	// By default it is discarded.
+	Progress io.Writer
+}
+
+// Manager manages the tools of one config file. It holds its own tool registry, HTTP client and mirrors, so
+// several Managers can be used in one process.
+// Its methods are safe for concurrent use; changes to a tool are serialized with a lock file per tool,
+// also across processes.
+type Manager struct {
+	opts     Options
+	registry *models.ToolRegistry
+	config   *config.LocalFileConfig
+
+	remoteVersionCache *config.RemoteVersionsCache
+	installStore       *state.InstallStore
+	linkHistory        *state.LinkHistory
+	journal            *state.Journal
+	releaseNotesStore  *state.ReleaseNotesStore
+	artifactCache      *state.ArtifactCache
+	daemonStatus       *state.DaemonStatusStore
+	httpClient         *httpclient.Client
+
+	recovered  []Recovery
+	progressMu sync.Mutex
+}
+
+// New loads the config and the state next to it, and recovers operations that a killed tvm process left
+// halfway, see Recovered
+func New(opts Options) (*Manager, error) {
+	if opts.ConfigPath == "" {
+		return nil, fmt.Errorf("no config file given")
+	}
+	if opts.Channel != "" {
+		if _, err := models.ParseChannel(string(opts.Channel)); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Progress == nil {
+		opts.Progress = io.Discard
+	}
+	m := &Manager{
+		opts:     opts,
+		registry: models.NewToolRegistry(),
+	}
+
+	cfg := config.NewLocalFileConfig(opts.ConfigPath, m.registry)
+	scriptsDrivenTVM := scriptdriventvm.NewScriptsDrivenTVM(cfg)
+	scriptsDrivenTVM.SetOffline(opts.Offline)
+	scriptsDrivenTVM.SetTvmExecutable(opts.TvmExecutable)
+	if err := m.registry.RegisterConfig("scripts_driven", cfg); err != nil {
+		return nil, err
+	}
+	if err := m.registry.RegisterTVM("scripts_driven", scriptsDrivenTVM); err != nil {
+		return nil, err
+	}
+	// any other tool type in the config is served by an external tvm-backend-<type> plugin
+	m.registry.SetResolver(func(toolType string) (models.ToolVersionManager, error) {
+		return m.newPluginTVM(toolType, cfg), nil
+	})
+	if err := cfg.Load(); err != nil {
+		return nil, fmt.Errorf("failed to create config service for script_driven: %w", err)
+	}
+	m.config = cfg
+	// tvm's own requests go through the config's mirrors
+	m.httpClient = httpclient.NewClient()
+	m.httpClient.Mirrors = cfg.GetMirrors()
+	scriptsDrivenTVM.UseHTTPClient(m.httpClient)
+
+	// Initialize the install metadata store, stamping new records with this build and config
+	configDigest, err := utils.FileDigest(opts.ConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash config file: %w", err)
+	}
+	m.installStore = state.NewInstallStore(filepath.Join(cfg.StateDir, "installs.yaml"), state.Installer{
+		TvmVersion:   opts.TvmVersion,
+		ConfigDigest: configDigest,
+	})
+	if err := m.installStore.Load(); err != nil {
+		return nil, fmt.Errorf("failed to load install store: %w", err)
+	}
+	scriptsDrivenTVM.UseInstallStore(m.installStore)
+	// the plugins of the tool types in the config were created while it loaded, before the install store
+	for _, toolType := range m.registry.GetRegisteredToolTypes() {
+		tvm, _ := m.registry.GetTVM(toolType)
+		if pluginTVM, ok := tvm.(*plugintvm.PluginTVM); ok {
+			m.finishPluginTVM(pluginTVM, toolType)
+		}
+	}
+
+	m.linkHistory = state.NewLinkHistory(filepath.Join(cfg.StateDir, "link_history.yaml"))
+	if err := m.linkHistory.Load(); err != nil {
+		return nil, fmt.Errorf("failed to load link history: %w", err)
+	}
+
+	m.releaseNotesStore = state.NewReleaseNotesStore(filepath.Join(cfg.StateDir, "release_notes.yaml"))
+	if err := m.releaseNotesStore.Load(); err != nil {
+		return nil, fmt.Errorf("failed to load release notes: %w", err)
+	}
+
+	m.artifactCache = state.NewArtifactCache(cfg.ArtifactsDir)
+	m.artifactCache.UseHTTPClient(m.httpClient)
+	if err := m.artifactCache.Load(); err != nil {
+		return nil, fmt.Errorf("failed to load artifact cache: %w", err)
+	}
+
+	m.daemonStatus = state.NewDaemonStatusStore(filepath.Join(cfg.StateDir, "daemon_status.yaml"))
+	if err := m.daemonStatus.Load(); err != nil {
+		return nil, fmt.Errorf("failed to load daemon status: %w", err)
+	}
+
+	// Finish or revert operations that an earlier tvm process didn't complete
+	m.journal = state.NewJournal(filepath.Join(cfg.StateDir, "journal"), filepath.Join(cfg.StateDir, "journal_recovered.yaml"))
+	m.recoverInterruptedOperations()
+
+	// Initialize remote versions cache
+	m.remoteVersionCache = config.NewRemoteVersionsCache(cfg.RemoteVersionsCacheFilePath)
+	scriptsDrivenTVM.UseRemoteVersionsCache(m.remoteVersionCache)
+	if err := m.remoteVersionCache.Load(); err != nil {
+		return nil, fmt.Errorf("failed to load remote versions cache: %w", err)
+	}
+
+	return m, nil
+}
+
+// newPluginTVM creates the manager of a tool type served by a plugin, with the Manager's offline mode.
+// Tool types met while the config loads are finished by New once the install store is loaded, later ones here.
+func (m *Manager) newPluginTVM(toolType string, cfg *config.LocalFileConfig) *plugintvm.PluginTVM {
+	pluginTVM := plugintvm.NewPluginTVM(toolType, cfg)
+	pluginTVM.SetOffline(m.opts.Offline)
+	if m.installStore != nil {
+		m.finishPluginTVM(pluginTVM, toolType)
+	}
+	return pluginTVM
+}
+
+// finishPluginTVM gives a plugin the install store and locates it, which needs the loaded config
+func (m *Manager) finishPluginTVM(pluginTVM *plugintvm.PluginTVM, toolType string) {
+	pluginTVM.UseInstallStore(m.installStore)
+	if err := pluginTVM.Resolve(); err != nil {
+		slog.Warn("Tools of this type can't be managed", "type", toolType, "error", err)
+	}
+}
+
+// Config returns the loaded config
+func (m *Manager) Config() *config.LocalFileConfig { return m.config }
+
+// Registry returns the registry of the tool types of this Manager
+func (m *Manager) Registry() *models.ToolRegistry { return m.registry }
+
+// Offline reports whether the Manager only uses cached versions and artifacts
+func (m *Manager) Offline() bool { return m.opts.Offline }
+
+// RemoteVersionsCache returns the cache of the latest remote versions
+func (m *Manager) RemoteVersionsCache() *config.RemoteVersionsCache { return m.remoteVersionCache }
+
+// InstallStore returns the install metadata
+func (m *Manager) InstallStore() *state.InstallStore { return m.installStore }
+
+// LinkHistory returns the history of links and unlinks
+func (m *Manager) LinkHistory() *state.LinkHistory { return m.linkHistory }
+
+// Journal returns the journal of install, link and unlink operations
+func (m *Manager) Journal() *state.Journal { return m.journal }
+
+// ReleaseNotesStore returns the cache of release notes
+func (m *Manager) ReleaseNotesStore() *state.ReleaseNotesStore { return m.releaseNotesStore }
+
+// ArtifactCache returns the cache of downloaded artifacts
+func (m *Manager) ArtifactCache() *state.ArtifactCache { return m.artifactCache }
+
+// HTTPClient returns the client of tvm's own requests, which applies the config's mirrors
+func (m *Manager) HTTPClient() *httpclient.Client { return m.httpClient }
+
+// DaemonStatus returns the status of the last background check of the daemon
+func (m *Manager) DaemonStatus() *state.DaemonStatusStore { return m.daemonStatus }
+
+// progressf writes a line to Options.Progress, keeping lines of concurrent operations apart
+func (m *Manager) progressf(format string, args ...any) {
+	m.progressMu.Lock()
+	defer m.progressMu.Unlock()
+	fmt.Fprintf(m.opts.Progress, format+"\n", args...)
+}
+
+// Tools returns the tools of the config
+func (m *Manager) Tools() (models.UniqueToolWrappers, error) {
+	var allTools models.UniqueToolWrappers
+
+	for _, toolType := range m.registry.GetRegisteredConfigTypes() {
+		cfg, err := m.registry.GetConfig(toolType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get config for tool type '%s': %w", toolType, err)
+		}
+		allTools = append(allTools, cfg.GetTools()...)
+	}
+
+	return allTools, nil
+}
+
+// ToolIDs returns the IDs of the tools of the config
+func (m *Manager) ToolIDs() ([]string, error) {
+	tools, err := m.Tools()
+	if err != nil {
+		return nil, err
+	}
+	toolIDs := make([]string, len(tools))
+	for i, tool := range tools {
+		toolIDs[i] = tool.Wrapped.GetId()
+	}
+	return toolIDs, nil
+}
+
+// Tool returns the tool with an ID
+func (m *Manager) Tool(toolID string) (models.Tool, error) {
+	for _, toolType := range m.registry.GetRegisteredConfigTypes() {
+		cfg, err := m.registry.GetConfig(toolType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get config service for tool type '%s': %w", toolType, err)
+		}
+		for _, toolWrapper := range cfg.GetTools() {
+			if toolWrapper.Wrapped.GetId() == toolID {
+				return toolWrapper.Wrapped, nil
+			}
+		}
+	}
+	// if we reach here, we didn't find the tool in any registered config service
+	return nil, fmt.Errorf("tool '%s' not found in any configuration", toolID)
+}
+
+// ToolWithTVM returns the tool with an ID and the manager of its type
+func (m *Manager) ToolWithTVM(toolID string) (models.Tool, models.ToolVersionManager, error) {
+	tool, err := m.Tool(toolID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get tool '%s': %w", toolID, err)
+	}
+
+	tvm, err := m.registry.GetTVM(tool.GetType())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get TVM for tool type '%s': %w", tool.GetType(), err)
+	}
+
+	return tool, tvm, nil
+}
+
+// resolveToolIDs returns toolIDs after checking that every tool exists, or all tool IDs if toolIDs is empty
+func (m *Manager) resolveToolIDs(toolIDs []string) ([]string, error) {
+	if len(toolIDs) == 0 {
+		return m.ToolIDs()
+	}
+	for _, toolID := range toolIDs {
+		if toolID == "" {
+			return nil, fmt.Errorf("invalid empty tool ID in input")
+		}
+		if _, err := m.Tool(toolID); err != nil {
+			return nil, fmt.Errorf("tool %s does not exist: %w", toolID, err)
+		}
+	}
+	return toolIDs, nil
+}
+
+func (m *Manager) toolLockPath(toolID string) string {
+	return filepath.Join(m.config.DownloadsDir, ".locks", toolID+".lock")
+}
+
+// LockTool takes the per-tool file lock that guards install, link and unlink,
+// so that two tvm processes (e.g. cron and an interactive shell) don't modify the same tool at once
+func (m *Manager) LockTool(toolID string) (*utils.FileLock, error) {
+	lock, err := utils.LockFile(m.toolLockPath(toolID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock tool %s: %w", toolID, err)
+	}
+	return lock, nil
+}
@@ -0,0 +1,354 @@
+package tvm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"rayyanriaz/tool-version-manager/pkg/impl/state"
+	"rayyanriaz/tool-version-manager/pkg/models"
+)
+
+// verifyTimeout bounds a verify command, so that a hanging binary fails the check instead of blocking it
+const verifyTimeout = 30 * time.Second
+
+// InstallOptions configure Install
+type InstallOptions struct {
+	// Link links the version after installing it
+	Link bool
+	// Force overwrites links owned by other tools or not managed by tvm
+	Force bool
+	// Reinstall replaces an installed version with a fresh build of it, see ReinstallVersion
+	Reinstall bool
+}
+
+// InstallResult is the outcome of Install
+type InstallResult struct {
+	ToolID  string
+	Version models.ToolVersion
+	// AlreadyInstalled is set when the version was installed before and wasn't reinstalled
+	AlreadyInstalled bool
+	// Linked is set when the version was linked, PreviousVersion is what was linked before
+	Linked          bool
+	PreviousVersion models.ToolVersion
+}
+
+// Install installs a version of a tool, the latest one if version is empty
+func (m *Manager) Install(ctx context.Context, toolID string, version models.ToolVersion, opts InstallOptions) (*InstallResult, error) {
+	tool, tvm, err := m.ToolWithTVM(toolID)
+	if err != nil {
+		return nil, err
+	}
+	if version == "" {
+		if version, err = m.LatestVersion(tool, tvm); err != nil {
+			return nil, fmt.Errorf("failed to get latest version for %s: %w", toolID, err)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	lock, err := m.LockTool(toolID)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	result := &InstallResult{ToolID: toolID, Version: version}
+	installed, err := m.IsInstalled(tool, tvm, version)
+	if err != nil {
+		slog.Debug("Failed to get local versions", "tool", toolID, "error", err)
+	}
+	switch {
+	case installed && opts.Reinstall:
+		m.progressf("Reinstalling %s version %s...", toolID, version)
+		if err := m.ReinstallVersion(tool, tvm, version); err != nil {
+			return nil, fmt.Errorf("failed to reinstall %s version %s: %w", toolID, version, err)
+		}
+	case installed:
+		result.AlreadyInstalled = true
+	default:
+		m.progressf("Installing %s version %s...", toolID, version)
+		if err := m.InstallVersion(tool, tvm, version); err != nil {
+			return nil, fmt.Errorf("failed to install %s version %s: %w", toolID, version, err)
+		}
+	}
+
+	if opts.Link {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if result.PreviousVersion, err = m.LinkedVersion(tool, tvm); err != nil {
+			slog.Debug("Failed to get linked version before linking", "tool", toolID, "error", err)
+		}
+		if err := m.LinkVersion(tool, tvm, version, opts.Force); err != nil {
+			return result, fmt.Errorf("failed to link %s version %s: %w", toolID, version, err)
+		}
+		result.Linked = true
+	}
+	return result, nil
+}
+
+// Link links an installed version of a tool. Unless force is set, links owned by other tools or not managed
+// by tvm are not overwritten.
+func (m *Manager) Link(ctx context.Context, toolID string, version models.ToolVersion, force bool) error {
+	tool, tvm, err := m.ToolWithTVM(toolID)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	lock, err := m.LockTool(toolID)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	if err := m.LinkVersion(tool, tvm, version, force); err != nil {
+		return fmt.Errorf("failed to link %s version %s: %w", toolID, version, err)
+	}
+	return nil
+}
+
+// Unlink removes the links of a tool
+func (m *Manager) Unlink(ctx context.Context, toolID string) error {
+	tool, tvm, err := m.ToolWithTVM(toolID)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	lock, err := m.LockTool(toolID)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	if err := m.UnlinkVersion(tool, tvm); err != nil {
+		return fmt.Errorf("failed to unlink %s: %w", toolID, err)
+	}
+	return nil
+}
+
+// VersionDir is where a version of a tool is installed
+func (m *Manager) VersionDir(toolID string, version models.ToolVersion) string {
+	return filepath.Join(m.config.DownloadsDir, toolID, string(version))
+}
+
+// VersionBinaries maps the link names of a tool to its binaries in an installed version,
+// i.e. what its symlinks would point to if that version was linked
+func (m *Manager) VersionBinaries(tool models.Tool, version models.ToolVersion) (map[string]string, error) {
+	versionDir := m.VersionDir(tool.GetId(), version)
+	if _, err := os.Stat(versionDir); err != nil {
+		return nil, fmt.Errorf("%s version %s is not installed", tool.GetId(), version)
+	}
+	binaries := make(map[string]string)
+	for _, symlink := range tool.GetSymlinks() {
+		binaries[symlink.LinkName()] = filepath.Join(versionDir, strings.TrimSpace(symlink.From))
+	}
+	return binaries, nil
+}
+
+// LinkedVersion returns the currently linked version of a tool, or "" if it isn't linked
+func (m *Manager) LinkedVersion(tool models.Tool, tvm models.ToolVersionManager) (models.ToolVersion, error) {
+	linkInfo, err := tvm.GetLinkInfo(tool)
+	if err != nil {
+		return "", err
+	}
+	return linkInfo.Version, nil
+}
+
+// IsInstalled reports whether a version of a tool is installed locally
+func (m *Manager) IsInstalled(tool models.Tool, tvm models.ToolVersionManager, version models.ToolVersion) (bool, error) {
+	localVersions, err := tvm.GetAllLocalVersions(tool)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range localVersions {
+		if v == version {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// InstallVersion installs a version of a tool. The install is journaled, so that a partial install
+// left behind by a killed process is cleaned up on the next startup. A version whose directory already
+// exists is reinstalled instead, see ReinstallVersion. Callers are expected to hold the tool lock.
+func (m *Manager) InstallVersion(tool models.Tool, tvm models.ToolVersionManager, version models.ToolVersion) error {
+	if _, err := os.Stat(m.VersionDir(tool.GetId(), version)); err == nil {
+		return m.ReinstallVersion(tool, tvm, version)
+	}
+	entry, err := m.journal.BeginInstall(tool.GetId(), version, false)
+	if err != nil {
+		return err
+	}
+	defer m.completeJournalEntry(entry)
+
+	return tvm.InstallToolForVersion(tool, version)
+}
+
+// ReinstallVersion replaces an installed version of a tool with a fresh build of it. The new build is staged
+// and swapped in only once it succeeded, so the installed one and its links keep working if it fails, and
+// recovery never removes it. Callers are expected to hold the tool lock.
+func (m *Manager) ReinstallVersion(tool models.Tool, tvm models.ToolVersionManager, version models.ToolVersion) error {
+	reinstaller, ok := tvm.(models.ToolReinstaller)
+	if !ok {
+		return fmt.Errorf("the backend of tool %s can't replace installed versions", tool.GetId())
+	}
+	entry, err := m.journal.BeginInstall(tool.GetId(), version, true)
+	if err != nil {
+		return err
+	}
+	defer m.completeJournalEntry(entry)
+
+	return reinstaller.ReinstallToolForVersion(tool, version)
+}
+
+// LinkVersion links a version of a tool and records the change in the link history. Unless force is set,
+// links owned by other tools or not managed by tvm are not overwritten. The link is journaled, so that a link
+// interrupted by a killed process is reverted on the next startup. Callers are expected to hold the tool lock.
+func (m *Manager) LinkVersion(tool models.Tool, tvm models.ToolVersionManager, version models.ToolVersion, force bool) error {
+	previous, err := m.LinkedVersion(tool, tvm)
+	if err != nil {
+		slog.Debug("Failed to get linked version before linking", "tool", tool.GetId(), "error", err)
+	}
+
+	entry, err := m.journal.Begin(state.JournalOperationLink, tool.GetId(), version, previous)
+	if err != nil {
+		return err
+	}
+	defer m.completeJournalEntry(entry)
+
+	if err := tvm.LinkTool(tool, version, force); err != nil {
+		return err
+	}
+	m.recordLinkEvent(tool.GetId(), state.LinkEvent{Action: state.LinkActionLink, Version: version, PreviousVersion: previous})
+	return nil
+}
+
+// UnlinkVersion unlinks a tool and records the change in the link history. The unlink is journaled,
+// so that it is completed on the next startup if the process is killed. Callers are expected to hold the tool lock.
+func (m *Manager) UnlinkVersion(tool models.Tool, tvm models.ToolVersionManager) error {
+	previous, err := m.LinkedVersion(tool, tvm)
+	if err != nil {
+		slog.Debug("Failed to get linked version before unlinking", "tool", tool.GetId(), "error", err)
+	}
+
+	entry, err := m.journal.Begin(state.JournalOperationUnlink, tool.GetId(), "", previous)
+	if err != nil {
+		return err
+	}
+	defer m.completeJournalEntry(entry)
+
+	if err := tvm.UnlinkTool(tool); err != nil {
+		return err
+	}
+	m.recordLinkEvent(tool.GetId(), state.LinkEvent{Action: state.LinkActionUnlink, PreviousVersion: previous})
+	return nil
+}
+
+func (m *Manager) completeJournalEntry(entry *state.JournalEntry) {
+	if err := m.journal.Complete(entry); err != nil {
+		slog.Warn("Failed to complete journal entry", "entry", entry.ID, "error", err)
+	}
+}
+
+func (m *Manager) recordLinkEvent(toolID string, event state.LinkEvent) {
+	if err := m.linkHistory.Record(toolID, event); err != nil {
+		slog.Warn("Failed to record link event", "tool", toolID, "action", event.Action, "error", err)
+	}
+}
+
+// Verify runs the verify command of a tool, which must be linked to version, through the symlinks dir.
+// Tools without a verify block always pass.
+func (m *Manager) Verify(ctx context.Context, tool models.Tool, version models.ToolVersion) error {
+	verify := tool.GetVerify()
+	if verify == nil {
+		return nil
+	}
+	command, err := verify.RenderCommand(version)
+	if err != nil {
+		return fmt.Errorf("failed to render verify command: %w", err)
+	}
+	expected, err := verify.ExpectedPattern(version)
+	if err != nil {
+		return err
+	}
+	symlinksDir, err := filepath.Abs(m.config.SymlinksDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve symlinks dir: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, verifyTimeout)
+	defer cancel()
+	child := exec.CommandContext(ctx, "bash", "-c", command)
+	// the linked binaries win over anything else on PATH
+	child.Env = append(os.Environ(), "PATH="+symlinksDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	out, err := child.CombinedOutput()
+	output := strings.TrimSpace(string(out))
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("`%s` did not finish within %s", command, verifyTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("`%s` failed: %v: %s", command, err, firstLine(output))
+	}
+	if !expected.MatchString(output) {
+		return fmt.Errorf("`%s` printed %q, expected a match for %s", command, firstLine(output), expected)
+	}
+	return nil
+}
+
+// firstLine shortens command output for error messages
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	const maxLen = 200
+	if len(line) > maxLen {
+		line = line[:maxLen] + "..."
+	}
+	return line
+}
+
+// ReleaseNotes returns the release notes of the versions after from, up to and including to, newest first.
+// Cached notes are used if they include to, or if fresh ones can't be fetched.
+func (m *Manager) ReleaseNotes(tool models.Tool, tvm models.ToolVersionManager, from, to models.ToolVersion) ([]models.ReleaseNote, error) {
+	cached, found := m.releaseNotesStore.Get(tool.GetId())
+	if found && models.HasReleaseNote(cached.Notes, to) {
+		return models.ReleaseNotesBetween(tvm, tool, cached.Notes, from, to), nil
+	}
+
+	provider, ok := tvm.(models.ReleaseNotesProvider)
+	if !ok {
+		return nil, fmt.Errorf("the backend of tool %s doesn't provide release notes", tool.GetId())
+	}
+	notes, err := provider.GetReleaseNotes(tool)
+	if err != nil {
+		if found {
+			slog.Warn("Failed to fetch release notes, showing cached ones", "tool", tool.GetId(), "fetched_at", cached.FetchedAt, "error", err)
+			return models.ReleaseNotesBetween(tvm, tool, cached.Notes, from, to), nil
+		}
+		return nil, err
+	}
+	if err := m.releaseNotesStore.Put(tool.GetId(), notes); err != nil {
+		slog.Warn("Failed to cache release notes", "tool", tool.GetId(), "error", err)
+	}
+	return models.ReleaseNotesBetween(tvm, tool, notes, from, to), nil
+}
+
+// UpdateAvailable reports whether the candidate version is newer than the linked one.
+// Versions that can't be compared are not considered updates.
+func UpdateAvailable(tool models.Tool, tvm models.ToolVersionManager, linked, candidate models.ToolVersion) bool {
+	result, err := tvm.CompareVersions(tool, linked, candidate)
+	if err != nil {
+		slog.Debug("Failed to compare versions", "tool", tool.GetId(), "linked", linked, "candidate", candidate, "error", err)
+		return false
+	}
+	return result < 0
+}
@@ -0,0 +1,64 @@
+package tvm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInstallInstalledVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		reinstall bool
+		hookFails bool
+		wantErr   bool
+		// wantRebuilt tells whether the installed build was replaced
+		wantRebuilt bool
+	}{
+		{name: "skipped without reinstall", wantRebuilt: false},
+		{name: "reinstalled", reinstall: true, wantRebuilt: true},
+		{name: "failed reinstall keeps the installed build", reinstall: true, hookFails: true, wantErr: true, wantRebuilt: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			m := newTestManager(t, dir)
+			ctx := context.Background()
+			if _, err := m.Install(ctx, "t", "1.0", InstallOptions{Link: true}); err != nil {
+				t.Fatal(err)
+			}
+			binary := filepath.Join(m.VersionDir("t", "1.0"), "bin", "t")
+			before, err := os.ReadFile(binary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.hookFails {
+				if err := os.WriteFile(filepath.Join(dir, "hookfail"), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result, err := m.Install(ctx, "t", "1.0", InstallOptions{Reinstall: tt.reinstall})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Install() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && result.AlreadyInstalled == tt.reinstall {
+				t.Errorf("AlreadyInstalled = %v with reinstall %v", result.AlreadyInstalled, tt.reinstall)
+			}
+			after, err := os.ReadFile(filepath.Join(dir, "bin", "t"))
+			if err != nil {
+				t.Fatalf("the linked binary is gone: %v", err)
+			}
+			if rebuilt := string(after) != string(before); rebuilt != tt.wantRebuilt {
+				t.Errorf("rebuilt = %v, want %v", rebuilt, tt.wantRebuilt)
+			}
+			if linked := linkedVersion(t, m); linked != "1.0" {
+				t.Errorf("linked version = %q, want 1.0", linked)
+			}
+			if pending, _ := m.journal.Pending(); len(pending) != 0 {
+				t.Errorf("entries still pending: %+v", pending)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package tvm
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"rayyanriaz/tool-version-manager/pkg/impl/state"
+	"rayyanriaz/tool-version-manager/pkg/models"
+	"rayyanriaz/tool-version-manager/pkg/utils"
+)
+
+// Recovery is an operation interrupted by a killed tvm process, which New finished or reverted
+type Recovery struct {
+	Entry      state.JournalEntry
+	Resolution string
+	Err        error
+}
+
+// Recovered returns the interrupted operations that New recovered
+func (m *Manager) Recovered() []Recovery {
+	return m.recovered
+}
+
+// recoverInterruptedOperations resolves journal entries left behind by tvm processes that were killed.
+// Entries of tools whose lock is still held belong to a running process and are left alone.
+func (m *Manager) recoverInterruptedOperations() {
+	entries, err := m.journal.Pending()
+	if err != nil {
+		slog.Warn("Failed to read journal", "error", err)
+		return
+	}
+
+	for _, entry := range entries {
+		lock, acquired, err := utils.TryLockFile(m.toolLockPath(entry.ToolID))
+		if err != nil {
+			slog.Warn("Failed to lock tool for journal recovery", "tool", entry.ToolID, "error", err)
+			continue
+		}
+		if !acquired {
+			slog.Debug("Journal entry belongs to a running tvm process", "entry", entry.ID)
+			continue
+		}
+
+		resolution, recoverErr := m.recoverJournalEntry(entry)
+		lock.Unlock()
+
+		if err := m.journal.MarkRecovered(entry, resolution, recoverErr); err != nil {
+			slog.Warn("Failed to mark journal entry as recovered", "entry", entry.ID, "error", err)
+		}
+		m.recovered = append(m.recovered, Recovery{Entry: entry, Resolution: resolution, Err: recoverErr})
+	}
+}
+
+// recoverJournalEntry brings a tool back to a consistent state after an interrupted operation:
+// links are reverted to the previous version, unlinks are completed, and partial installs are removed.
+// A version that was installed before the interrupted install, or that is linked, is kept and checked instead.
+func (m *Manager) recoverJournalEntry(entry state.JournalEntry) (string, error) {
+	tool, tvm, err := m.ToolWithTVM(entry.ToolID)
+	if err != nil {
+		return "tool is no longer configured, entry discarded", nil
+	}
+
+	switch entry.Operation {
+	case state.JournalOperationInstall:
+		if linked, err := m.LinkedVersion(tool, tvm); entry.PreExisting || (err == nil && linked == entry.Version) {
+			return m.checkInterruptedReinstall(tool, entry.Version)
+		}
+		uninstaller, ok := tvm.(models.ToolUninstaller)
+		if !ok {
+			return "the possibly partial install was left in place", nil
+		}
+		if err := uninstaller.UninstallToolVersion(tool, entry.Version); err != nil {
+			return "failed to remove the partial install", err
+		}
+		return fmt.Sprintf("removed the partial install of %s", entry.Version), nil
+
+	case state.JournalOperationLink:
+		if entry.PreviousVersion == "" {
+			if err := m.forceUnlink(tool, tvm); err != nil {
+				return "failed to unlink", err
+			}
+			m.recordLinkEvent(tool.GetId(), state.LinkEvent{Action: state.LinkActionUnlink})
+			return "unlinked, as no version was linked before", nil
+		}
+		if err := tvm.LinkTool(tool, entry.PreviousVersion, false); err != nil {
+			return fmt.Sprintf("failed to relink %s", entry.PreviousVersion), err
+		}
+		m.recordLinkEvent(tool.GetId(), state.LinkEvent{Action: state.LinkActionLink, Version: entry.PreviousVersion})
+		return fmt.Sprintf("relinked the previous version %s", entry.PreviousVersion), nil
+
+	case state.JournalOperationUnlink:
+		if err := m.forceUnlink(tool, tvm); err != nil {
+			return "failed to complete the unlink", err
+		}
+		m.recordLinkEvent(tool.GetId(), state.LinkEvent{Action: state.LinkActionUnlink, PreviousVersion: entry.PreviousVersion})
+		return "completed the unlink", nil
+	}
+	return "", fmt.Errorf("unknown journal operation %q", entry.Operation)
+}
+
+// checkInterruptedReinstall keeps a version whose reinstall was interrupted. If its content no longer matches
+// its install record, the record is marked as unverified.
+func (m *Manager) checkInterruptedReinstall(tool models.Tool, version models.ToolVersion) (string, error) {
+	record, found := m.installStore.Get(tool.GetId(), version)
+	digest, _, err := utils.DirDigest(m.VersionDir(tool.GetId(), version))
+	if found && err == nil && record.Digest != "" && digest == record.Digest {
+		return fmt.Sprintf("kept %s, which was installed before and is unchanged", version), nil
+	}
+	if !found {
+		record = state.InstallRecord{Version: version, InstalledAt: time.Now()}
+	}
+	record.Unverified = "a reinstall was interrupted, it may be incomplete"
+	if err := m.installStore.Put(tool.GetId(), record); err != nil {
+		return fmt.Sprintf("kept %s, which was installed before, but failed to mark it as unverified", version), err
+	}
+	return fmt.Sprintf("kept %s, which was installed before, and marked it as unverified: reinstall it if it doesn't work", version), nil
+}
+
+// forceUnlink unlinks a tool, treating a tool that isn't linked as success
+func (m *Manager) forceUnlink(tool models.Tool, tvm models.ToolVersionManager) error {
+	if linked, err := m.LinkedVersion(tool, tvm); err == nil && linked == "" {
+		return nil
+	}
+	return tvm.UnlinkTool(tool)
+}
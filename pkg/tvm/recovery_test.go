@@ -0,0 +1,217 @@
+package tvm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rayyanriaz/tool-version-manager/pkg/impl/state"
+	"rayyanriaz/tool-version-manager/pkg/models"
+)
+
+// testConfig has a tool "t" whose versions are installed from a script, with bin/t linked. Its post_install
+// hook fails while {{DIR}}/hookfail exists.
+const testConfig = `downloads_dir: {{DIR}}/dl
+symlinks_dir: {{DIR}}/bin
+remote_versions_cache_file_path: {{DIR}}/cache.yaml
+tools:
+  - id: t
+    type: scripts_driven
+    symlinks:
+      - from: bin/t
+    hooks:
+      post_install:
+        - name: check
+          script: '[ ! -f "{{DIR}}/hookfail" ]'
+    source:
+      scripts:
+        getAllLocalVersions:
+          - name: base
+            script: ls "{{.Config.DownloadsDir}}/{{.Tool.Id}}" | grep -v current
+        getAllRemoteVersions:
+          - name: base
+            script: printf '1.0\n2.0\n'
+        getLatestRemoteVersion:
+          - name: base
+            script: echo 2.0
+        fetchToolForVersion:
+          - name: base
+            script: |
+              d="{{.Config.DownloadsDir}}/{{.Tool.Id}}/{{.Arg}}/bin"
+              mkdir -p "$d" && date +%s%N > "$d/t" && chmod +x "$d/t"
+`
+
+func newTestManager(t *testing.T, dir string) *Manager {
+	t.Helper()
+	configPath := filepath.Join(dir, "tools.yaml")
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		if err := os.WriteFile(configPath, []byte(strings.ReplaceAll(testConfig, "{{DIR}}", dir)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, err := New(Options{ConfigPath: configPath})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return m
+}
+
+func linkedVersion(t *testing.T, m *Manager) models.ToolVersion {
+	t.Helper()
+	tool, tvm, err := m.ToolWithTVM("t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	linked, err := m.LinkedVersion(tool, tvm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return linked
+}
+
+func TestRecoverInterruptedOperations(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup installs and links versions and leaves a journal entry behind, like a killed process
+		setup          func(t *testing.T, m *Manager, journal *state.Journal) (*state.JournalEntry, error)
+		wantResolution string
+		check          func(t *testing.T, m *Manager)
+	}{
+		{
+			name: "partial install is removed",
+			setup: func(t *testing.T, m *Manager, journal *state.Journal) (*state.JournalEntry, error) {
+				if err := os.MkdirAll(filepath.Join(m.VersionDir("t", "3.0"), "bin"), 0755); err != nil {
+					t.Fatal(err)
+				}
+				return journal.BeginInstall("t", "3.0", false)
+			},
+			wantResolution: "removed the partial install of 3.0",
+			check: func(t *testing.T, m *Manager) {
+				if _, err := os.Stat(m.VersionDir("t", "3.0")); !os.IsNotExist(err) {
+					t.Errorf("partial install was not removed: %v", err)
+				}
+			},
+		},
+		{
+			name: "unchanged reinstalled version is kept",
+			setup: func(t *testing.T, m *Manager, journal *state.Journal) (*state.JournalEntry, error) {
+				return journal.BeginInstall("t", "1.0", true)
+			},
+			wantResolution: "kept 1.0, which was installed before and is unchanged",
+			check: func(t *testing.T, m *Manager) {
+				if record, _ := m.installStore.Get("t", "1.0"); record.Unverified != "" {
+					t.Errorf("unchanged version was marked as unverified: %s", record.Unverified)
+				}
+			},
+		},
+		{
+			name: "changed reinstalled version is kept and marked as unverified",
+			setup: func(t *testing.T, m *Manager, journal *state.Journal) (*state.JournalEntry, error) {
+				if err := os.WriteFile(filepath.Join(m.VersionDir("t", "1.0"), "bin", "t"), []byte("half"), 0755); err != nil {
+					t.Fatal(err)
+				}
+				return journal.BeginInstall("t", "1.0", true)
+			},
+			wantResolution: "marked it as unverified",
+			check: func(t *testing.T, m *Manager) {
+				if _, err := os.Stat(m.VersionDir("t", "1.0")); err != nil {
+					t.Errorf("version was removed: %v", err)
+				}
+				if record, _ := m.installStore.Get("t", "1.0"); record.Unverified == "" {
+					t.Errorf("changed version was not marked as unverified")
+				}
+			},
+		},
+		{
+			name: "linked version is never removed",
+			setup: func(t *testing.T, m *Manager, journal *state.Journal) (*state.JournalEntry, error) {
+				return journal.BeginInstall("t", "2.0", false)
+			},
+			wantResolution: "kept 2.0",
+			check: func(t *testing.T, m *Manager) {
+				if linked := linkedVersion(t, m); linked != "2.0" {
+					t.Errorf("linked version = %q, want 2.0", linked)
+				}
+			},
+		},
+		{
+			name: "link is reverted to the previous version",
+			setup: func(t *testing.T, m *Manager, journal *state.Journal) (*state.JournalEntry, error) {
+				return journal.Begin(state.JournalOperationLink, "t", "2.0", "1.0")
+			},
+			wantResolution: "relinked the previous version 1.0",
+			check: func(t *testing.T, m *Manager) {
+				if linked := linkedVersion(t, m); linked != "1.0" {
+					t.Errorf("linked version = %q, want 1.0", linked)
+				}
+			},
+		},
+		{
+			name: "first link is reverted by unlinking",
+			setup: func(t *testing.T, m *Manager, journal *state.Journal) (*state.JournalEntry, error) {
+				return journal.Begin(state.JournalOperationLink, "t", "2.0", "")
+			},
+			wantResolution: "unlinked, as no version was linked before",
+			check: func(t *testing.T, m *Manager) {
+				if linked := linkedVersion(t, m); linked != "" {
+					t.Errorf("linked version = %q, want none", linked)
+				}
+			},
+		},
+		{
+			name: "unlink is completed",
+			setup: func(t *testing.T, m *Manager, journal *state.Journal) (*state.JournalEntry, error) {
+				return journal.Begin(state.JournalOperationUnlink, "t", "", "2.0")
+			},
+			wantResolution: "completed the unlink",
+			check: func(t *testing.T, m *Manager) {
+				if linked := linkedVersion(t, m); linked != "" {
+					t.Errorf("linked version = %q, want none", linked)
+				}
+			},
+		},
+		{
+			name: "tool no longer configured",
+			setup: func(t *testing.T, m *Manager, journal *state.Journal) (*state.JournalEntry, error) {
+				return journal.BeginInstall("gone", "1.0", false)
+			},
+			wantResolution: "tool is no longer configured, entry discarded",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			m := newTestManager(t, dir)
+			ctx := context.Background()
+			if _, err := m.Install(ctx, "t", "1.0", InstallOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := m.Install(ctx, "t", "2.0", InstallOptions{Link: true}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tt.setup(t, m, m.journal); err != nil {
+				t.Fatal(err)
+			}
+
+			m = newTestManager(t, dir)
+			recovered := m.Recovered()
+			if len(recovered) != 1 {
+				t.Fatalf("recovered %d operations, want 1: %+v", len(recovered), recovered)
+			}
+			if err := recovered[0].Err; err != nil {
+				t.Errorf("recovery failed: %v", err)
+			}
+			if !strings.Contains(recovered[0].Resolution, tt.wantResolution) {
+				t.Errorf("resolution = %q, want one containing %q", recovered[0].Resolution, tt.wantResolution)
+			}
+			if pending, _ := m.journal.Pending(); len(pending) != 0 {
+				t.Errorf("entries still pending after recovery: %+v", pending)
+			}
+			if tt.check != nil {
+				tt.check(t, m)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package tvm
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"rayyanriaz/tool-version-manager/pkg/models"
+)
+
+// RefreshMode tells Status which latest versions to fetch before reporting
+type RefreshMode string
+
+const (
+	// RefreshStale fetches the latest versions whose cache entry is older than remote_versions_cache_max_age
+	RefreshStale RefreshMode = ""
+	// RefreshAll fetches every latest version
+	RefreshAll RefreshMode = "all"
+	// RefreshNone only uses the cache
+	RefreshNone RefreshMode = "none"
+)
+
+// StatusOptions configure Status
+type StatusOptions struct {
+	// ToolIDs are the tools to report on, all tools if empty
+	ToolIDs []string
+	Refresh RefreshMode
+}
+
+// ToolStatus is what is installed and linked of a tool, and what is available upstream
+type ToolStatus struct {
+	ID   string
+	Type string
+	// LinkedVersion is empty if the tool isn't linked
+	LinkedVersion models.ToolVersion
+	LinkedAt      string
+	LocalVersions []models.ToolVersion
+	// LatestVersion is the cached latest version in the tool's channel, empty if it was never fetched
+	LatestVersion   models.ToolVersion
+	UpdateAvailable bool
+	// FetchError is the error of the last failed fetch of the latest version, if it failed
+	FetchError    string
+	FetchFailedAt time.Time
+}
+
+// Status reports on tools, after refreshing their latest versions as opts.Refresh says
+func (m *Manager) Status(ctx context.Context, opts StatusOptions) ([]ToolStatus, error) {
+	toolIDs, err := m.resolveToolIDs(opts.ToolIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshIDs := toolIDs
+	switch opts.Refresh {
+	case RefreshNone:
+		refreshIDs = nil
+	case RefreshStale:
+		refreshIDs = m.StaleToolIDs(toolIDs, m.config.GetRemoteVersionsCacheMaxAge())
+	}
+	if len(refreshIDs) > 0 {
+		slog.Debug("Refreshing latest remote versions", "tools", refreshIDs)
+		m.refreshLatestVersions(ctx, refreshIDs)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]ToolStatus, 0, len(toolIDs))
+	for _, toolID := range toolIDs {
+		tool, tvm, err := m.ToolWithTVM(toolID)
+		if err != nil {
+			return nil, err
+		}
+		status := ToolStatus{
+			ID:   tool.GetId(),
+			Type: tool.GetType(),
+		}
+
+		if linkInfo, err := tvm.GetLinkInfo(tool); err == nil && linkInfo != nil && linkInfo.Version != "" {
+			status.LinkedVersion = linkInfo.Version
+			status.LinkedAt = linkInfo.LinkedAt
+		}
+		if localVersions, err := tvm.GetAllLocalVersions(tool); err == nil {
+			status.LocalVersions = localVersions
+		}
+		if latest, _, found := m.CachedLatestVersion(tool); found {
+			status.LatestVersion = latest
+			if status.LinkedVersion != "" {
+				status.UpdateAvailable = UpdateAvailable(tool, tvm, status.LinkedVersion, latest)
+			}
+		}
+		if fetchErr, failedAt, failed := m.remoteVersionCache.GetFetchError(toolID); failed {
+			status.FetchError = fetchErr
+			status.FetchFailedAt = failedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
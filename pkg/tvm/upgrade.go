@@ -0,0 +1,355 @@
+package tvm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
+
+	"rayyanriaz/tool-version-manager/pkg/models"
+)
+
+// UpgradeOptions configure Upgrade
+type UpgradeOptions struct {
+	// ToolIDs are the tools to upgrade, all tools if empty
+	ToolIDs []string
+	// Force upgrades even if the latest version is already linked
+	Force bool
+	// OverwriteLinks overwrites links owned by other tools or not managed by tvm
+	OverwriteLinks bool
+	// Atomic installs everything first and switches links only if all installs succeed,
+	// reverting all links if any link or verification fails
+	Atomic bool
+	// WithinConstraints upgrades tools with a constraint to the newest version it allows instead of the latest one
+	WithinConstraints bool
+	// OnNotes, if set, is called with the release notes of each upgrade before it is installed.
+	// It may be called concurrently for different tools.
+	OnNotes func(tool models.Tool, from, to models.ToolVersion, notes []models.ReleaseNote, err error)
+}
+
+// UpgradeStatus is what happened to one tool in an upgrade
+type UpgradeStatus string
+
+const (
+	UpgradeUpgraded UpgradeStatus = "upgraded"
+	UpgradeUpToDate UpgradeStatus = "up_to_date"
+	UpgradeFailed   UpgradeStatus = "failed"
+	// UpgradeRolledBack is an upgrade of an atomic batch that was undone because another one failed
+	UpgradeRolledBack UpgradeStatus = "rolled_back"
+)
+
+// UpgradeResult is the outcome of upgrading one tool
+type UpgradeResult struct {
+	ToolID string
+	// From is the version linked before the upgrade, empty if none was
+	From   models.ToolVersion
+	To     models.ToolVersion
+	Status UpgradeStatus
+	Err    error
+	// RestoreErr is set when From could not be linked again after a failed or rolled back upgrade
+	RestoreErr error
+}
+
+// upgradePlan is a tool whose target version has been resolved and installed, but not linked yet
+type upgradePlan struct {
+	tool           models.Tool
+	tvm            models.ToolVersionManager
+	currentVersion models.ToolVersion
+	targetVersion  models.ToolVersion
+	upToDate       bool
+}
+
+func (p *upgradePlan) result(status UpgradeStatus, err error) UpgradeResult {
+	return UpgradeResult{ToolID: p.tool.GetId(), From: p.currentVersion, To: p.targetVersion, Status: status, Err: err}
+}
+
+// Upgrade upgrades tools to their latest versions. Without opts.Atomic, tools are upgraded independently,
+// so some may succeed while others fail. The error summarizes the failures, the results tell each tool's outcome.
+func (m *Manager) Upgrade(ctx context.Context, opts UpgradeOptions) ([]UpgradeResult, error) {
+	toolIDs, err := m.resolveToolIDs(opts.ToolIDs)
+	if err != nil {
+		return nil, err
+	}
+	slog.Debug("Upgrading", "tools", toolIDs)
+	if opts.Atomic {
+		return m.upgradeAtomically(ctx, toolIDs, opts)
+	}
+
+	var wg sync.WaitGroup
+	results := make([]UpgradeResult, len(toolIDs))
+	for i, toolID := range toolIDs {
+		wg.Add(1)
+		go func(i int, toolID string) {
+			defer wg.Done()
+			results[i] = m.upgradeTool(ctx, toolID, opts)
+		}(i, toolID)
+	}
+	wg.Wait()
+
+	var finalErr error
+	for _, result := range results {
+		if result.Err != nil {
+			finalErr = errors.Join(finalErr, fmt.Errorf("%s: %w", result.ToolID, result.Err))
+		}
+	}
+	if finalErr != nil {
+		return results, fmt.Errorf("errors occurred during upgrade: %w", finalErr)
+	}
+	return results, nil
+}
+
+func (m *Manager) upgradeTool(ctx context.Context, toolID string, opts UpgradeOptions) UpgradeResult {
+	failed := func(err error) UpgradeResult {
+		return UpgradeResult{ToolID: toolID, Status: UpgradeFailed, Err: err}
+	}
+	lock, err := m.LockTool(toolID)
+	if err != nil {
+		return failed(err)
+	}
+	defer lock.Unlock()
+
+	plan, err := m.prepareUpgrade(ctx, toolID, opts)
+	if err != nil {
+		return failed(err)
+	}
+	if plan.upToDate {
+		return plan.result(UpgradeUpToDate, nil)
+	}
+	if err := ctx.Err(); err != nil {
+		return plan.result(UpgradeFailed, err)
+	}
+
+	// Link latest version
+	if err := m.LinkVersion(plan.tool, plan.tvm, plan.targetVersion, opts.OverwriteLinks); err != nil {
+		return plan.result(UpgradeFailed, fmt.Errorf("failed to link %s version %s: %w", toolID, plan.targetVersion, err))
+	}
+	if err := m.Verify(ctx, plan.tool, plan.targetVersion); err != nil {
+		return m.revertFailedVerification(plan, err)
+	}
+	return plan.result(UpgradeUpgraded, nil)
+}
+
+// revertFailedVerification relinks the previous version after the upgraded one failed its verify check
+func (m *Manager) revertFailedVerification(plan *upgradePlan, verifyErr error) UpgradeResult {
+	toolID := plan.tool.GetId()
+	if err := m.revertLink(plan); err != nil {
+		result := plan.result(UpgradeFailed, fmt.Errorf("%s version %s failed verification: %w (and failed to restore %s: %v)", toolID, plan.targetVersion, verifyErr, DescribeVersion(plan.currentVersion), err))
+		result.RestoreErr = err
+		return result
+	}
+	return plan.result(UpgradeFailed, fmt.Errorf("%s version %s failed verification, restored %s: %w", toolID, plan.targetVersion, DescribeVersion(plan.currentVersion), verifyErr))
+}
+
+// prepareUpgrade resolves the latest version of a tool and installs it if needed, without linking it.
+// Callers are expected to hold the tool lock.
+func (m *Manager) prepareUpgrade(ctx context.Context, toolID string, opts UpgradeOptions) (*upgradePlan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tool, tvm, err := m.ToolWithTVM(toolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tool %s: %w", toolID, err)
+	}
+	plan := &upgradePlan{tool: tool, tvm: tvm}
+
+	// Get latest version, refetching it only if the cached one is stale
+	latestVersion, err := m.LatestVersion(tool, tvm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest version for %s: %w", toolID, err)
+	}
+	if opts.WithinConstraints {
+		if latestVersion, err = m.TargetVersion(tool, tvm, latestVersion); err != nil {
+			return nil, fmt.Errorf("failed to get target version for %s: %w", toolID, err)
+		}
+	}
+	plan.targetVersion = latestVersion
+
+	// Get current linked version
+	linkInfo, err := tvm.GetLinkInfo(tool)
+	if err != nil {
+		// If linkInfo is nil or Version is empty, treat as no current version
+		if linkInfo != nil && linkInfo.Version != "" {
+			return nil, fmt.Errorf("failed to get current version for %s: %w", toolID, err)
+		}
+		// No current version, proceed with installation
+	} else {
+		plan.currentVersion = linkInfo.Version
+	}
+
+	// a moving tag like `nightly` keeps its name when upstream rebuilds it, so its digest tells whether to refetch
+	rebuilt := m.hasMovedSinceInstall(tool, tvm, latestVersion)
+
+	// Compare versions if there's a current version
+	if plan.currentVersion != "" && !rebuilt {
+		result, err := tvm.CompareVersions(tool, plan.currentVersion, latestVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compare versions for %s: %w", toolID, err)
+		}
+
+		if result >= 0 && !opts.Force {
+			if opts.WithinConstraints && tool.GetConstraint() != "" {
+				m.progressf("%s is already at the newest version its constraint allows (%s)", toolID, plan.currentVersion)
+			} else {
+				m.progressf("%s is already at the latest version (%s)", toolID, plan.currentVersion)
+			}
+			plan.upToDate = true
+			return plan, nil
+		}
+	}
+
+	m.progressf("Upgrading %s to version %s...", toolID, latestVersion)
+	if opts.OnNotes != nil && !rebuilt {
+		notes, err := m.ReleaseNotes(tool, tvm, plan.currentVersion, latestVersion)
+		opts.OnNotes(tool, plan.currentVersion, latestVersion, notes, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// check if the tool is already installed
+	installed, err := m.IsInstalled(tool, tvm, latestVersion)
+	if err != nil {
+		slog.Debug("Warning: failed to get local versions", "tool", toolID, "error", err)
+	}
+	if installed && rebuilt {
+		// the installed build stays in place, and linked, until the new one is installed
+		m.progressf("%s %s was rebuilt upstream, refetching it...", toolID, latestVersion)
+		if err := m.ReinstallVersion(tool, tvm, latestVersion); err != nil {
+			return nil, fmt.Errorf("failed to refetch %s version %s: %w", toolID, latestVersion, err)
+		}
+	} else if installed {
+		slog.Debug("Tool version is already installed", "tool", toolID, "version", latestVersion)
+	} else {
+		err = m.InstallVersion(tool, tvm, latestVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to install %s version %s: %w", toolID, latestVersion, err)
+		}
+	}
+	return plan, nil
+}
+
+// upgradeAtomically upgrades all tools or none: every install happens first, links are switched only
+// if all installs succeeded, and if any link fails every tool is relinked to its pre-upgrade version
+func (m *Manager) upgradeAtomically(ctx context.Context, toolIDs []string, opts UpgradeOptions) ([]UpgradeResult, error) {
+	// hold all tool locks for the whole batch, taken in a stable order so that concurrent batches can't deadlock
+	sortedIDs := append([]string(nil), toolIDs...)
+	sort.Strings(sortedIDs)
+	sortedIDs = slices.Compact(sortedIDs)
+	for _, toolID := range sortedIDs {
+		lock, err := m.LockTool(toolID)
+		if err != nil {
+			return nil, err
+		}
+		defer lock.Unlock()
+	}
+
+	// Phase 1: resolve and install everything, nothing is linked yet
+	var wg sync.WaitGroup
+	plans := make([]*upgradePlan, len(sortedIDs))
+	errs := make([]error, len(sortedIDs))
+	for i, toolID := range sortedIDs {
+		wg.Add(1)
+		go func(i int, toolID string) {
+			defer wg.Done()
+			plans[i], errs[i] = m.prepareUpgrade(ctx, toolID, opts)
+		}(i, toolID)
+	}
+	wg.Wait()
+
+	results := make([]UpgradeResult, len(sortedIDs))
+	if installErr := errors.Join(errs...); installErr != nil {
+		for i, toolID := range sortedIDs {
+			switch {
+			case errs[i] != nil:
+				results[i] = UpgradeResult{ToolID: toolID, Status: UpgradeFailed, Err: errs[i]}
+			case plans[i].upToDate:
+				results[i] = plans[i].result(UpgradeUpToDate, nil)
+			default:
+				results[i] = plans[i].result(UpgradeRolledBack, nil)
+			}
+		}
+		return results, fmt.Errorf("atomic upgrade rolled back: %w", installErr)
+	}
+
+	// Phase 2: switch the links, remembering what was switched so that it can be reverted
+	var switched []int
+	var linkErr error
+	for i, plan := range plans {
+		if plan.upToDate {
+			results[i] = plan.result(UpgradeUpToDate, nil)
+			continue
+		}
+		results[i] = plan.result(UpgradeUpgraded, nil)
+		if linkErr != nil {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			linkErr = err
+			continue
+		}
+		switched = append(switched, i)
+		if err := m.LinkVersion(plan.tool, plan.tvm, plan.targetVersion, opts.OverwriteLinks); err != nil {
+			linkErr = fmt.Errorf("failed to link %s version %s: %w", plan.tool.GetId(), plan.targetVersion, err)
+			results[i].Err = linkErr
+		}
+	}
+
+	// Phase 3: smoke test what was switched, a failure reverts the whole batch like a failed link
+	if linkErr == nil {
+		for _, i := range switched {
+			plan := plans[i]
+			if err := m.Verify(ctx, plan.tool, plan.targetVersion); err != nil {
+				linkErr = fmt.Errorf("%s version %s failed verification: %w", plan.tool.GetId(), plan.targetVersion, err)
+				results[i].Err = linkErr
+				break
+			}
+		}
+	}
+	if linkErr == nil {
+		return results, nil
+	}
+
+	var restoreErr error
+	for i := range results {
+		if results[i].Status == UpgradeUpgraded {
+			results[i].Status = UpgradeRolledBack
+			if results[i].Err != nil {
+				results[i].Status = UpgradeFailed
+			}
+		}
+	}
+	for j := len(switched) - 1; j >= 0; j-- {
+		i := switched[j]
+		if err := m.revertLink(plans[i]); err != nil {
+			results[i].RestoreErr = err
+			restoreErr = errors.Join(restoreErr, err)
+		}
+	}
+	if restoreErr != nil {
+		return results, fmt.Errorf("atomic upgrade failed and could not be fully rolled back: %w", errors.Join(linkErr, restoreErr))
+	}
+	return results, fmt.Errorf("atomic upgrade rolled back: %w", linkErr)
+}
+
+// revertLink restores the version that was linked before the upgrade, or unlinks the tool if none was
+func (m *Manager) revertLink(plan *upgradePlan) error {
+	if plan.currentVersion == "" {
+		linked, err := m.LinkedVersion(plan.tool, plan.tvm)
+		if err == nil && linked == "" {
+			return nil
+		}
+		return m.UnlinkVersion(plan.tool, plan.tvm)
+	}
+	return m.LinkVersion(plan.tool, plan.tvm, plan.currentVersion, false)
+}
+
+// DescribeVersion renders a linked version for humans, an empty one as "(not linked)"
+func DescribeVersion(version models.ToolVersion) string {
+	if version == "" {
+		return "(not linked)"
+	}
+	return string(version)
+}
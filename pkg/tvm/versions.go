@@ -0,0 +1,210 @@
+package tvm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"rayyanriaz/tool-version-manager/pkg/models"
+)
+
+// FetchOptions configure Fetch
+type FetchOptions struct {
+	// ToolIDs are the tools to fetch, all tools if empty
+	ToolIDs []string
+	// StaleOnly skips tools whose cached version is fresher than MaxAge
+	StaleOnly bool
+	// MaxAge is how old a cached version may be with StaleOnly, remote_versions_cache_max_age if zero
+	MaxAge time.Duration
+}
+
+// FetchResult is the outcome of fetching the latest remote version of one tool
+type FetchResult struct {
+	ToolID  string
+	Version models.ToolVersion
+	Err     error
+}
+
+// Fetch asks the remote for the latest versions of tools and records every outcome, version or error,
+// in the remote versions cache. It only fails as a whole on invalid options, the results hold the errors
+// of single tools.
+func (m *Manager) Fetch(ctx context.Context, opts FetchOptions) ([]FetchResult, error) {
+	toolIDs, err := m.resolveToolIDs(opts.ToolIDs)
+	if err != nil {
+		return nil, err
+	}
+	if opts.StaleOnly {
+		maxAge := opts.MaxAge
+		if maxAge == 0 {
+			maxAge = m.config.GetRemoteVersionsCacheMaxAge()
+		}
+		toolIDs = m.StaleToolIDs(toolIDs, maxAge)
+		if len(toolIDs) == 0 {
+			return nil, nil
+		}
+	}
+	if m.opts.Offline {
+		return nil, fmt.Errorf("cannot fetch remote versions: %w", models.ErrOffline)
+	}
+	return m.refreshLatestVersions(ctx, toolIDs), nil
+}
+
+// refreshLatestVersions fetches the latest remote versions for the given tools concurrently
+// and records every outcome (version or error) in the remote versions cache
+func (m *Manager) refreshLatestVersions(ctx context.Context, toolIDs []string) []FetchResult {
+	var wg sync.WaitGroup
+	results := make([]FetchResult, len(toolIDs))
+	tools := make([]models.Tool, len(toolIDs))
+	if m.opts.Offline {
+		for i, toolID := range toolIDs {
+			results[i] = FetchResult{toolID, "", fmt.Errorf("cannot fetch the latest version: %w", models.ErrOffline)}
+		}
+		return results
+	}
+
+	for i, toolID := range toolIDs {
+		wg.Add(1)
+		go func(i int, toolID string) {
+			defer wg.Done()
+			if err := ctx.Err(); err != nil {
+				results[i] = FetchResult{toolID, "", err}
+				return
+			}
+			tool, tvm, err := m.ToolWithTVM(toolID)
+			if err != nil {
+				results[i] = FetchResult{toolID, "", err}
+				return
+			}
+
+			version, err := models.LatestRemoteVersionInChannel(tvm, tool, m.ToolChannel(tool))
+			tools[i] = tool
+			results[i] = FetchResult{toolID, version, err}
+		}(i, toolID)
+	}
+
+	wg.Wait()
+
+	for i, result := range results {
+		if ctx.Err() != nil && result.Err == ctx.Err() {
+			// not fetched at all, which isn't a fetch failure worth remembering
+			continue
+		}
+		if result.Err != nil {
+			if err := m.recordFetchError(result.ToolID, result.Err); err != nil {
+				results[i].Err = fmt.Errorf("%w (and failed to record the error: %v)", result.Err, err)
+			}
+			continue
+		}
+		if err := m.updateCachedLatestVersion(tools[i], result.Version); err != nil {
+			results[i].Err = fmt.Errorf("failed to cache: %w", err)
+		}
+	}
+	return results
+}
+
+// ToolChannel returns the release channel to follow for a tool: Options.Channel, or the tool's own
+func (m *Manager) ToolChannel(tool models.Tool) models.Channel {
+	if m.opts.Channel != "" {
+		return m.opts.Channel
+	}
+	// validated when the config was loaded
+	channel, _ := models.ParseChannel(tool.GetChannel())
+	return channel
+}
+
+// CachedLatestVersion returns the cached latest version of a tool in its channel
+func (m *Manager) CachedLatestVersion(tool models.Tool) (models.ToolVersion, time.Time, bool) {
+	return m.remoteVersionCache.GetCachedVersion(tool.GetId(), m.ToolChannel(tool))
+}
+
+// updateCachedLatestVersion updates the cached latest version of a tool in its channel and saves to disk
+func (m *Manager) updateCachedLatestVersion(tool models.Tool, version models.ToolVersion) error {
+	if _, err := models.ParseVersionOutput(string(version)); err != nil {
+		return fmt.Errorf("refusing to cache the latest version of %s: %w", tool.GetId(), err)
+	}
+	m.remoteVersionCache.SetCachedVersion(tool.GetId(), version, m.ToolChannel(tool))
+	return m.remoteVersionCache.Save()
+}
+
+// recordFetchError remembers a failed fetch for a tool and saves to disk, so that stale data can be reported as such
+func (m *Manager) recordFetchError(toolID string, fetchErr error) error {
+	m.remoteVersionCache.SetFetchError(toolID, fetchErr)
+	return m.remoteVersionCache.Save()
+}
+
+// StaleToolIDs returns the subset of toolIDs whose cached latest version is missing, older than maxAge,
+// or cached for another channel
+func (m *Manager) StaleToolIDs(toolIDs []string, maxAge time.Duration) []string {
+	if m.opts.Offline {
+		// nothing can be refreshed, cached versions are used however old they are
+		return nil
+	}
+	var stale []string
+	for _, toolID := range toolIDs {
+		tool, err := m.Tool(toolID)
+		if err != nil || m.remoteVersionCache.IsStale(toolID, m.ToolChannel(tool), maxAge) {
+			stale = append(stale, toolID)
+		}
+	}
+	return stale
+}
+
+// LatestVersion returns the cached latest version of a tool while it is fresh,
+// and fetches it from remote (updating the cache) once it has gone stale
+func (m *Manager) LatestVersion(tool models.Tool, tvm models.ToolVersionManager) (models.ToolVersion, error) {
+	if version, checkedAt, found := m.CachedLatestVersion(tool); found &&
+		(m.opts.Offline || time.Since(checkedAt) <= m.config.GetRemoteVersionsCacheMaxAge()) {
+		slog.Debug("Using cached latest version", "tool", tool.GetId(), "version", version)
+		return version, nil
+	}
+	return m.FetchLatestVersion(tool, tvm)
+}
+
+// FetchLatestVersion always asks the remote for the latest version in the tool's channel and records the outcome in the cache
+func (m *Manager) FetchLatestVersion(tool models.Tool, tvm models.ToolVersionManager) (models.ToolVersion, error) {
+	version, err := models.LatestRemoteVersionInChannel(tvm, tool, m.ToolChannel(tool))
+	if m.opts.Offline {
+		// answered from the cache, which must not look freshly checked
+		return version, err
+	}
+	if err != nil {
+		_ = m.recordFetchError(tool.GetId(), err)
+		return "", err
+	}
+	_ = m.updateCachedLatestVersion(tool, version)
+	return version, nil
+}
+
+// TargetVersion is the version a tool should be on given its latest version: the latest version itself,
+// or, if the tool has a constraint, the newest remote version the constraint allows
+func (m *Manager) TargetVersion(tool models.Tool, tvm models.ToolVersionManager, latest models.ToolVersion) (models.ToolVersion, error) {
+	if tool.GetConstraint() == "" {
+		return latest, nil
+	}
+	remoteVersions, err := tvm.GetAllRemoteVersions(tool)
+	if err != nil {
+		return "", fmt.Errorf("failed to get remote versions: %w", err)
+	}
+	return models.NewestAllowed(tvm, tool, remoteVersions, m.ToolChannel(tool))
+}
+
+// hasMovedSinceInstall reports whether upstream has rebuilt an installed moving tag like `nightly`,
+// by comparing the upstream digest with the one recorded at install time
+func (m *Manager) hasMovedSinceInstall(tool models.Tool, tvm models.ToolVersionManager, version models.ToolVersion) bool {
+	digester, ok := tvm.(models.RemoteDigester)
+	if !ok || !models.IsMovingTag(version) {
+		return false
+	}
+	remoteDigest, err := digester.GetRemoteDigest(tool, version)
+	if err != nil {
+		slog.Warn("Failed to get remote digest, assuming the installed build is current", "tool", tool.GetId(), "version", version, "error", err)
+		return false
+	}
+	record, found := m.installStore.Get(tool.GetId(), version)
+	if remoteDigest == "" || !found {
+		return false
+	}
+	return record.RemoteDigest != remoteDigest
+}
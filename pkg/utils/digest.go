@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FileDigest returns the sha256 digest of a file as "sha256:<hex>"
+func FileDigest(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", filePath, err)
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// DirDigest returns a sha256 digest over the relative paths, symlink targets and file contents of a
+// directory tree, along with the total size of its regular files
+func DirDigest(dir string) (string, int64, error) {
+	hash := sha256.New()
+	var size int64
+
+	// WalkDir visits entries in lexical order, so the digest is stable
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(hash, "link %s %s\n", rel, target)
+		case d.IsDir():
+			fmt.Fprintf(hash, "dir %s\n", rel)
+		case d.Type().IsRegular():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+			fileDigest, err := FileDigest(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(hash, "file %s %o %s\n", rel, info.Mode().Perm(), fileDigest)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to hash directory %s: %w", dir, err)
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// DirSize returns the total size of the regular files in a directory tree
+func DirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// FileLock is an exclusive advisory lock (flock) on a file, shared between processes
+type FileLock struct {
+	file *os.File
+}
+
+// LockFile acquires an exclusive lock on the given file, creating it (and its parent directory) if needed.
+// It blocks until the lock is available.
+func LockFile(filePath string) (*FileLock, error) {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory for %s: %w", filePath, err)
+	}
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %s: %w", filePath, err)
+	}
+
+	// try without blocking first, so that we can tell the user why we are waiting
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		slog.Info("Waiting for lock held by another tvm process", "lock", filePath)
+		err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", filePath, err)
+	}
+	return &FileLock{file: file}, nil
+}
+
+// Unlock releases the lock
+func (l *FileLock) Unlock() error {
+	defer l.file.Close()
+	if err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN); err != nil {
+		return fmt.Errorf("failed to unlock %s: %w", l.file.Name(), err)
+	}
+	return nil
+}
+
+// TryLockFile is like LockFile, but returns (nil, false, nil) instead of waiting if the lock is held elsewhere
+func TryLockFile(filePath string) (*FileLock, bool, error) {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return nil, false, fmt.Errorf("failed to create lock directory for %s: %w", filePath, err)
+	}
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open lock file %s: %w", filePath, err)
+	}
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		file.Close()
+		return nil, false, nil
+	}
+	if err != nil {
+		file.Close()
+		return nil, false, fmt.Errorf("failed to lock %s: %w", filePath, err)
+	}
+	return &FileLock{file: file}, true, nil
+}
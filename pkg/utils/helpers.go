@@ -2,9 +2,11 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"text/template"
 
 	"github.com/goccy/go-yaml"
@@ -22,6 +24,11 @@ func LoadJSONFile[T any](filePath string, out *T) error {
 }
 
 func LoadYAMLFile[T any](filePath string, out *T) error {
+	return LoadYAMLFileContext(context.Background(), filePath, out)
+}
+
+// LoadYAMLFileContext is LoadYAMLFile with a context for the unmarshalers, e.g. one with a tool registry
+func LoadYAMLFileContext[T any](ctx context.Context, filePath string, out *T) error {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to read file %s: %w", filePath, err)
@@ -29,7 +36,7 @@ func LoadYAMLFile[T any](filePath string, out *T) error {
 	if len(bytes.TrimSpace(data)) == 0 {
 		return fmt.Errorf("YAML file %s is empty", filePath)
 	}
-	if err := yaml.UnmarshalWithOptions(data, out, yaml.AllowDuplicateMapKey()); err != nil {
+	if err := yaml.UnmarshalContext(ctx, data, out, yaml.AllowDuplicateMapKey()); err != nil {
 		return fmt.Errorf("failed to unmarshal YAML from file %s: %w", filePath, err)
 	}
 	return nil
@@ -40,14 +47,58 @@ func SaveJSONFile[T any](filePath string, data *T) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal data to JSON: %w", err)
 	}
-	if err := os.WriteFile(filePath, jsonData, 0644); err != nil {
+	if err := WriteFileAtomic(filePath, jsonData, 0644); err != nil {
 		return fmt.Errorf("failed to write JSON to file %s: %w", filePath, err)
 	}
 	return nil
 }
 
-func RenderTemplate(tmplStr string, data map[string]any) (string, error) {
-	tmpl, err := template.New("cmd").Parse(tmplStr)
+// WriteFileAtomic writes data to a temp file next to filePath and renames it into place,
+// so that readers never see a partially written file
+func WriteFileAtomic(filePath string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filePath)
+}
+
+// ReplaceDir moves newDir to dir, replacing whatever is there. The old dir is moved aside first and restored if
+// newDir can't be moved, so dir is only missing between two renames. Both must be on the same filesystem.
+func ReplaceDir(dir, newDir string) error {
+	oldDir := newDir + ".old"
+	if err := os.Rename(dir, oldDir); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Rename(newDir, dir); err != nil {
+		if restoreErr := os.Rename(oldDir, dir); restoreErr != nil && !os.IsNotExist(restoreErr) {
+			return fmt.Errorf("%w (and failed to restore %s: %v)", err, dir, restoreErr)
+		}
+		return err
+	}
+	return os.RemoveAll(oldDir)
+}
+
+// RenderTemplate renders a template, whose `mirror` function rewrites URLs with mirrors, which may be nil
+func RenderTemplate(tmplStr string, data map[string]any, mirrors *Mirrors) (string, error) {
+	tmpl, err := template.New("cmd").Funcs(mirrors.TemplateFuncs()).Parse(tmplStr)
 	if err != nil {
 		return "", err
 	}
@@ -68,7 +119,7 @@ func SaveYAMLFile[T any](filePath string, data *T) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal data to YAML: %w", err)
 	}
-	if err := os.WriteFile(filePath, yamlData, 0644); err != nil {
+	if err := WriteFileAtomic(filePath, yamlData, 0644); err != nil {
 		return fmt.Errorf("failed to write YAML to file %s: %w", filePath, err)
 	}
 	return nil
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Mirror rewrites URLs starting with From to start with To instead, e.g. to download through a proxy
+type Mirror struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Mirrors are the URL rewrite rules of a config. With Fallback, downloads retry the original URL when the
+// mirror fails. A nil Mirrors rewrites nothing.
+type Mirrors struct {
+	Rules    []Mirror
+	Fallback bool
+}
+
+// ValidateMirrors checks that every rule has both a prefix and a replacement
+func ValidateMirrors(rules []Mirror) error {
+	for i, rule := range rules {
+		if rule.From == "" || rule.To == "" {
+			return fmt.Errorf("mirror %d: both from and to are required", i)
+		}
+	}
+	return nil
+}
+
+// URL rewrites url with the rule of the longest matching prefix, or returns it unchanged
+func (m *Mirrors) URL(url string) string {
+	if m == nil {
+		return url
+	}
+	var match *Mirror
+	for i := range m.Rules {
+		if strings.HasPrefix(url, m.Rules[i].From) && (match == nil || len(m.Rules[i].From) > len(match.From)) {
+			match = &m.Rules[i]
+		}
+	}
+	if match == nil {
+		return url
+	}
+	return match.To + strings.TrimPrefix(url, match.From)
+}
+
+// Candidates returns the URLs to try for url, in order: the mirrored URL, then the original one
+// if it was rewritten and fallback is enabled
+func (m *Mirrors) Candidates(url string) []string {
+	mirrored := m.URL(url)
+	if mirrored != url && m.Fallback {
+		return []string{mirrored, url}
+	}
+	return []string{mirrored}
+}
+
+// TemplateFuncs are the functions available to templates rendered with these mirrors
+func (m *Mirrors) TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// mirror joins its arguments into a URL and applies the mirrors, e.g. {{mirror "https://github.com/" .Tool.Extra.Repo}}
+		"mirror": func(parts ...any) string {
+			var b strings.Builder
+			for _, part := range parts {
+				fmt.Fprint(&b, part)
+			}
+			return m.URL(b.String())
+		},
+	}
+}
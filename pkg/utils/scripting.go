@@ -1,9 +1,13 @@
 package utils
 
 import (
+	"bytes"
 	"fmt"
 	"log/slog"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 )
 
 type ScriptStep struct {
@@ -11,7 +15,15 @@ type ScriptStep struct {
 	Script string `json:"script"`
 }
 
-func executeScriptSteps(steps []ScriptStep, varsInput map[string]any, shell string) (string, error) {
+// ScriptOptions configure how script steps run
+type ScriptOptions struct {
+	// Env is added to tvm's own environment
+	Env []string
+	// Mirrors rewrite URLs in the `mirror` template function
+	Mirrors *Mirrors
+}
+
+func executeScriptSteps(steps []ScriptStep, varsInput map[string]any, shell string, opts ScriptOptions) (string, error) {
 	if shell == "" {
 		return "", fmt.Errorf("shell must be specified")
 	}
@@ -45,25 +57,71 @@ func executeScriptSteps(steps []ScriptStep, varsInput map[string]any, shell stri
 			vars["Arg"] = ""
 		}
 
-		cmdStr, err := RenderTemplate(step.Script, vars)
+		cmdStr, err := RenderTemplate(step.Script, vars, opts.Mirrors)
 		slog.Debug("Rendered script command for step", "step", step.Name, "cmd", cmdStr)
 		if err != nil {
 			return "", err
 		}
 
-		out, err := exec.Command(shell, "-c", cmdStr).CombinedOutput()
-		slog.Debug("Executed script for step", "step", step.Name, "output", string(out), "error", err)
+		// only stdout is the step's output, so that warnings and errors on stderr are never taken for data
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(shell, "-c", cmdStr)
+		if opts.Env != nil {
+			cmd.Env = append(os.Environ(), opts.Env...)
+		}
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		err = cmd.Run()
+		slog.Debug("Executed script for step", "step", step.Name, "output", stdout.String(), "stderr", stderr.String(), "error", err)
 
 		if err != nil {
 			slog.Error("Failed to execute script for step", "step", step.Name, "error", err)
+			tail := OutputTail(stderr.String())
+			if tail == "" {
+				tail = OutputTail(stdout.String())
+			}
+			if tail != "" {
+				return "", fmt.Errorf("failed to execute script %s: %w: %s", step.Name, err, tail)
+			}
 			return "", fmt.Errorf("failed to execute script %s: %w", step.Name, err)
 		}
 
-		vars["StepOutputs"].(map[string]string)[step.Name] = string(out)
+		vars["StepOutputs"].(map[string]string)[step.Name] = stdout.String()
 	}
 	return vars["StepOutputs"].(map[string]string)[steps[len(steps)-1].Name], nil
 }
 
 func ExecuteBashScriptSteps(steps []ScriptStep, vars map[string]any) (string, error) {
-	return executeScriptSteps(steps, vars, "bash")
+	return executeScriptSteps(steps, vars, "bash", ScriptOptions{})
+}
+
+// ExecuteBashScriptStepsWith runs script steps with options
+func ExecuteBashScriptStepsWith(steps []ScriptStep, vars map[string]any, opts ScriptOptions) (string, error) {
+	return executeScriptSteps(steps, vars, "bash", opts)
+}
+
+// TvmEnv is the environment that makes the tvm helpers a script or plugin calls, like `tvm _download`, use the
+// same config and offline mode as the tvm running it. With refetch, `tvm _download` skips the artifact cache.
+func TvmEnv(configPath string, offline, refetch bool) []string {
+	if absConfigPath, err := filepath.Abs(configPath); err == nil {
+		configPath = absConfigPath
+	}
+	env := []string{"TVM_CONFIG=" + configPath, "TVM_OFFLINE=", "TVM_REFETCH="}
+	if offline {
+		env[1] = "TVM_OFFLINE=1"
+	}
+	if refetch {
+		env[2] = "TVM_REFETCH=1"
+	}
+	return env
+}
+
+// OutputTail returns the last lines of a failed command's output, which usually say why it failed
+func OutputTail(out string) string {
+	const maxLines = 5
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) > maxLines {
+		lines = lines[len(lines)-maxLines:]
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
 }
@@ -0,0 +1,240 @@
+// tvm-backend-localdir is the reference plugin for tvm's plugin protocol. It manages tools whose versions are
+// directories on the local filesystem, e.g. builds on a shared drive:
+//
+//	tools:
+//	  - id: mytool
+//	    type: localdir
+//	    symlinks: [{from: bin/mytool}]
+//	    extra:
+//	      source_dir: /mnt/builds/mytool   # one directory per version
+//
+// Build it with `go build -o ~/.config/tvm/plugins/ ./plugins/tvm-backend-localdir`.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"rayyanriaz/tool-version-manager/pkg/models"
+	"rayyanriaz/tool-version-manager/pkg/plugin"
+)
+
+type tool struct {
+	models.ToolBase
+	Extra struct {
+		SourceDir string `json:"source_dir"`
+	} `json:"extra"`
+}
+
+func main() {
+	plugin.Serve([]string{
+		plugin.OpListLocalVersions,
+		plugin.OpListRemoteVersions,
+		plugin.OpLatestRemoteVersion,
+		plugin.OpInstall,
+		plugin.OpUninstall,
+		plugin.OpLink,
+		plugin.OpUnlink,
+		plugin.OpLinkInfo,
+		plugin.OpCompare,
+	}, handle)
+}
+
+func handle(req plugin.Request) (plugin.Response, error) {
+	var t tool
+	if err := json.Unmarshal(req.Tool, &t); err != nil {
+		return plugin.Response{}, fmt.Errorf("invalid tool: %w", err)
+	}
+
+	switch req.Operation {
+	case plugin.OpListLocalVersions:
+		versions, err := versionDirs(req.Context.ToolDir, t)
+		return plugin.Response{Versions: versions}, err
+	case plugin.OpListRemoteVersions:
+		versions, err := remoteVersions(t)
+		return plugin.Response{Versions: versions}, err
+	case plugin.OpLatestRemoteVersion:
+		versions, err := remoteVersions(t)
+		if err != nil {
+			return plugin.Response{}, err
+		}
+		if len(versions) == 0 {
+			return plugin.Response{}, fmt.Errorf("no versions in %s", t.Extra.SourceDir)
+		}
+		return plugin.Response{Version: versions[0]}, nil
+	case plugin.OpInstall:
+		src := filepath.Join(t.Extra.SourceDir, string(req.Version))
+		if err := copyTree(src, req.Context.VersionDir); err != nil {
+			os.RemoveAll(req.Context.VersionDir)
+			return plugin.Response{}, err
+		}
+		return plugin.Response{SourceURL: "file://" + src}, nil
+	case plugin.OpUninstall:
+		return plugin.Response{}, os.RemoveAll(req.Context.VersionDir)
+	case plugin.OpLink:
+		return plugin.Response{}, link(req, t)
+	case plugin.OpUnlink:
+		return plugin.Response{}, unlink(req, t)
+	case plugin.OpLinkInfo:
+		return linkInfo(req.Context.ToolDir)
+	case plugin.OpCompare:
+		comparison, err := models.CompareVersions(t, req.V1, req.V2)
+		return plugin.Response{Comparison: comparison}, err
+	}
+	return plugin.Response{}, plugin.ErrUnsupported
+}
+
+// versionDirs lists the version directories in dir, newest first
+func versionDirs(dir string, t tool) ([]models.ToolVersion, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var versions []models.ToolVersion
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != "current" && !strings.HasPrefix(entry.Name(), ".") {
+			versions = append(versions, models.ToolVersion(entry.Name()))
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		result, err := models.CompareVersions(t, versions[i], versions[j])
+		return err == nil && result > 0
+	})
+	return versions, nil
+}
+
+func remoteVersions(t tool) ([]models.ToolVersion, error) {
+	if t.Extra.SourceDir == "" {
+		return nil, fmt.Errorf("tool %s needs extra.source_dir", t.Id)
+	}
+	if _, err := os.Stat(t.Extra.SourceDir); err != nil {
+		return nil, err
+	}
+	return versionDirs(t.Extra.SourceDir, t)
+}
+
+// copyTree copies a directory tree, keeping file modes and symlinks
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			in, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer in.Close()
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, in); err != nil {
+				out.Close()
+				return err
+			}
+			return out.Close()
+		}
+	})
+}
+
+// link points <tool_dir>/current at the version and the tool's symlinks at current
+func link(req plugin.Request, t tool) error {
+	if _, err := os.Stat(req.Context.VersionDir); err != nil {
+		return fmt.Errorf("version %s is not installed", req.Version)
+	}
+	current := filepath.Join(req.Context.ToolDir, "current")
+	for _, symlink := range t.Symlinks {
+		path := filepath.Join(req.Context.SymlinksDir, symlink.LinkName())
+		if target, err := os.Readlink(path); err == nil && !strings.HasPrefix(target, req.Context.ToolDir+"/") && !req.Force {
+			return fmt.Errorf("%s belongs to something else (%s), link with --force to overwrite it", path, target)
+		} else if err != nil && !os.IsNotExist(err) && !req.Force {
+			return fmt.Errorf("%s exists and isn't a symlink, link with --force to overwrite it", path)
+		}
+	}
+	if err := replaceSymlink(current, req.Context.VersionDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(req.Context.SymlinksDir, 0755); err != nil {
+		return err
+	}
+	for _, symlink := range t.Symlinks {
+		if err := replaceSymlink(filepath.Join(req.Context.SymlinksDir, symlink.LinkName()), filepath.Join(current, strings.TrimSpace(symlink.From))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unlink(req plugin.Request, t tool) error {
+	current := filepath.Join(req.Context.ToolDir, "current")
+	if _, err := os.Lstat(current); err != nil {
+		return fmt.Errorf("tool %s is not linked to any version", t.Id)
+	}
+	for _, symlink := range t.Symlinks {
+		path := filepath.Join(req.Context.SymlinksDir, symlink.LinkName())
+		if target, err := os.Readlink(path); err == nil && strings.HasPrefix(target, req.Context.ToolDir+"/") {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+	}
+	return os.Remove(current)
+}
+
+func linkInfo(toolDir string) (plugin.Response, error) {
+	current := filepath.Join(toolDir, "current")
+	info, err := os.Lstat(current)
+	if os.IsNotExist(err) {
+		return plugin.Response{LinkInfo: &models.ToolLinkInfo{}}, nil
+	}
+	if err != nil {
+		return plugin.Response{}, err
+	}
+	target, err := os.Readlink(current)
+	if err != nil {
+		return plugin.Response{}, err
+	}
+	return plugin.Response{LinkInfo: &models.ToolLinkInfo{
+		Version:  models.ToolVersion(filepath.Base(target)),
+		LinkedAt: info.ModTime().Format(time.RFC3339),
+	}}, nil
+}
+
+// replaceSymlink atomically points path at target
+func replaceSymlink(path, target string) error {
+	tmp := path + ".tvm-new"
+	os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}